@@ -0,0 +1,222 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	errs "github.com/ONSdigital/dp-census-alpha-search-api/apierrors"
+	"github.com/ONSdigital/dp-census-alpha-search-api/internal/elasticsearch"
+	"github.com/ONSdigital/dp-census-alpha-search-api/metrics"
+	"github.com/ONSdigital/dp-census-alpha-search-api/models"
+	"github.com/ONSdigital/log.go/log"
+	"github.com/gorilla/mux"
+)
+
+const bearerPrefix = "Bearer "
+
+// requireAdminAuth only calls the handler for requests with the admin auth token set as a bearer token
+func (api *SearchAPI) requireAdminAuth(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		token := strings.TrimPrefix(auth, bearerPrefix)
+
+		if !strings.HasPrefix(auth, bearerPrefix) || subtle.ConstantTimeCompare([]byte(token), []byte(api.adminAuthToken)) != 1 {
+			log.Event(r.Context(), "admin endpoint: request is unauthorised", log.WARN, log.Data{"path": r.URL.Path})
+			setErrorCode(w, errs.ErrUnauthorised)
+			return
+		}
+
+		handler(w, r)
+	}
+}
+
+func (api *SearchAPI) reloadReferenceFiles(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	log.Event(ctx, "reloadReferenceFiles endpoint: incoming request", log.INFO)
+
+	docs, err := api.reference.Reload(ctx)
+	if err != nil {
+		log.Event(ctx, "reloadReferenceFiles endpoint: failed to reload reference files", log.ERROR, log.Error(err))
+		setErrorCode(w, errs.New(errs.CodeReloadFailed, http.StatusUnprocessableEntity, "failed to reload reference files, the previous version is still in use: "+err.Error(), ""))
+		return
+	}
+
+	b, err := json.Marshal(models.ReloadResponse{
+		Dimensions:  len(docs.Dimensions.Dimensions),
+		Hierarchies: len(docs.Hierarchies.Items),
+		Topics:      len(docs.Taxonomy.Topics),
+		LoadedAt:    docs.LoadedAt,
+	})
+	if err != nil {
+		log.Event(ctx, "reloadReferenceFiles endpoint: failed to marshal reload response into bytes", log.ERROR, log.Error(err))
+		setErrorCode(w, errs.ErrInternalServer)
+		return
+	}
+
+	_, err = w.Write(b)
+	if err != nil {
+		log.Event(ctx, "reloadReferenceFiles endpoint: error writing response", log.ERROR, log.Error(err))
+	}
+
+	log.Event(ctx, "reloadReferenceFiles endpoint: successfully reloaded reference files", log.INFO)
+}
+
+// purgeCache removes every cached search response, so that loaders can make newly indexed
+// documents searchable straight away rather than once the cached responses expire
+func (api *SearchAPI) purgeCache(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	log.Event(ctx, "purgeCache endpoint: incoming request", log.INFO)
+
+	api.cache.Purge()
+	metrics.CachePurged(metrics.CacheAdmin)
+
+	w.WriteHeader(http.StatusNoContent)
+
+	log.Event(ctx, "purgeCache endpoint: successfully purged cache", log.INFO)
+}
+
+// maxDocumentSize is the largest document body accepted by the admin endpoints, area profiles
+// hold the full boundary of the area so can be large
+const maxDocumentSize = 10 << 20
+
+// putDataset stores a dataset under its alias, replacing any dataset with the same alias
+func (api *SearchAPI) putDataset(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	alias := mux.Vars(r)["alias"]
+	logData := log.Data{"alias": alias}
+
+	log.Event(ctx, "putDataset endpoint: incoming request", log.INFO, logData)
+
+	dataset := &models.DatasetDocument{}
+	if err := decodeDocument(w, r, dataset); err != nil {
+		log.Event(ctx, "putDataset endpoint: failed to parse dataset", log.WARN, log.Error(err), logData)
+		setErrorCode(w, errs.ErrUnableToParseJSON)
+		return
+	}
+
+	if err := dataset.Validate(alias, api.reference.Get().TopicLevels); err != nil {
+		log.Event(ctx, "putDataset endpoint: invalid dataset", log.WARN, log.Error(err), logData)
+		setErrorCode(w, err)
+		return
+	}
+
+	api.indexDocument(w, r, "putDataset", api.datasetIndex, alias, dataset, logData)
+}
+
+// deleteDataset removes the dataset with the alias
+func (api *SearchAPI) deleteDataset(w http.ResponseWriter, r *http.Request) {
+	alias := mux.Vars(r)["alias"]
+
+	api.deleteDocument(w, r, "deleteDataset", api.datasetIndex, alias, errs.ErrDatasetNotFound, log.Data{"alias": alias})
+}
+
+// putAreaProfile stores an area profile under its id, replacing any area profile with the same id
+func (api *SearchAPI) putAreaProfile(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := mux.Vars(r)["id"]
+	logData := log.Data{"id": id}
+
+	log.Event(ctx, "putAreaProfile endpoint: incoming request", log.INFO, logData)
+
+	areaProfile := &models.AreaProfileDocument{}
+	if err := decodeDocument(w, r, areaProfile); err != nil {
+		log.Event(ctx, "putAreaProfile endpoint: failed to parse area profile", log.WARN, log.Error(err), logData)
+		setErrorCode(w, errs.ErrUnableToParseJSON)
+		return
+	}
+
+	if err := areaProfile.Validate(id, api.reference.Get().Hierarchies); err != nil {
+		log.Event(ctx, "putAreaProfile endpoint: invalid area profile", log.WARN, log.Error(err), logData)
+		setErrorCode(w, err)
+		return
+	}
+
+	api.indexDocument(w, r, "putAreaProfile", api.areaProfileIndex, id, areaProfile, logData)
+}
+
+// deleteAreaProfile removes the area profile with the id
+func (api *SearchAPI) deleteAreaProfile(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	api.deleteDocument(w, r, "deleteAreaProfile", api.areaProfileIndex, id, errs.ErrAreaProfileNotFound, log.Data{"id": id})
+}
+
+// decodeDocument decodes the request body into the document, rejecting fields the document does
+// not have so that misspelt fields are not silently dropped
+func decodeDocument(w http.ResponseWriter, r *http.Request, document interface{}) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxDocumentSize))
+	decoder.DisallowUnknownFields()
+
+	return decoder.Decode(document)
+}
+
+// indexDocument stores the document with the id and responds with the stored document, cached
+// search responses are purged once the change is searchable so that they are not cached again
+// from the old document
+func (api *SearchAPI) indexDocument(w http.ResponseWriter, r *http.Request, handler, indexName, id string, document interface{}, logData log.Data) {
+	ctx := r.Context()
+
+	status, err := api.elasticsearch.IndexDocument(ctx, indexName, id, document)
+	if err != nil {
+		logData["elasticsearch_status"] = status
+		log.Event(ctx, handler+" endpoint: failed to store document", log.ERROR, log.Error(err), logData)
+		setErrorCode(w, err)
+		return
+	}
+
+	api.cache.Purge()
+	metrics.CachePurged(metrics.CacheWrite)
+
+	b, err := json.Marshal(document)
+	if err != nil {
+		log.Event(ctx, handler+" endpoint: failed to marshal document into bytes", log.ERROR, log.Error(err), logData)
+		setErrorCode(w, errs.ErrInternalServer)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if status == http.StatusCreated {
+		w.WriteHeader(http.StatusCreated)
+	}
+
+	_, err = w.Write(b)
+	if err != nil {
+		log.Event(ctx, handler+" endpoint: error writing response", log.ERROR, log.Error(err), logData)
+	}
+
+	log.Event(ctx, handler+" endpoint: successfully stored document", log.INFO, logData)
+}
+
+// deleteDocument removes the document with the id, responding with the not found error if the
+// index does not hold the document
+func (api *SearchAPI) deleteDocument(w http.ResponseWriter, r *http.Request, handler, indexName, id string, notFound error, logData log.Data) {
+	ctx := r.Context()
+
+	log.Event(ctx, handler+" endpoint: incoming request", log.INFO, logData)
+
+	status, err := api.elasticsearch.DeleteDocument(ctx, indexName, id)
+	if err == elasticsearch.ErrDocumentNotFound {
+		log.Event(ctx, handler+" endpoint: document not found", log.WARN, logData)
+		setErrorCode(w, notFound)
+		return
+	}
+
+	if err != nil {
+		logData["elasticsearch_status"] = status
+		log.Event(ctx, handler+" endpoint: failed to delete document", log.ERROR, log.Error(err), logData)
+		setErrorCode(w, err)
+		return
+	}
+
+	api.cache.Purge()
+	metrics.CachePurged(metrics.CacheWrite)
+
+	w.WriteHeader(http.StatusNoContent)
+
+	log.Event(ctx, handler+" endpoint: successfully deleted document", log.INFO, logData)
+}
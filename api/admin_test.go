@@ -0,0 +1,183 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	errs "github.com/ONSdigital/dp-census-alpha-search-api/apierrors"
+	"github.com/ONSdigital/dp-census-alpha-search-api/cache"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// adminRequest makes an admin request with the body to the router and returns the response
+func adminRequest(router http.Handler, method, url, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, url, strings.NewReader(body))
+	r.Header.Set("Authorization", bearerPrefix+testAdminAuthToken)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+
+	return w
+}
+
+func TestPutDataset(t *testing.T) {
+	Convey("Given a search api holding datasets with cached search responses", t, func() {
+		router, es, _ := setupAPIWithCache(cache.New(10, time.Minute))
+
+		So(get(router, "/search?q=house").Body.String(), ShouldContainSubstring, "House prices by age of property")
+
+		Convey("When the title of a dataset is corrected", func() {
+			w := adminRequest(router, http.MethodPut, "/admin/datasets/House%20prices", `{"title":"House prices by the age of the property","topic1":"economy"}`)
+
+			Convey("Then the dataset is replaced and the corrected title is searchable straight away", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(w.Body.String(), ShouldContainSubstring, `"alias":"House prices"`)
+				So(w.Body.String(), ShouldContainSubstring, `"doc_type":"dataset"`)
+
+				search := get(router, "/search?q=house")
+				So(search.Header().Get(cacheHeader), ShouldEqual, cacheMiss)
+				So(search.Body.String(), ShouldContainSubstring, "House prices by the age of the property")
+				So(search.Body.String(), ShouldNotContainSubstring, "House prices by age of property")
+			})
+		})
+
+		Convey("When a new dataset is stored then it is created", func() {
+			w := adminRequest(router, http.MethodPut, "/admin/datasets/Births", `{"title":"Live births","topic1":"peoplepopulationandcommunity"}`)
+
+			So(w.Code, ShouldEqual, http.StatusCreated)
+			So(get(router, "/search?q=births").Body.String(), ShouldContainSubstring, "Live births")
+		})
+
+		Convey("When a dataset has a topic that is not in the taxonomy", func() {
+			w := adminRequest(router, http.MethodPut, "/admin/datasets/House%20prices", `{"title":"House prices","topic1":"housing"}`)
+
+			Convey("Then the dataset is rejected and the cached response is kept", func() {
+				So(w.Code, ShouldEqual, http.StatusBadRequest)
+				So(decodeError(w).Parameter, ShouldEqual, "topic1")
+				So(get(router, "/search?q=house").Header().Get(cacheHeader), ShouldEqual, cacheHit)
+			})
+		})
+
+		Convey("When a dataset has a field that does not exist then it is rejected", func() {
+			w := adminRequest(router, http.MethodPut, "/admin/datasets/House%20prices", `{"title":"House prices","titel":"House prices"}`)
+
+			So(w.Code, ShouldEqual, http.StatusBadRequest)
+			So(decodeError(w).Code, ShouldEqual, errs.ErrUnableToParseJSON.Code)
+		})
+
+		Convey("When elasticsearch is unavailable then the error is returned", func() {
+			es.Fail(testDatasetIndex, http.StatusServiceUnavailable, errs.ErrElasticsearchUnavailable)
+
+			w := adminRequest(router, http.MethodPut, "/admin/datasets/House%20prices", `{"title":"House prices"}`)
+
+			So(w.Code, ShouldEqual, http.StatusServiceUnavailable)
+		})
+
+		Convey("When the request does not have the admin auth token then it is unauthorised", func() {
+			w := request(router, http.MethodPut, "/admin/datasets/House%20prices", nil)
+
+			So(w.Code, ShouldEqual, http.StatusUnauthorized)
+		})
+
+		Convey("When the admin auth token is sent without the bearer prefix then it is unauthorised", func() {
+			w := request(router, http.MethodPut, "/admin/datasets/House%20prices", map[string]string{"Authorization": testAdminAuthToken})
+
+			So(w.Code, ShouldEqual, http.StatusUnauthorized)
+		})
+	})
+}
+
+func TestDeleteDataset(t *testing.T) {
+	Convey("Given a search api holding datasets", t, func() {
+		router, _ := setupAPI()
+
+		Convey("When a dataset is deleted then it is no longer searchable", func() {
+			w := adminRequest(router, http.MethodDelete, "/admin/datasets/House%20prices", "")
+
+			So(w.Code, ShouldEqual, http.StatusNoContent)
+			So(get(router, "/search?q=house").Body.String(), ShouldNotContainSubstring, "House prices by age of property")
+		})
+
+		Convey("When a dataset that does not exist is deleted then it is not found", func() {
+			w := adminRequest(router, http.MethodDelete, "/admin/datasets/Births", "")
+
+			So(w.Code, ShouldEqual, http.StatusNotFound)
+			So(decodeError(w).Code, ShouldEqual, errs.ErrDatasetNotFound.Code)
+		})
+	})
+}
+
+func TestPutAreaProfile(t *testing.T) {
+	Convey("Given a search api holding area profiles", t, func() {
+		router, _ := setupAPI()
+
+		Convey("When an area profile is replaced", func() {
+			w := adminRequest(router, http.MethodPut, "/admin/area-profiles/W06000015", `{
+				"name": "Caerdydd",
+				"code": "W06000015",
+				"hierarchy": "Major Towns and Cities",
+				"location": {"type": "Polygon", "coordinates": [[[-3.3, 51.4], [-3.1, 51.4], [-3.1, 51.6], [-3.3, 51.6], [-3.3, 51.4]]]}
+			}`)
+
+			Convey("Then the area profile is stored with a centroid and returned by id", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(w.Body.String(), ShouldContainSubstring, `"centroid":{"lat":51.5`)
+
+				areaProfile := get(router, "/area-profiles/W06000015")
+				So(areaProfile.Code, ShouldEqual, http.StatusOK)
+				So(areaProfile.Body.String(), ShouldContainSubstring, "Caerdydd")
+			})
+		})
+
+		Convey("When an area profile has a hierarchy that does not exist then it is rejected", func() {
+			w := adminRequest(router, http.MethodPut, "/admin/area-profiles/W06000015", `{
+				"name": "Cardiff",
+				"code": "W06000015",
+				"hierarchy": "Towns",
+				"location": {"type": "Polygon", "coordinates": [[[-3.3, 51.4], [-3.1, 51.4], [-3.1, 51.6], [-3.3, 51.4]]]}
+			}`)
+
+			So(w.Code, ShouldEqual, http.StatusBadRequest)
+			So(decodeError(w).Parameter, ShouldEqual, "hierarchy")
+		})
+
+		Convey("When the id does not match the url then it is rejected", func() {
+			w := adminRequest(router, http.MethodPut, "/admin/area-profiles/W06000015", `{"id":"E92000001","name":"England"}`)
+
+			So(w.Code, ShouldEqual, http.StatusBadRequest)
+			So(decodeError(w).Parameter, ShouldEqual, "id")
+		})
+	})
+}
+
+func TestDeleteAreaProfile(t *testing.T) {
+	Convey("Given a search api holding area profiles", t, func() {
+		router, es := setupAPI()
+
+		Convey("When an area profile is deleted then it is no longer found", func() {
+			w := adminRequest(router, http.MethodDelete, "/admin/area-profiles/W06000015", "")
+
+			So(w.Code, ShouldEqual, http.StatusNoContent)
+			So(get(router, "/area-profiles/W06000015").Code, ShouldEqual, http.StatusNotFound)
+		})
+
+		Convey("When an area profile that does not exist is deleted then it is not found", func() {
+			w := adminRequest(router, http.MethodDelete, "/admin/area-profiles/W00000000", "")
+
+			So(w.Code, ShouldEqual, http.StatusNotFound)
+			So(decodeError(w).Code, ShouldEqual, errs.ErrAreaProfileNotFound.Code)
+		})
+
+		Convey("When elasticsearch fails with an unexpected error then an internal error is returned", func() {
+			es.Fail(testAreaProfileIndex, http.StatusInternalServerError, errors.New("unexpected"))
+
+			w := adminRequest(router, http.MethodDelete, "/admin/area-profiles/W06000015", "")
+
+			So(w.Code, ShouldEqual, http.StatusInternalServerError)
+		})
+	})
+}
@@ -3,7 +3,10 @@ package api
 import (
 	"context"
 
-	"github.com/ONSdigital/dp-census-alpha-search-api/models"
+	"github.com/ONSdigital/dp-census-alpha-search-api/cache"
+	"github.com/ONSdigital/dp-census-alpha-search-api/health"
+	"github.com/ONSdigital/dp-census-alpha-search-api/metrics"
+	"github.com/ONSdigital/dp-census-alpha-search-api/reference"
 	"github.com/ONSdigital/go-ns/server"
 	"github.com/ONSdigital/log.go/log"
 	"github.com/gorilla/mux"
@@ -11,21 +14,30 @@ import (
 
 var httpServer *server.Server
 
+// Routes that zero result searches are recorded against
+const (
+	searchRoute            = "/search"
+	areaProfileSearchRoute = "/area-profiles/{id}/search"
+)
+
 // SearchAPI manages searches across indices
 type SearchAPI struct {
-	areaProfileIndex  string
-	datasetIndex      string
-	defaultMaxResults int
-	dimensions        models.DimensionsDoc
-	hierarchies       models.GeoHierarchiesDoc
-	elasticsearch     Elasticsearcher
-	postcodeIndex     string
-	router            *mux.Router
-	taxonomy          models.Taxonomy
+	adminAuthToken       string
+	areaProfileIndex     string
+	cache                *cache.Cache
+	datasetIndex         string
+	defaultMaxResults    int
+	elasticsearch        Elasticsearcher
+	health               *health.Checker
+	partialResultsStatus int
+	postcodeIndex        string
+	publicationIndex     string
+	reference            *reference.Store
+	router               *mux.Router
 }
 
 // CreateAndInitialiseSearchAPI manages all the routes configured to API
-func CreateAndInitialiseSearchAPI(ctx context.Context, bindAddr string, esAPI Elasticsearcher, defaultMaxResults int, datasetIndex, areaProfileIndex, postcodeIndex string, dimensions models.DimensionsDoc, taxonomy models.Taxonomy, hierarchies models.GeoHierarchiesDoc, errorChan chan error) {
+func CreateAndInitialiseSearchAPI(ctx context.Context, bindAddr string, esAPI Elasticsearcher, defaultMaxResults int, datasetIndex, areaProfileIndex, postcodeIndex, publicationIndex string, partialResultsStatus int, adminAuthToken string, referenceDocs *reference.Store, healthChecker *health.Checker, responseCache *cache.Cache, timeouts Timeouts, errorChan chan error) {
 
 	router := mux.NewRouter()
 	routes(ctx,
@@ -35,9 +47,13 @@ func CreateAndInitialiseSearchAPI(ctx context.Context, bindAddr string, esAPI El
 		datasetIndex,
 		areaProfileIndex,
 		postcodeIndex,
-		dimensions,
-		taxonomy,
-		hierarchies,
+		publicationIndex,
+		partialResultsStatus,
+		adminAuthToken,
+		referenceDocs,
+		healthChecker,
+		responseCache,
+		timeouts,
 	)
 
 	httpServer = server.New(bindAddr, router)
@@ -58,30 +74,55 @@ func routes(ctx context.Context,
 	router *mux.Router,
 	elasticsearch Elasticsearcher,
 	defaultMaxResults int,
-	datasetIndex, areaProfileIndex, postcodeIndex string,
-	dimensions models.DimensionsDoc,
-	taxonomy models.Taxonomy,
-	hierarchies models.GeoHierarchiesDoc) *SearchAPI {
+	datasetIndex, areaProfileIndex, postcodeIndex, publicationIndex string,
+	partialResultsStatus int,
+	adminAuthToken string,
+	referenceDocs *reference.Store,
+	healthChecker *health.Checker,
+	responseCache *cache.Cache,
+	timeouts Timeouts) *SearchAPI {
 
 	api := SearchAPI{
-		areaProfileIndex:  areaProfileIndex,
-		datasetIndex:      datasetIndex,
-		defaultMaxResults: defaultMaxResults,
-		dimensions:        dimensions,
-		elasticsearch:     elasticsearch,
-		hierarchies:       hierarchies,
-		postcodeIndex:     postcodeIndex,
-		router:            router,
-		taxonomy:          taxonomy,
+		adminAuthToken:       adminAuthToken,
+		areaProfileIndex:     areaProfileIndex,
+		cache:                responseCache,
+		datasetIndex:         datasetIndex,
+		defaultMaxResults:    defaultMaxResults,
+		elasticsearch:        withTimeouts(elasticsearch, timeouts),
+		health:               healthChecker,
+		partialResultsStatus: partialResultsStatus,
+		postcodeIndex:        postcodeIndex,
+		publicationIndex:     publicationIndex,
+		reference:            referenceDocs,
+		router:               router,
 	}
 
-	api.router.HandleFunc("/search", api.searchData).Methods("GET", "OPTIONS")
+	api.purgeCacheOnReload()
+
+	api.router.Use(metrics.Middleware)
+
+	api.router.Handle("/metrics", metrics.Handler()).Methods("GET")
+	api.router.HandleFunc("/health", api.getHealth).Methods("GET")
+	api.router.HandleFunc(searchRoute, api.searchData).Methods("GET", "OPTIONS")
+	api.router.HandleFunc("/autocomplete", api.getAutocomplete).Methods("GET", "OPTIONS")
 	api.router.HandleFunc("/dimensions", api.getDimensions).Methods("GET", "OPTIONS")
 	api.router.HandleFunc("/taxonomy", api.getTaxonomy).Methods("GET", "OPTIONS")
 	api.router.HandleFunc("/taxonomy/{topic}", api.getTopic).Methods("GET", "OPTIONS")
 	api.router.HandleFunc("/hierarchies", api.getHierarchies).Methods("GET", "OPTIONS")
+	api.router.HandleFunc("/area-profiles", api.getAreaProfiles).Methods("GET", "OPTIONS")
 	api.router.HandleFunc("/area-profiles/{id}", api.getAreaProfile).Methods("GET", "OPTIONS")
-	api.router.HandleFunc("/area-profiles/{id}/search", api.getAreaProfileSearch).Methods("GET", "OPTIONS")
+	api.router.HandleFunc(areaProfileSearchRoute, api.getAreaProfileSearch).Methods("GET", "OPTIONS")
+	api.router.HandleFunc("/postcodes/{postcode}", api.getPostcode).Methods("GET", "OPTIONS")
+
+	// Admin endpoints are only available when an admin auth token has been configured
+	if adminAuthToken != "" {
+		api.router.HandleFunc("/admin/reload", api.requireAdminAuth(api.reloadReferenceFiles)).Methods("POST")
+		api.router.HandleFunc("/admin/cache", api.requireAdminAuth(api.purgeCache)).Methods("DELETE")
+		api.router.HandleFunc("/admin/datasets/{alias}", api.requireAdminAuth(api.putDataset)).Methods("PUT")
+		api.router.HandleFunc("/admin/datasets/{alias}", api.requireAdminAuth(api.deleteDataset)).Methods("DELETE")
+		api.router.HandleFunc("/admin/area-profiles/{id}", api.requireAdminAuth(api.putAreaProfile)).Methods("PUT")
+		api.router.HandleFunc("/admin/area-profiles/{id}", api.requireAdminAuth(api.deleteAreaProfile)).Methods("DELETE")
+	}
 
 	return &api
 }
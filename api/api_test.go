@@ -0,0 +1,182 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	errs "github.com/ONSdigital/dp-census-alpha-search-api/apierrors"
+	"github.com/ONSdigital/dp-census-alpha-search-api/cache"
+	"github.com/ONSdigital/dp-census-alpha-search-api/health"
+	"github.com/ONSdigital/dp-census-alpha-search-api/internal/elasticsearch/memory"
+	"github.com/ONSdigital/dp-census-alpha-search-api/models"
+	"github.com/ONSdigital/dp-census-alpha-search-api/reference"
+	"github.com/gorilla/mux"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const (
+	testAreaProfileIndex = "test-area-profiles"
+	testDatasetIndex     = "test-datasets"
+	testPostcodeIndex    = "test-postcodes"
+	testPublicationIndex = "test-publications"
+
+	testMaxResults = 1000
+
+	testAdminAuthToken = "test-admin-token"
+)
+
+// Boxes around each area as [lon, lat] coordinates, cardiff does not overlap london
+var (
+	cardiff = models.GeoLocation{
+		Type:        "polygon",
+		Coordinates: [][][]float64{{{-3.3, 51.4}, {-3.1, 51.4}, {-3.1, 51.6}, {-3.3, 51.6}, {-3.3, 51.4}}},
+	}
+	london = models.GeoLocation{
+		Type:        "polygon",
+		Coordinates: [][][]float64{{{-0.3, 51.4}, {0.1, 51.4}, {0.1, 51.6}, {-0.3, 51.6}, {-0.3, 51.4}}},
+	}
+	england = models.GeoLocation{
+		Type:        "polygon",
+		Coordinates: [][][]float64{{{-5.7, 49.9}, {1.8, 49.9}, {1.8, 55.8}, {-5.7, 55.8}, {-5.7, 49.9}}},
+	}
+)
+
+func testDatasets() []interface{} {
+	return []interface{}{
+		map[string]interface{}{
+			"alias":       "Deaths by sex",
+			"description": "Number of deaths registered in Cardiff by sex",
+			"doc_type":    "dataset",
+			"title":       "Deaths registered by sex",
+			"topic1":      "peoplepopulationandcommunity",
+			"topic2":      "birthsdeathsandmarriages",
+			"dimensions":  []models.Dimension{{Label: "Sex", Name: "sex"}},
+			"location":    cardiff,
+		},
+		map[string]interface{}{
+			"alias":       "Deaths in London",
+			"description": "Number of deaths registered in London",
+			"doc_type":    "dataset",
+			"title":       "Deaths registered in London",
+			"topic1":      "peoplepopulationandcommunity",
+			"topic2":      "birthsdeathsandmarriages",
+			"dimensions":  []models.Dimension{{Label: "Age", Name: "age"}},
+			"location":    london,
+		},
+		map[string]interface{}{
+			"alias":       "House prices",
+			"description": "Median house prices by the age of the property",
+			"doc_type":    "dataset",
+			"title":       "House prices by age of property",
+			"topic1":      "economy",
+			"dimensions":  []models.Dimension{{Label: "House price age", Name: "housepriceage"}},
+			"location":    london,
+		},
+	}
+}
+
+func testAreaProfiles() []interface{} {
+	return []interface{}{
+		models.AreaProfile{
+			ID:        "W06000015",
+			Code:      "W06000015",
+			Name:      "Cardiff",
+			Hierarchy: "Major Towns and Cities",
+			Location:  cardiff,
+		},
+		models.AreaProfile{
+			ID:        "E92000001",
+			Code:      "E92000001",
+			Name:      "England",
+			Hierarchy: "Countries",
+			Location:  england,
+		},
+	}
+}
+
+func testPublications() []interface{} {
+	return []interface{}{
+		models.SearchResult{
+			DocType:         "publication",
+			Keywords:        []string{"deaths", "mortality"},
+			PublicationType: "bulletin",
+			Summary:         "Provisional counts of the number of deaths registered each week",
+			Title:           "Deaths registered weekly in England and Wales",
+			Topic1:          "peoplepopulationandcommunity",
+			Topic2:          "birthsdeathsandmarriages",
+		},
+	}
+}
+
+// setupAPI creates a router for the api backed by an in memory elasticsearch holding the test
+// documents and the reference files in the data directory
+func setupAPI() (*mux.Router, *memory.Elasticsearch) {
+	router, es, _ := setupAPIWithCache(nil)
+	return router, es
+}
+
+// setupAPIWithTimeouts creates a router for the api that cancels calls to elasticsearch after
+// the timeouts
+func setupAPIWithTimeouts(timeouts Timeouts) (*mux.Router, *memory.Elasticsearch) {
+	router, es, _ := newTestAPI(nil, timeouts)
+	return router, es
+}
+
+// setupAPIWithCache creates a router for the api that caches search responses, admin endpoints
+// are enabled with the test admin auth token
+func setupAPIWithCache(responseCache *cache.Cache) (*mux.Router, *memory.Elasticsearch, *reference.Store) {
+	return newTestAPI(responseCache, Timeouts{})
+}
+
+func newTestAPI(responseCache *cache.Cache, timeouts Timeouts) (*mux.Router, *memory.Elasticsearch, *reference.Store) {
+	es := memory.New()
+	So(es.AddDocuments(testDatasetIndex, testDatasets()...), ShouldBeNil)
+	So(es.AddDocuments(testAreaProfileIndex, testAreaProfiles()...), ShouldBeNil)
+	So(es.AddDocuments(testPublicationIndex, testPublications()...), ShouldBeNil)
+	es.CreateIndex(testPostcodeIndex)
+
+	referenceDocs, err := reference.New(reference.Files{
+		Dimensions:  "../data/dimensions.json",
+		Hierarchies: "../data/hierarchy.json",
+		Taxonomy:    "../data/taxonomy.json",
+	})
+	So(err, ShouldBeNil)
+
+	router := mux.NewRouter()
+	routes(context.Background(),
+		router,
+		es,
+		testMaxResults,
+		testDatasetIndex,
+		testAreaProfileIndex,
+		testPostcodeIndex,
+		testPublicationIndex,
+		http.StatusOK,
+		testAdminAuthToken,
+		referenceDocs,
+		health.New(es, referenceDocs, 0, 0, testDatasetIndex, testAreaProfileIndex, testPostcodeIndex),
+		responseCache,
+		timeouts,
+	)
+
+	return router, es, referenceDocs
+}
+
+// get makes a GET request to the router and returns the response
+func get(router *mux.Router, url string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+
+	return w
+}
+
+// decodeError decodes the single error in an error response
+func decodeError(w *httptest.ResponseRecorder) *errs.Error {
+	var body errs.Errors
+	So(json.Unmarshal(w.Body.Bytes(), &body), ShouldBeNil)
+	So(body.Errors, ShouldHaveLength, 1)
+
+	return body.Errors[0]
+}
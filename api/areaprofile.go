@@ -1,22 +1,21 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"strconv"
 	"strings"
 
 	errs "github.com/ONSdigital/dp-census-alpha-search-api/apierrors"
+	"github.com/ONSdigital/dp-census-alpha-search-api/metrics"
 	"github.com/ONSdigital/dp-census-alpha-search-api/models"
+	"github.com/ONSdigital/dp-census-alpha-search-api/parser"
 	"github.com/ONSdigital/log.go/log"
 	"github.com/gorilla/mux"
 )
 
-const (
-	defaultRelation = "intersects"
-
-	relationError = "invalid relation value"
-)
+const defaultRelation = "intersects"
 
 func (api *SearchAPI) getAreaProfile(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -59,7 +58,6 @@ func (api *SearchAPI) getAreaProfile(w http.ResponseWriter, r *http.Request) {
 	_, err = w.Write(b)
 	if err != nil {
 		log.Event(ctx, "getAreaProfile endpoint: error writing response", log.ERROR, log.Error(err), logData)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 
 	log.Event(ctx, "getAreaProfile endpoint: successfully searched index", log.INFO, logData)
@@ -80,23 +78,32 @@ func (api *SearchAPI) getAreaProfileSearch(w http.ResponseWriter, r *http.Reques
 	q := r.FormValue("q")
 	requestedLimit := r.FormValue("limit")
 	requestedOffset := r.FormValue("offset")
+	requestedCursor := r.FormValue("cursor")
 	dimensions := r.FormValue("dimensions")
 	topics := r.FormValue("topics")
 
 	requestedRelation := r.FormValue("relation")
+	requestedSort := r.FormValue("sort")
 
 	logData := log.Data{
 		"id":                 id,
 		"query_term":         q,
 		"requested_limit":    requestedLimit,
 		"requested_offset":   requestedOffset,
+		"requested_cursor":   requestedCursor,
 		"dimensions":         dimensions,
 		"topics":             topics,
 		"requested_relation": requestedRelation,
+		"requested_sort":     requestedSort,
 	}
 
 	log.Event(ctx, "getAreaProfileSearch endpoint: incoming request", log.INFO, logData)
 
+	key := cacheKey(r, areaProfileSearchRoute, areaProfileSearchCacheParams)
+	if api.writeCachedResponse(ctx, w, r, areaProfileSearchRoute, key) {
+		return
+	}
+
 	// Remove leading and/or trailing whitespace
 	term := strings.TrimSpace(q)
 
@@ -106,14 +113,19 @@ func (api *SearchAPI) getAreaProfileSearch(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	var err error
+	searchQuery, err := parser.Parse(term)
+	if err != nil {
+		log.Event(ctx, "getAreaProfileSearch endpoint: failed to parse query parameter \"q\"", log.ERROR, log.Error(err), logData)
+		setErrorCode(w, err)
+		return
+	}
 
 	limit := defaultLimit
 	if requestedLimit != "" {
 		limit, err = strconv.Atoi(requestedLimit)
 		if err != nil {
 			log.Event(ctx, "getAreaProfileSearch endpoint: request limit parameter error", log.ERROR, log.Error(err), logData)
-			setErrorCode(w, errs.ErrParsingQueryParameters)
+			setErrorCode(w, errs.WithParameter(errs.ErrParsingQueryParameters, "limit"))
 			return
 		}
 	}
@@ -123,7 +135,7 @@ func (api *SearchAPI) getAreaProfileSearch(w http.ResponseWriter, r *http.Reques
 		offset, err = strconv.Atoi(requestedOffset)
 		if err != nil {
 			log.Event(ctx, "getAreaProfileSearch endpoint: request offset parameter error", log.ERROR, log.Error(err), logData)
-			setErrorCode(w, errs.ErrParsingQueryParameters)
+			setErrorCode(w, errs.WithParameter(errs.ErrParsingQueryParameters, "offset"))
 			return
 		}
 	}
@@ -134,6 +146,15 @@ func (api *SearchAPI) getAreaProfileSearch(w http.ResponseWriter, r *http.Reques
 		Offset:            offset,
 	}
 
+	if requestedCursor != "" {
+		page.Cursor, err = models.ParseCursor(requestedCursor)
+		if err != nil {
+			log.Event(ctx, "getAreaProfileSearch endpoint: request cursor parameter error", log.ERROR, log.Error(err), logData)
+			setErrorCode(w, err)
+			return
+		}
+	}
+
 	if err = page.Validate(); err != nil {
 		log.Event(ctx, "getAreaProfileSearch endpoint: validate pagination", log.ERROR, log.Error(err), logData)
 		setErrorCode(w, err)
@@ -150,20 +171,38 @@ func (api *SearchAPI) getAreaProfileSearch(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	dimensionFilters, err := models.ValidateDimensions(dimensions)
+	// Validate filters against a single version of the reference files in case they are reloaded
+	referenceDocs := api.reference.Get()
+
+	dimensionFilters, err := models.ValidateDimensions(dimensions, referenceDocs.Dimensions)
 	if err != nil {
 		log.Event(ctx, "getAreaProfileSearch endpoint: validate dimensions filter", log.ERROR, log.Error(err), logData)
 		setErrorCode(w, err)
 		return
 	}
 
-	topicFilters, err := models.ValidateTopics(topics)
+	topicFilters, err := models.ValidateTopics(topics, referenceDocs.TopicLevels)
 	if err != nil {
 		log.Event(ctx, "getAreaProfileSearch endpoint: validate topics filter", log.ERROR, log.Error(err), logData)
 		setErrorCode(w, err)
 		return
 	}
 
+	sort, err := models.ValidateSort(requestedSort, models.DatasetSortOptions)
+	if err != nil {
+		log.Event(ctx, "getAreaProfileSearch endpoint: validate query param, sort", log.ERROR, log.Error(err), logData)
+		setErrorCode(w, err)
+		return
+	}
+
+	if page.Cursor != nil {
+		if err = page.Cursor.ForSort(sort); err != nil {
+			log.Event(ctx, "getAreaProfileSearch endpoint: cursor was issued for another sort", log.ERROR, log.Error(err), logData)
+			setErrorCode(w, err)
+			return
+		}
+	}
+
 	query := models.AreaProfileQuery{
 		Query: models.Query{
 			Term: map[string]string{
@@ -181,7 +220,8 @@ func (api *SearchAPI) getAreaProfileSearch(w http.ResponseWriter, r *http.Reques
 	}
 
 	// build dataset search query
-	datasetQuery := buildAreaProfileDatasetSearchQuery(&areaProfile.Location, term, dimensionFilters, topicFilters, relation, page.Limit, page.Offset)
+	datasetQuery := buildAreaProfileDatasetSearchQuery(&areaProfile.Location, searchQuery, dimensionFilters, topicFilters, relation, page.Limit, page.Offset, models.BuildSort(sort, nil, referenceDocs.HierarchyLevels))
+	applyCursor(datasetQuery, page, datasetList)
 
 	response, status, err := api.elasticsearch.QuerySearchIndex(ctx, api.datasetIndex, datasetQuery)
 	if err != nil {
@@ -217,6 +257,22 @@ func (api *SearchAPI) getAreaProfileSearch(w http.ResponseWriter, r *http.Reques
 
 	datasets.Count = len(datasets.Items)
 
+	if datasets.TotalCount == 0 {
+		metrics.ZeroResultSearch(areaProfileSearchRoute)
+	}
+
+	datasets.NextCursor, err = getNextCursor(page, map[string]models.SearchResults{
+		datasetList: {
+			Count:       datasets.Count,
+			SearchAfter: lastSortValues(response.Hits.HitList),
+		},
+	}, nil)
+	if err != nil {
+		log.Event(ctx, "getAreaProfileSearch endpoint: failed to create next cursor", log.ERROR, log.Error(err), logData)
+		setErrorCode(w, errs.ErrInternalServer)
+		return
+	}
+
 	b, err := json.Marshal(datasets)
 	if err != nil {
 		log.Event(ctx, "getAreaProfileSearch endpoint: failed to marshal search resource into bytes", log.ERROR, log.Error(err), logData)
@@ -224,16 +280,17 @@ func (api *SearchAPI) getAreaProfileSearch(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	api.cache.Set(key, b)
+
 	_, err = w.Write(b)
 	if err != nil {
 		log.Event(ctx, "getAreaProfileSearch endpoint: error writing response", log.ERROR, log.Error(err), logData)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 
 	log.Event(ctx, "getAreaProfileSearch endpoint: successfully searched index", log.INFO, logData)
 }
 
-func buildAreaProfileDatasetSearchQuery(geoLocation *models.GeoLocation, term string, dimensionFilters []models.Filter, topicFilters []models.Filter, relation string, limit, offset int) *models.Body {
+func buildAreaProfileDatasetSearchQuery(geoLocation *models.GeoLocation, q *parser.Query, dimensionFilters []models.Filter, topicFilters []models.Filter, relation string, limit, offset int, sort []models.Scores) *models.Body {
 	var object models.Object
 	highlight := make(map[string]models.Object)
 
@@ -246,49 +303,164 @@ func buildAreaProfileDatasetSearchQuery(geoLocation *models.GeoLocation, term st
 	highlight["dimensions.label"] = object
 	highlight["dimensions.name"] = object
 
+	query := &models.Body{
+		From: offset,
+		Size: limit,
+		Highlight: &models.Highlight{
+			Fields:   highlight,
+			PreTags:  []string{"<b>"},
+			PostTags: []string{"</b>"},
+		},
+		Query: models.Query{
+			Bool: q.Compile(areaProfileDatasetMatches),
+		},
+		Sort:      sort,
+		TotalHits: true,
+	}
+
+	query.Query.Bool.Filter = []models.Filter{
+		{
+			Shape: &models.GeoShape{
+				Location: models.GeoLocationObj{
+					Shape:    *geoLocation,
+					Relation: relation,
+				},
+			},
+		},
+	}
+
+	if topicFilters != nil {
+		query.Query.Bool.Filter = topicFilters
+	}
+
+	if dimensionFilters != nil && len(dimensionFilters) > 0 {
+		query.Query.Bool.Filter = append(query.Query.Bool.Filter, dimensionFilters...)
+	}
+
+	return query
+}
+
+// areaProfileDatasetMatches creates the list of dataset fields to match text against
+func areaProfileDatasetMatches(text string) []models.Match {
 	alias := make(map[string]string)
 	description := make(map[string]string)
 	title := make(map[string]string)
 	topic1 := make(map[string]string)
 	topic2 := make(map[string]string)
 	topic3 := make(map[string]string)
-	dimensionLabels := make(map[string]string)
-	dimensionNames := make(map[string]string)
-	alias["alias"] = term
-	description["description"] = term
-	title["title"] = term
-	topic1["topic1"] = term
-	topic2["topic2"] = term
-	topic3["topic3"] = term
-	dimensionLabels["dimensions.label"] = term
-	dimensionNames["dimensions.name"] = term
+	alias["alias"] = text
+	description["description"] = text
+	title["title"] = text
+	topic1["topic1"] = text
+	topic2["topic2"] = text
+	topic3["topic3"] = text
+
+	return []models.Match{
+		{
+			Match: alias,
+		},
+		{
+			Match: description,
+		},
+		{
+			Match: title,
+		},
+		{
+			Match: topic1,
+		},
+		{
+			Match: topic2,
+		},
+		{
+			Match: topic3,
+		},
+		models.DimensionMatch(text),
+	}
+}
+
+func (api *SearchAPI) getAreaProfiles(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	setAccessControl(w, http.MethodGet)
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	requestedLat := r.FormValue("lat")
+	requestedLon := r.FormValue("lon")
+
+	logData := log.Data{
+		"requested_lat": requestedLat,
+		"requested_lon": requestedLon,
+	}
+
+	log.Event(ctx, "getAreaProfiles endpoint: incoming request", log.INFO, logData)
+
+	pin, err := models.ValidateLatLon(requestedLat, requestedLon)
+	if err != nil {
+		log.Event(ctx, "getAreaProfiles endpoint: validate lat and lon", log.ERROR, log.Error(err), logData)
+		setErrorCode(w, err)
+		return
+	}
 
-	aliasMatch := models.Match{
-		Match: alias,
+	areaProfiles, status, err := api.getAreaProfilesContainingPoint(ctx, *pin)
+	if err != nil {
+		logData["elasticsearch_status"] = status
+		log.Event(ctx, "getAreaProfiles endpoint: failed to get area profiles containing point", log.ERROR, log.Error(err), logData)
+		setErrorCode(w, err)
+		return
 	}
 
-	descriptionMatch := models.Match{
-		Match: description,
+	result := models.LocationResult{
+		Pin:          *pin,
+		AreaProfiles: *areaProfiles,
 	}
 
-	titleMatch := models.Match{
-		Match: title,
+	b, err := json.Marshal(result)
+	if err != nil {
+		log.Event(ctx, "getAreaProfiles endpoint: failed to marshal area profiles resource into bytes", log.ERROR, log.Error(err), logData)
+		setErrorCode(w, errs.ErrInternalServer)
+		return
 	}
 
-	topic1Match := models.Match{
-		Match: topic1,
+	_, err = w.Write(b)
+	if err != nil {
+		log.Event(ctx, "getAreaProfiles endpoint: error writing response", log.ERROR, log.Error(err), logData)
+	}
+
+	log.Event(ctx, "getAreaProfiles endpoint: successfully searched index", log.INFO, logData)
+}
+
+// getAreaProfilesContainingPoint finds all area profiles whose boundary contains the point,
+// ordered by hierarchy level from country down to output area
+func (api *SearchAPI) getAreaProfilesContainingPoint(ctx context.Context, pin models.PinLocation) (*models.SearchResults, int, error) {
+	query := buildAreaProfilesContainingPointQuery(pin)
+
+	response, status, err := api.elasticsearch.QuerySearchIndex(ctx, api.areaProfileIndex, query)
+	if err != nil {
+		return nil, status, err
 	}
 
-	topic2Match := models.Match{
-		Match: topic2,
+	areaProfiles := &models.SearchResults{
+		TotalCount: response.Hits.Total,
+		Items:      []models.SearchResult{},
 	}
 
-	topic3Match := models.Match{
-		Match: topic3,
+	for _, result := range response.Hits.HitList {
+		areaProfiles.Items = append(areaProfiles.Items, result.Source)
 	}
 
+	models.SortByHierarchyLevel(areaProfiles.Items, api.reference.Get().HierarchyLevels)
+
+	areaProfiles.Count = len(areaProfiles.Items)
+
+	return areaProfiles, status, nil
+}
+
+func buildAreaProfilesContainingPointQuery(pin models.PinLocation) *models.Body {
 	scores := models.Scores{
-		Score: models.Score{
+		Score: &models.Score{
 			Order: "desc",
 		},
 	}
@@ -296,61 +468,30 @@ func buildAreaProfileDatasetSearchQuery(geoLocation *models.GeoLocation, term st
 	listOfScores := []models.Scores{}
 	listOfScores = append(listOfScores, scores)
 
-	query := &models.Body{
-		From: offset,
-		Size: limit,
-		Highlight: &models.Highlight{
-			Fields:   highlight,
-			PreTags:  []string{"<b>"},
-			PostTags: []string{"</b>"},
-		},
+	return &models.Body{
+		From: 0,
+		Size: defaultLimit,
 		Query: models.Query{
 			Bool: &models.Bool{
-				Should: []models.Match{
-					aliasMatch,
-					descriptionMatch,
-					titleMatch,
-					topic1Match,
-					topic2Match,
-					topic3Match,
-					{
-						Nested: &models.Nested{
-							Path: "dimensions",
-							Query: []models.NestedQuery{
-								{
-									Term: dimensionLabels,
-								},
-								{
-									Term: dimensionNames,
-								},
-							},
-						},
-					},
-				},
-				MinimumShouldMatch: 1,
 				Filter: []models.Filter{
 					{
 						Shape: &models.GeoShape{
 							Location: models.GeoLocationObj{
-								Shape:    *geoLocation,
-								Relation: relation,
+								Shape: models.GeoLocation{
+									Type:        "point",
+									Coordinates: []float64{pin.Lon, pin.Lat},
+								},
+								Relation: "intersects",
 							},
 						},
 					},
 				},
 			},
 		},
+		Source: &models.SourceFilter{
+			Excludes: []string{"location"},
+		},
 		Sort:      listOfScores,
 		TotalHits: true,
 	}
-
-	if topicFilters != nil {
-		query.Query.Bool.Filter = topicFilters
-	}
-
-	if dimensionFilters != nil && len(dimensionFilters) > 0 {
-		query.Query.Bool.Filter = append(query.Query.Bool.Filter, dimensionFilters...)
-	}
-
-	return query
 }
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	errs "github.com/ONSdigital/dp-census-alpha-search-api/apierrors"
+	"github.com/ONSdigital/dp-census-alpha-search-api/models"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestGetAreaProfile(t *testing.T) {
+	Convey("Given a search api with area profiles", t, func() {
+		router, es := setupAPI()
+
+		Convey("When an area profile that exists is requested", func() {
+			w := get(router, "/area-profiles/W06000015")
+
+			Convey("Then the area profile is returned", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+
+				var areaProfile models.AreaProfile
+				So(json.Unmarshal(w.Body.Bytes(), &areaProfile), ShouldBeNil)
+				So(areaProfile.Name, ShouldEqual, "Cardiff")
+				So(areaProfile.Hierarchy, ShouldEqual, "Major Towns and Cities")
+			})
+		})
+
+		Convey("When an area profile that does not exist is requested", func() {
+			w := get(router, "/area-profiles/W00000000")
+
+			Convey("Then a not found error is returned", func() {
+				So(w.Code, ShouldEqual, http.StatusNotFound)
+				So(decodeError(w).Code, ShouldEqual, errs.ErrAreaProfileNotFound.Code)
+			})
+		})
+
+		Convey("When the area profile index fails", func() {
+			es.Fail(testAreaProfileIndex, http.StatusInternalServerError, errs.ErrIndexNotFound)
+			w := get(router, "/area-profiles/W06000015")
+
+			Convey("Then an internal server error is returned", func() {
+				So(w.Code, ShouldEqual, http.StatusInternalServerError)
+				So(decodeError(w).Code, ShouldEqual, errs.ErrInternalServer.Code)
+			})
+		})
+	})
+}
+
+func TestGetAreaProfileSearch(t *testing.T) {
+	Convey("Given a search api with datasets in different areas", t, func() {
+		router, es := setupAPI()
+
+		Convey("When searching for datasets within a city", func() {
+			w := get(router, "/area-profiles/W06000015/search?q=deaths")
+
+			Convey("Then only the matching datasets that overlap the city are returned", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+
+				var results models.DatasetSearchResults
+				So(json.Unmarshal(w.Body.Bytes(), &results), ShouldBeNil)
+				So(results.TotalCount, ShouldEqual, 1)
+				So(results.Items[0].Title, ShouldEqual, "Deaths registered by sex")
+			})
+		})
+
+		Convey("When searching for datasets within a country", func() {
+			w := get(router, "/area-profiles/E92000001/search?q=deaths&relation=within")
+
+			Convey("Then the matching datasets within the country are returned", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+
+				var results models.DatasetSearchResults
+				So(json.Unmarshal(w.Body.Bytes(), &results), ShouldBeNil)
+				So(results.TotalCount, ShouldEqual, 2)
+			})
+		})
+
+		Convey("When searching within an area profile that does not exist", func() {
+			w := get(router, "/area-profiles/W00000000/search?q=deaths")
+
+			Convey("Then a not found error is returned", func() {
+				So(w.Code, ShouldEqual, http.StatusNotFound)
+				So(decodeError(w).Code, ShouldEqual, errs.ErrAreaProfileNotFound.Code)
+			})
+		})
+
+		Convey("When searching with an invalid relation", func() {
+			w := get(router, "/area-profiles/W06000015/search?q=deaths&relation=overlaps")
+
+			Convey("Then a bad request is returned", func() {
+				So(w.Code, ShouldEqual, http.StatusBadRequest)
+
+				err := decodeError(w)
+				So(err.Code, ShouldEqual, errs.CodeInvalidRelation)
+				So(err.Parameter, ShouldEqual, "relation")
+			})
+		})
+
+		Convey("When searching without a search term", func() {
+			w := get(router, "/area-profiles/W06000015/search")
+
+			Convey("Then a bad request is returned", func() {
+				So(w.Code, ShouldEqual, http.StatusBadRequest)
+				So(decodeError(w).Code, ShouldEqual, errs.ErrEmptySearchTerm.Code)
+			})
+		})
+
+		Convey("When the dataset index fails", func() {
+			es.Fail(testDatasetIndex, http.StatusInternalServerError, errs.ErrIndexNotFound)
+			w := get(router, "/area-profiles/W06000015/search?q=deaths")
+
+			Convey("Then an internal server error is returned", func() {
+				So(w.Code, ShouldEqual, http.StatusInternalServerError)
+				So(decodeError(w).Code, ShouldEqual, errs.ErrInternalServer.Code)
+			})
+		})
+	})
+}
@@ -0,0 +1,260 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	errs "github.com/ONSdigital/dp-census-alpha-search-api/apierrors"
+	"github.com/ONSdigital/dp-census-alpha-search-api/models"
+	"github.com/ONSdigital/log.go/log"
+)
+
+const (
+	defaultAutocompleteDatasetLimit     = 5
+	defaultAutocompleteAreaProfileLimit = 5
+	defaultAutocompletePostcodeLimit    = 3
+	maximumAutocompleteLimit            = 10
+
+	datasetDocType     = "dataset"
+	areaProfileDocType = "area_profile"
+	postcodeDocType    = "postcode"
+)
+
+func (api *SearchAPI) getAutocomplete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	setAccessControl(w, http.MethodGet)
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	q := r.FormValue("q")
+	requestedDatasetLimit := r.FormValue("datasets_limit")
+	requestedAreaProfileLimit := r.FormValue("area_profiles_limit")
+	requestedPostcodeLimit := r.FormValue("postcodes_limit")
+
+	logData := log.Data{
+		"query_term":                    q,
+		"requested_datasets_limit":      requestedDatasetLimit,
+		"requested_area_profiles_limit": requestedAreaProfileLimit,
+		"requested_postcodes_limit":     requestedPostcodeLimit,
+	}
+
+	log.Event(ctx, "getAutocomplete endpoint: incoming request", log.INFO, logData)
+
+	// Remove leading and/or trailing whitespace
+	term := strings.TrimSpace(q)
+
+	if term == "" {
+		log.Event(ctx, "getAutocomplete endpoint: query parameter \"q\" empty", log.ERROR, log.Error(errs.ErrEmptySearchTerm), logData)
+		setErrorCode(w, errs.ErrEmptySearchTerm)
+		return
+	}
+
+	datasetLimit, err := getAutocompleteLimit("datasets_limit", requestedDatasetLimit, defaultAutocompleteDatasetLimit)
+	if err != nil {
+		log.Event(ctx, "getAutocomplete endpoint: request datasets_limit parameter error", log.ERROR, log.Error(err), logData)
+		setErrorCode(w, err)
+		return
+	}
+
+	areaProfileLimit, err := getAutocompleteLimit("area_profiles_limit", requestedAreaProfileLimit, defaultAutocompleteAreaProfileLimit)
+	if err != nil {
+		log.Event(ctx, "getAutocomplete endpoint: request area_profiles_limit parameter error", log.ERROR, log.Error(err), logData)
+		setErrorCode(w, err)
+		return
+	}
+
+	postcodeLimit, err := getAutocompleteLimit("postcodes_limit", requestedPostcodeLimit, defaultAutocompletePostcodeLimit)
+	if err != nil {
+		log.Event(ctx, "getAutocomplete endpoint: request postcodes_limit parameter error", log.ERROR, log.Error(err), logData)
+		setErrorCode(w, err)
+		return
+	}
+
+	// Postcodes are stored lower cased with all whitespace removed
+	postcodeTerm := strings.ToLower(strings.Join(strings.Fields(term), ""))
+
+	var (
+		datasetChan     = make(chan []models.AutocompleteResult, 1)
+		areaProfileChan = make(chan []models.AutocompleteResult, 1)
+		postcodeChan    = make(chan []models.AutocompleteResult, 1)
+
+		datasetReqError, areaProfileReqError, postcodeReqError error
+	)
+
+	// find dataset titles
+	go func() {
+		query := buildAutocompleteQuery("title.autocomplete", term, []string{"title", "links", "doc_type"}, datasetLimit)
+
+		response, status, err := api.elasticsearch.QuerySearchIndex(ctx, api.datasetIndex, query)
+		if err != nil {
+			log.Event(ctx, "getAutocomplete endpoint: failed to get dataset suggestions", log.ERROR, log.Error(err), withStatus(logData, status))
+			datasetReqError = err
+			datasetChan <- nil
+			return
+		}
+
+		var datasets []models.AutocompleteResult
+		for _, result := range response.Hits.HitList {
+			links := result.Source.Links
+			datasets = append(datasets, models.AutocompleteResult{
+				DocType: datasetDocType,
+				Text:    result.Source.Title,
+				Links:   &links,
+			})
+		}
+
+		datasetChan <- datasets
+	}()
+
+	// find area names
+	go func() {
+		query := buildAutocompleteQuery("name.autocomplete", term, []string{"id", "name", "links", "doc_type"}, areaProfileLimit)
+
+		response, status, err := api.elasticsearch.QuerySearchIndex(ctx, api.areaProfileIndex, query)
+		if err != nil {
+			log.Event(ctx, "getAutocomplete endpoint: failed to get area profile suggestions", log.ERROR, log.Error(err), withStatus(logData, status))
+			areaProfileReqError = err
+			areaProfileChan <- nil
+			return
+		}
+
+		var areaProfiles []models.AutocompleteResult
+		for _, result := range response.Hits.HitList {
+			links := result.Source.Links
+			areaProfiles = append(areaProfiles, models.AutocompleteResult{
+				DocType: areaProfileDocType,
+				Text:    result.Source.Name,
+				ID:      result.Source.ID,
+				Links:   &links,
+			})
+		}
+
+		areaProfileChan <- areaProfiles
+	}()
+
+	// find postcodes
+	go func() {
+		query := buildAutocompleteQuery("postcode.autocomplete", postcodeTerm, []string{"postcode", "postcode_raw"}, postcodeLimit)
+
+		response, status, err := api.elasticsearch.QuerySearchIndex(ctx, api.postcodeIndex, query)
+		if err != nil {
+			log.Event(ctx, "getAutocomplete endpoint: failed to get postcode suggestions", log.ERROR, log.Error(err), withStatus(logData, status))
+			postcodeReqError = err
+			postcodeChan <- nil
+			return
+		}
+
+		var postcodes []models.AutocompleteResult
+		for _, result := range response.Hits.HitList {
+			postcodes = append(postcodes, models.AutocompleteResult{
+				DocType: postcodeDocType,
+				Text:    result.Source.PostcodeRaw,
+				ID:      result.Source.Postcode,
+			})
+		}
+
+		postcodeChan <- postcodes
+	}()
+
+	// Wait till we have results from all suggestion requests
+	datasets := <-datasetChan
+	areaProfiles := <-areaProfileChan
+	postcodes := <-postcodeChan
+
+	// handle any request errors from suggestion queries
+	if datasetReqError != nil {
+		setErrorCode(w, datasetReqError)
+		return
+	}
+
+	if areaProfileReqError != nil {
+		setErrorCode(w, areaProfileReqError)
+		return
+	}
+
+	if postcodeReqError != nil {
+		setErrorCode(w, postcodeReqError)
+		return
+	}
+
+	results := models.AutocompleteResults{
+		Items: []models.AutocompleteResult{},
+	}
+
+	results.Items = append(results.Items, datasets...)
+	results.Items = append(results.Items, areaProfiles...)
+	results.Items = append(results.Items, postcodes...)
+	results.Count = len(results.Items)
+
+	b, err := json.Marshal(results)
+	if err != nil {
+		log.Event(ctx, "getAutocomplete endpoint: failed to marshal autocomplete resource into bytes", log.ERROR, log.Error(err), logData)
+		setErrorCode(w, errs.ErrInternalServer)
+		return
+	}
+
+	_, err = w.Write(b)
+	if err != nil {
+		log.Event(ctx, "getAutocomplete endpoint: error writing response", log.ERROR, log.Error(err), logData)
+	}
+
+	log.Event(ctx, "getAutocomplete endpoint: successfully searched indexes", log.INFO, logData)
+}
+
+func getAutocompleteLimit(parameter, requestedLimit string, defaultLimit int) (int, error) {
+	if requestedLimit == "" {
+		return defaultLimit, nil
+	}
+
+	limit, err := strconv.Atoi(requestedLimit)
+	if err != nil {
+		return 0, errs.WithParameter(errs.ErrParsingQueryParameters, parameter)
+	}
+
+	if limit < 0 {
+		return 0, errs.WithParameter(errs.ErrNegativeLimit, parameter)
+	}
+
+	if limit > maximumAutocompleteLimit {
+		return 0, errs.WithParameter(models.ErrorMaximumLimitReached(maximumAutocompleteLimit), parameter)
+	}
+
+	return limit, nil
+}
+
+func buildAutocompleteQuery(field, term string, includes []string, limit int) *models.Body {
+	match := make(map[string]string)
+	match[field] = term
+
+	scores := models.Scores{
+		Score: &models.Score{
+			Order: "desc",
+		},
+	}
+
+	listOfScores := []models.Scores{}
+	listOfScores = append(listOfScores, scores)
+
+	return &models.Body{
+		From: 0,
+		Size: limit,
+		Query: models.Query{
+			Bool: &models.Bool{
+				Must: []models.Match{
+					{
+						Match: match,
+					},
+				},
+			},
+		},
+		Source: &models.SourceFilter{
+			Includes: includes,
+		},
+		Sort: listOfScores,
+	}
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/ONSdigital/dp-census-alpha-search-api/metrics"
+	"github.com/ONSdigital/dp-census-alpha-search-api/reference"
+	"github.com/ONSdigital/log.go/log"
+	"github.com/gorilla/mux"
+)
+
+// cacheHeader tells clients whether a response was served from the cache
+const cacheHeader = "X-Cache"
+
+// Values of the cache header
+const (
+	cacheHit    = "HIT"
+	cacheMiss   = "MISS"
+	cacheBypass = "BYPASS"
+)
+
+// Query parameters that change the response of each cached route
+var (
+	searchCacheParams            = []string{"q", "limit", "offset", "cursor", "dimensions", "hierarchies", "topics", "distance", "relation", "autocorrect", "sort"}
+	areaProfileSearchCacheParams = []string{"q", "limit", "offset", "cursor", "dimensions", "topics", "relation", "sort"}
+)
+
+// listParams are comma separated lists of filters that return the same results in any order
+var listParams = map[string]bool{
+	"dimensions":  true,
+	"hierarchies": true,
+	"topics":      true,
+}
+
+// cacheKey creates the key of the response to a request from the route, the path variables and
+// the normalised values of the query parameters that change the response
+func cacheKey(r *http.Request, route string, params []string) string {
+	values := url.Values{}
+
+	for name, value := range mux.Vars(r) {
+		values.Set(":"+name, value)
+	}
+
+	for _, param := range params {
+		if value := normaliseParam(param, r.FormValue(param)); value != "" {
+			values.Set(param, value)
+		}
+	}
+
+	return route + "?" + values.Encode()
+}
+
+// normaliseParam removes differences in query parameter values that do not change the response,
+// such as extra whitespace in the search term or the order of filters
+func normaliseParam(param, value string) string {
+	if param == "q" {
+		return strings.Join(strings.Fields(value), " ")
+	}
+
+	value = strings.TrimSpace(value)
+	if !listParams[param] || value == "" {
+		return value
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	sort.Strings(items)
+
+	return strings.Join(items, ",")
+}
+
+// writeCachedResponse writes the cached response for the key, returning false if the response
+// is not cached. Clients can send Cache-Control: no-cache to skip the cache.
+func (api *SearchAPI) writeCachedResponse(ctx context.Context, w http.ResponseWriter, r *http.Request, route, key string) bool {
+	if api.cache == nil {
+		return false
+	}
+
+	if noCache(r) {
+		metrics.CacheLookup(route, metrics.CacheBypass)
+		w.Header().Set(cacheHeader, cacheBypass)
+		return false
+	}
+
+	b, ok := api.cache.Get(key)
+	if !ok {
+		metrics.CacheLookup(route, metrics.CacheMiss)
+		w.Header().Set(cacheHeader, cacheMiss)
+		return false
+	}
+
+	metrics.CacheLookup(route, metrics.CacheHit)
+	w.Header().Set(cacheHeader, cacheHit)
+
+	if _, err := w.Write(b); err != nil {
+		log.Event(ctx, "error writing cached response", log.ERROR, log.Error(err), log.Data{"route": route})
+	}
+
+	log.Event(ctx, "returned cached response", log.INFO, log.Data{"route": route, "cache_key": key})
+
+	return true
+}
+
+// noCache checks whether the client asked for a response that has not been cached
+func noCache(r *http.Request) bool {
+	for _, directive := range strings.Split(r.Header.Get("Cache-Control"), ",") {
+		if strings.EqualFold(strings.TrimSpace(directive), "no-cache") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// purgeCacheOnReload removes every cached response when the reference files are reloaded, as
+// responses depend on the filters in the files
+func (api *SearchAPI) purgeCacheOnReload() {
+	if api.cache == nil || api.reference == nil {
+		return
+	}
+
+	api.reference.OnReload(func(_ *reference.Docs) {
+		api.cache.Purge()
+		metrics.CachePurged(metrics.CacheReload)
+	})
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-census-alpha-search-api/cache"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// request makes a request to the router with the headers and returns the response
+func request(router http.Handler, method, url string, headers map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, url, nil)
+	for name, value := range headers {
+		r.Header.Set(name, value)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+
+	return w
+}
+
+func TestCacheKey(t *testing.T) {
+	Convey("Given requests for the same search written differently", t, func() {
+		first := httptest.NewRequest(http.MethodGet, "/search?q=deaths%20%20by+sex&topics=economy,+peoplepopulationandcommunity&limit=10", nil)
+		second := httptest.NewRequest(http.MethodGet, "/search?limit=10&topics=peoplepopulationandcommunity,economy,&q=+deaths+by+sex+", nil)
+		other := httptest.NewRequest(http.MethodGet, "/search?q=deaths+by+sex&topics=economy&limit=10", nil)
+
+		Convey("When the cache keys are created", func() {
+			firstKey := cacheKey(first, searchRoute, searchCacheParams)
+
+			Convey("Then the requests share a key that differs from a different search", func() {
+				So(cacheKey(second, searchRoute, searchCacheParams), ShouldEqual, firstKey)
+				So(cacheKey(other, searchRoute, searchCacheParams), ShouldNotEqual, firstKey)
+			})
+		})
+	})
+}
+
+func TestSearchCache(t *testing.T) {
+	Convey("Given a search api that caches responses", t, func() {
+		router, es, referenceDocs := setupAPIWithCache(cache.New(10, time.Minute))
+
+		first := get(router, "/search?q=deaths")
+		So(first.Code, ShouldEqual, http.StatusOK)
+
+		Convey("When the first search is made", func() {
+			Convey("Then the response is not from the cache", func() {
+				So(first.Header().Get(cacheHeader), ShouldEqual, cacheMiss)
+			})
+		})
+
+		Convey("When the same search is repeated with different whitespace", func() {
+			w := get(router, "/search?q=+deaths++")
+
+			Convey("Then the cached response is returned", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(w.Header().Get(cacheHeader), ShouldEqual, cacheHit)
+				So(w.Body.String(), ShouldEqual, first.Body.String())
+			})
+		})
+
+		Convey("When a document is indexed and the search is repeated with Cache-Control: no-cache", func() {
+			So(es.AddDocuments(testDatasetIndex, map[string]interface{}{
+				"id":       "deaths-by-age",
+				"doc_type": "dataset",
+				"title":    "Deaths registered by age",
+			}), ShouldBeNil)
+
+			w := request(router, http.MethodGet, "/search?q=deaths", map[string]string{"Cache-Control": "no-cache"})
+
+			Convey("Then the latest response is returned and replaces the cached response", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(w.Header().Get(cacheHeader), ShouldEqual, cacheBypass)
+				So(w.Body.String(), ShouldNotEqual, first.Body.String())
+
+				cached := get(router, "/search?q=deaths")
+				So(cached.Header().Get(cacheHeader), ShouldEqual, cacheHit)
+				So(cached.Body.String(), ShouldEqual, w.Body.String())
+			})
+		})
+
+		Convey("When the reference files are reloaded", func() {
+			_, err := referenceDocs.Reload(context.Background())
+			So(err, ShouldBeNil)
+
+			Convey("Then the cached response is purged", func() {
+				So(get(router, "/search?q=deaths").Header().Get(cacheHeader), ShouldEqual, cacheMiss)
+			})
+		})
+
+		Convey("When the cache is purged by the admin endpoint", func() {
+			w := request(router, http.MethodDelete, "/admin/cache", map[string]string{"Authorization": bearerPrefix + testAdminAuthToken})
+
+			Convey("Then the cached response is purged", func() {
+				So(w.Code, ShouldEqual, http.StatusNoContent)
+				So(get(router, "/search?q=deaths").Header().Get(cacheHeader), ShouldEqual, cacheMiss)
+			})
+		})
+
+		Convey("When the admin endpoint is called without the admin auth token", func() {
+			w := request(router, http.MethodDelete, "/admin/cache", nil)
+
+			Convey("Then the request is unauthorised and the cached response is kept", func() {
+				So(w.Code, ShouldEqual, http.StatusUnauthorized)
+				So(get(router, "/search?q=deaths").Header().Get(cacheHeader), ShouldEqual, cacheHit)
+			})
+		})
+
+		Convey("When an area profile search is repeated", func() {
+			miss := get(router, "/area-profiles/W06000015/search?q=deaths")
+			hit := get(router, "/area-profiles/W06000015/search?q=deaths")
+			other := get(router, "/area-profiles/E92000001/search?q=deaths")
+
+			Convey("Then the cached response is only returned for the same area profile", func() {
+				So(miss.Code, ShouldEqual, http.StatusOK)
+				So(miss.Header().Get(cacheHeader), ShouldEqual, cacheMiss)
+				So(hit.Header().Get(cacheHeader), ShouldEqual, cacheHit)
+				So(hit.Body.String(), ShouldEqual, miss.Body.String())
+				So(other.Header().Get(cacheHeader), ShouldEqual, cacheMiss)
+			})
+		})
+	})
+
+	Convey("Given a search api without a cache", t, func() {
+		router, _ := setupAPI()
+
+		Convey("When a search is made", func() {
+			w := get(router, "/search?q=deaths")
+
+			Convey("Then the cache header is not set", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(w.Header().Get(cacheHeader), ShouldBeEmpty)
+			})
+		})
+	})
+}
@@ -19,7 +19,7 @@ func (api *SearchAPI) getDimensions(w http.ResponseWriter, r *http.Request) {
 
 	log.Event(ctx, "getDimensions endpoint: incoming request", log.INFO)
 
-	b, err := json.Marshal(api.dimensions)
+	b, err := json.Marshal(api.reference.Get().Dimensions)
 	if err != nil {
 		log.Event(ctx, "getDimensions endpoint: failed to marshal dimensions resource into bytes", log.ERROR, log.Error(err))
 		setErrorCode(w, errs.ErrInternalServer)
@@ -28,7 +28,6 @@ func (api *SearchAPI) getDimensions(w http.ResponseWriter, r *http.Request) {
 	_, err = w.Write(b)
 	if err != nil {
 		log.Event(ctx, "getDimensions endpoint: error writing response", log.ERROR, log.Error(err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 
 	log.Event(ctx, "getDimensions endpoint: successfully searched index", log.INFO)
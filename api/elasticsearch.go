@@ -11,4 +11,6 @@ type Elasticsearcher interface {
 	GetAreaProfile(ctx context.Context, indexName string, query interface{}) (*models.AreaProfile, int, error)
 	QuerySearchIndex(ctx context.Context, indexName string, query interface{}) (*models.SearchResponse, int, error)
 	GetPostcodes(ctx context.Context, indexName, postcode string) (*models.PostcodeResponse, int, error)
+	IndexDocument(ctx context.Context, indexName, id string, document interface{}) (int, error)
+	DeleteDocument(ctx context.Context, indexName, id string) (int, error)
 }
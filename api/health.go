@@ -0,0 +1,31 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	errs "github.com/ONSdigital/dp-census-alpha-search-api/apierrors"
+	"github.com/ONSdigital/dp-census-alpha-search-api/health"
+	"github.com/ONSdigital/log.go/log"
+)
+
+func (api *SearchAPI) getHealth(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+
+	result := api.health.Get(ctx)
+
+	b, err := json.Marshal(result)
+	if err != nil {
+		log.Event(ctx, "getHealth endpoint: failed to marshal health into bytes", log.ERROR, log.Error(err))
+		setErrorCode(w, errs.ErrInternalServer)
+		return
+	}
+
+	w.WriteHeader(health.StatusCode(result.Status))
+
+	if _, err = w.Write(b); err != nil {
+		log.Event(ctx, "getHealth endpoint: error writing response", log.ERROR, log.Error(err))
+	}
+}
@@ -0,0 +1,31 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/ONSdigital/dp-census-alpha-search-api/health"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestGetHealth(t *testing.T) {
+	Convey("Given a search api with an empty postcode index", t, func() {
+		router, _ := setupAPI()
+
+		Convey("When the health is requested", func() {
+			w := get(router, "/health")
+
+			Convey("Then a warning is returned with the result of each check", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+
+				var result health.Result
+				So(json.Unmarshal(w.Body.Bytes(), &result), ShouldBeNil)
+				So(result.Status, ShouldEqual, health.StatusWarning)
+				So(result.Checks, ShouldHaveLength, 5)
+				So(result.Checks[3].Name, ShouldEqual, testPostcodeIndex+" index")
+				So(result.Checks[3].Status, ShouldEqual, health.StatusWarning)
+			})
+		})
+	})
+}
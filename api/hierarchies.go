@@ -19,7 +19,7 @@ func (api *SearchAPI) getHierarchies(w http.ResponseWriter, r *http.Request) {
 
 	log.Event(ctx, "getHierarchies endpoint: incoming request", log.INFO)
 
-	b, err := json.Marshal(api.hierarchies)
+	b, err := json.Marshal(api.reference.Get().Hierarchies)
 	if err != nil {
 		log.Event(ctx, "getHierarchies endpoint: failed to marshal hierarchies resource into bytes", log.ERROR, log.Error(err))
 		setErrorCode(w, errs.ErrInternalServer)
@@ -28,7 +28,6 @@ func (api *SearchAPI) getHierarchies(w http.ResponseWriter, r *http.Request) {
 	_, err = w.Write(b)
 	if err != nil {
 		log.Event(ctx, "getHierarchies endpoint: error writing response", log.ERROR, log.Error(err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 
 	log.Event(ctx, "getHierarchies endpoint: successfully retrieved geography hierarchies", log.INFO)
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMetrics(t *testing.T) {
+	Convey("Given a search api", t, func() {
+		router, _ := setupAPI()
+
+		Convey("When a search containing an unknown postcode does not find any results", func() {
+			w := get(router, "/search?q=nothing%20near%20cf10%201aa")
+			So(w.Code, ShouldEqual, http.StatusOK)
+
+			Convey("Then the request, zero result search and postcode miss are exposed as metrics", func() {
+				w := get(router, "/metrics")
+				So(w.Code, ShouldEqual, http.StatusOK)
+
+				body := w.Body.String()
+				So(body, ShouldContainSubstring, `search_api_http_requests_total{method="GET",route="/search",status="200"}`)
+				So(body, ShouldContainSubstring, `search_api_http_request_duration_seconds_bucket{method="GET",route="/search",status="200",le="0.005"}`)
+				So(body, ShouldContainSubstring, `search_api_zero_result_searches_total{route="/search"}`)
+				So(body, ShouldContainSubstring, `search_api_postcode_lookups_total{result="miss"}`)
+			})
+		})
+
+		Convey("When a search containing an unknown postcode is made", func() {
+			before := metricValue(router, `search_api_postcode_lookups_total{result="miss"}`)
+			w := get(router, "/search?q=deaths%20cf10%201aa")
+			So(w.Code, ShouldEqual, http.StatusOK)
+
+			Convey("Then the postcode is only looked up once for the request", func() {
+				So(metricValue(router, `search_api_postcode_lookups_total{result="miss"}`), ShouldEqual, before+1)
+			})
+		})
+
+		Convey("When an area profile is requested", func() {
+			get(router, "/area-profiles/W00000000")
+
+			Convey("Then the request is recorded against the route template", func() {
+				body := get(router, "/metrics").Body.String()
+				So(body, ShouldContainSubstring, `search_api_http_requests_total{method="GET",route="/area-profiles/{id}",status="404"}`)
+			})
+		})
+	})
+}
+
+// metricValue returns the value of the metric series exposed by the api, 0 is returned if the
+// series has not been recorded
+func metricValue(router *mux.Router, series string) float64 {
+	for _, line := range strings.Split(get(router, "/metrics").Body.String(), "\n") {
+		if strings.HasPrefix(line, series+" ") {
+			value, err := strconv.ParseFloat(strings.TrimPrefix(line, series+" "), 64)
+			So(err, ShouldBeNil)
+			return value
+		}
+	}
+
+	return 0
+}
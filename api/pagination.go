@@ -0,0 +1,91 @@
+package api
+
+import (
+	"github.com/ONSdigital/dp-census-alpha-search-api/models"
+)
+
+// Names of each list of search results tracked by a cursor
+const (
+	allList         = "all"
+	datasetList     = "datasets"
+	areaProfileList = "area_profiles"
+	publicationList = "publications"
+)
+
+// applyCursor replaces offset based paging on a query with search_after based paging when a
+// cursor has been requested, adding tiebreakers to the sort so that the order is stable. The
+// tiebreakers are the keyword fields that identify documents in each index, as sorting on _id
+// is deprecated in elasticsearch 7 and disabled by default in elasticsearch 8.
+func applyCursor(query *models.Body, page *models.PageVariables, list string) {
+	if page.Cursor == nil {
+		return
+	}
+
+	query.From = 0
+	query.Sort = append(query.Sort,
+		models.Scores{
+			Index: &models.Score{
+				Order: "asc",
+			},
+		},
+		models.Scores{
+			Alias: &models.Score{
+				Order:        "asc",
+				Missing:      "_last",
+				UnmappedType: "keyword",
+			},
+		},
+		models.Scores{
+			ID: &models.Score{
+				Order:        "asc",
+				Missing:      "_last",
+				UnmappedType: "keyword",
+			},
+		},
+	)
+
+	// Still query a list that has been paged through to return total counts and aggregations
+	if page.Cursor.IsDone(list) {
+		query.Size = 0
+		return
+	}
+
+	query.SearchAfter = page.Cursor.SearchAfter[list]
+}
+
+// lastSortValues returns the sort values of the last hit to page on from
+func lastSortValues(hits []models.HitList) []interface{} {
+	if len(hits) < 1 {
+		return nil
+	}
+
+	return hits[len(hits)-1].Sort
+}
+
+// getNextCursor creates the cursor for the next page of results, an empty string
+// is returned once all lists of search results have been paged through. Lists that
+// failed to be retrieved stay at the position of the requested cursor.
+func getNextCursor(page *models.PageVariables, lists map[string]models.SearchResults, listErrors map[string]error) (string, error) {
+	if page.Cursor == nil {
+		return "", nil
+	}
+
+	next := models.NewCursor()
+	next.Sort = page.Cursor.Sort
+
+	var names []string
+	for list, results := range lists {
+		if listErrors[list] != nil {
+			next.Retain(list, page.Cursor)
+		} else {
+			next.Advance(list, page.Cursor, results, page.Limit)
+		}
+		names = append(names, list)
+	}
+
+	if next.IsComplete(names...) {
+		return "", nil
+	}
+
+	return next.Encode()
+}
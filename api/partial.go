@@ -0,0 +1,82 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/ONSdigital/dp-census-alpha-search-api/models"
+)
+
+// searchLists are the lists of search results returned by search in the order errors are reported
+var searchLists = []string{allList, datasetList, areaProfileList, publicationList}
+
+// setPartialResults marks search results as partial when some lists of search results failed to
+// be retrieved, an error is returned instead if none of the lists could be retrieved
+func setPartialResults(searchResults *models.AllSearchResults, listErrors map[string]error) error {
+	var firstErr error
+
+	for _, list := range searchLists {
+		err := listErrors[list]
+		if err == nil {
+			continue
+		}
+
+		if firstErr == nil {
+			firstErr = err
+		}
+
+		if results := getList(searchResults, list); results != nil {
+			*results = models.SearchResults{Items: []models.SearchResult{}}
+		}
+
+		searchResults.Errors = append(searchResults.Errors, models.ListError{
+			List:  list,
+			Error: toAPIError(err),
+		})
+	}
+
+	if len(searchResults.Errors) == len(searchLists) {
+		searchResults.Errors = nil
+		return firstErr
+	}
+
+	searchResults.Partial = len(searchResults.Errors) > 0
+
+	return nil
+}
+
+// getList returns the list of search results with the given name
+func getList(searchResults *models.AllSearchResults, list string) *models.SearchResults {
+	switch list {
+	case allList:
+		return &searchResults.All
+	case datasetList:
+		return &searchResults.Datasets
+	case areaProfileList:
+		return &searchResults.AreaProfiles
+	case publicationList:
+		return &searchResults.Publications
+	}
+
+	return nil
+}
+
+// hasListError checks whether a list of search results failed to be retrieved
+func hasListError(searchResults *models.AllSearchResults, list string) bool {
+	for _, listErr := range searchResults.Errors {
+		if listErr.List == list {
+			return true
+		}
+	}
+
+	return false
+}
+
+// searchStatus returns the status code of a search response based on the partial results
+// policy, a response missing some lists of search results uses the configured status code
+func (api *SearchAPI) searchStatus(searchResults *models.AllSearchResults) int {
+	if searchResults.Partial && api.partialResultsStatus != 0 {
+		return api.partialResultsStatus
+	}
+
+	return http.StatusOK
+}
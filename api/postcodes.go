@@ -0,0 +1,102 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+
+	errs "github.com/ONSdigital/dp-census-alpha-search-api/apierrors"
+	"github.com/ONSdigital/dp-census-alpha-search-api/models"
+	"github.com/ONSdigital/log.go/log"
+	"github.com/gorilla/mux"
+)
+
+// regValidPostcode matches a whole postcode once whitespace has been removed
+var regValidPostcode = regexp.MustCompile(`(?i)^([A-Z][A-HJ-Y]?\d[A-Z\d]?\d[A-Z]{2}|GIR0A{2})$`)
+
+func (api *SearchAPI) getPostcode(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	setAccessControl(w, http.MethodGet)
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	vars := mux.Vars(r)
+	requestedPostcode := vars["postcode"]
+	logData := log.Data{"requested_postcode": requestedPostcode}
+
+	log.Event(ctx, "getPostcode endpoint: incoming request", log.INFO, logData)
+
+	postcode, err := normalisePostcode(requestedPostcode)
+	if err != nil {
+		log.Event(ctx, "getPostcode endpoint: invalid postcode", log.ERROR, log.Error(err), logData)
+		setErrorCode(w, err)
+		return
+	}
+
+	logData["postcode"] = postcode
+
+	postcodeResponse, status, err := api.elasticsearch.GetPostcodes(ctx, api.postcodeIndex, postcode)
+	if err != nil {
+		logData["elasticsearch_status"] = status
+		log.Event(ctx, "getPostcode endpoint: failed to search for postcode", log.ERROR, log.Error(err), logData)
+		setErrorCode(w, err)
+		return
+	}
+
+	if len(postcodeResponse.Hits.Hits) < 1 {
+		log.Event(ctx, "getPostcode endpoint: failed to find postcode", log.ERROR, log.Error(errs.ErrPostcodeNotFound), logData)
+		setErrorCode(w, errs.ErrPostcodeNotFound)
+		return
+	}
+
+	postcodeDoc := postcodeResponse.Hits.Hits[0].Source
+
+	areaProfiles, status, err := api.getAreaProfilesContainingPoint(ctx, postcodeDoc.Pin.Location)
+	if err != nil {
+		logData["elasticsearch_status"] = status
+		log.Event(ctx, "getPostcode endpoint: failed to get area profiles containing postcode", log.ERROR, log.Error(err), logData)
+		setErrorCode(w, err)
+		return
+	}
+
+	result := models.PostcodeResult{
+		Postcode:     postcodeDoc.Postcode,
+		PostcodeRaw:  postcodeDoc.RawPostcode,
+		Pin:          postcodeDoc.Pin.Location,
+		AreaProfiles: *areaProfiles,
+	}
+
+	b, err := json.Marshal(result)
+	if err != nil {
+		log.Event(ctx, "getPostcode endpoint: failed to marshal postcode resource into bytes", log.ERROR, log.Error(err), logData)
+		setErrorCode(w, errs.ErrInternalServer)
+		return
+	}
+
+	_, err = w.Write(b)
+	if err != nil {
+		log.Event(ctx, "getPostcode endpoint: error writing response", log.ERROR, log.Error(err), logData)
+	}
+
+	log.Event(ctx, "getPostcode endpoint: successfully retrieved postcode", log.INFO, logData)
+}
+
+// normalisePostcode removes all whitespace and lower cases the postcode to
+// match the format stored in the postcode index
+func normalisePostcode(postcode string) (string, error) {
+	p := strings.Join(strings.Fields(postcode), "")
+
+	if p == "" {
+		return "", errs.ErrEmptyPostcode
+	}
+
+	if !regValidPostcode.MatchString(p) {
+		return "", errs.ErrInvalidPostcode
+	}
+
+	return strings.ToLower(p), nil
+}
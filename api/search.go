@@ -10,7 +10,9 @@ import (
 
 	errs "github.com/ONSdigital/dp-census-alpha-search-api/apierrors"
 	"github.com/ONSdigital/dp-census-alpha-search-api/helpers"
+	"github.com/ONSdigital/dp-census-alpha-search-api/metrics"
 	"github.com/ONSdigital/dp-census-alpha-search-api/models"
+	"github.com/ONSdigital/dp-census-alpha-search-api/parser"
 	"github.com/ONSdigital/log.go/log"
 )
 
@@ -18,12 +20,6 @@ const (
 	defaultLimit    = 50
 	defaultOffset   = 0
 	defaultSegments = 20
-
-	internalError               = "internal server error"
-	exceedsDefaultMaximumOffset = "the maximum offset has been reached, the offset cannot be more than"
-	exceedsDefaultMaximumLimit  = "the maximum limit has been reached, the limit cannot be more than"
-	topicFilterError            = "invalid list of topics to filter by"
-	hierarchyFilterError        = "invalid hierarchy to filter by"
 )
 
 var regPostcode = regexp.MustCompile(`(?i)[A-Z][A-HJ-Y]?\d[A-Z\d]? ?\d[A-Z]{2}|GIR ?0A{2}`)
@@ -42,26 +38,37 @@ func (api *SearchAPI) searchData(w http.ResponseWriter, r *http.Request) {
 	q := r.FormValue("q")
 	requestedLimit := r.FormValue("limit")
 	requestedOffset := r.FormValue("offset")
+	requestedCursor := r.FormValue("cursor")
 	dimensions := r.FormValue("dimensions")
 	hierarchies := r.FormValue("hierarchies")
 	topics := r.FormValue("topics")
 
 	requestedDistance := r.FormValue("distance")
 	requestedRelation := r.FormValue("relation")
+	requestedAutocorrect := r.FormValue("autocorrect")
+	requestedSort := r.FormValue("sort")
 
 	logData := log.Data{
 		"query_term":         q,
 		"requested_limit":    requestedLimit,
 		"requested_offset":   requestedOffset,
+		"requested_cursor":   requestedCursor,
 		"dimensions":         dimensions,
 		"hierarchies":        hierarchies,
 		"topics":             topics,
 		"requested_distance": requestedDistance,
 		"requested_relation": requestedRelation,
+		"autocorrect":        requestedAutocorrect,
+		"requested_sort":     requestedSort,
 	}
 
 	log.Event(ctx, "searchData endpoint: incoming request", log.INFO, logData)
 
+	key := cacheKey(r, searchRoute, searchCacheParams)
+	if api.writeCachedResponse(ctx, w, r, searchRoute, key) {
+		return
+	}
+
 	// Remove leading and/or trailing whitespace
 	term := strings.TrimSpace(q)
 
@@ -76,7 +83,7 @@ func (api *SearchAPI) searchData(w http.ResponseWriter, r *http.Request) {
 		limit, err = strconv.Atoi(requestedLimit)
 		if err != nil {
 			log.Event(ctx, "searchData endpoint: request limit parameter error", log.ERROR, log.Error(err), logData)
-			setErrorCode(w, errs.ErrParsingQueryParameters)
+			setErrorCode(w, errs.WithParameter(errs.ErrParsingQueryParameters, "limit"))
 			return
 		}
 	}
@@ -86,7 +93,7 @@ func (api *SearchAPI) searchData(w http.ResponseWriter, r *http.Request) {
 		offset, err = strconv.Atoi(requestedOffset)
 		if err != nil {
 			log.Event(ctx, "searchData endpoint: request offset parameter error", log.ERROR, log.Error(err), logData)
-			setErrorCode(w, errs.ErrParsingQueryParameters)
+			setErrorCode(w, errs.WithParameter(errs.ErrParsingQueryParameters, "offset"))
 			return
 		}
 	}
@@ -97,6 +104,15 @@ func (api *SearchAPI) searchData(w http.ResponseWriter, r *http.Request) {
 		Offset:            offset,
 	}
 
+	if requestedCursor != "" {
+		page.Cursor, err = models.ParseCursor(requestedCursor)
+		if err != nil {
+			log.Event(ctx, "searchData endpoint: request cursor parameter error", log.ERROR, log.Error(err), logData)
+			setErrorCode(w, err)
+			return
+		}
+	}
+
 	if err = page.Validate(); err != nil {
 		log.Event(ctx, "searchData endpoint: validate pagination", log.ERROR, log.Error(err), logData)
 		setErrorCode(w, err)
@@ -106,21 +122,24 @@ func (api *SearchAPI) searchData(w http.ResponseWriter, r *http.Request) {
 	logData["limit"] = page.Limit
 	logData["offset"] = page.Offset
 
-	dimensionFilters, err := models.ValidateDimensions(dimensions)
+	// Validate filters against a single version of the reference files in case they are reloaded
+	referenceDocs := api.reference.Get()
+
+	dimensionFilters, err := models.ValidateDimensions(dimensions, referenceDocs.Dimensions)
 	if err != nil {
 		log.Event(ctx, "searchData endpoint: validate dimensions filter", log.ERROR, log.Error(err), logData)
 		setErrorCode(w, err)
 		return
 	}
 
-	hierarchyFilters, err := models.ValidateHierarchies(hierarchies)
+	hierarchyFilters, err := models.ValidateHierarchies(hierarchies, referenceDocs.Hierarchies)
 	if err != nil {
 		log.Event(ctx, "searchData endpoint: validate hierarchies filter", log.ERROR, log.Error(err), logData)
 		setErrorCode(w, err)
 		return
 	}
 
-	topicFilters, err := models.ValidateTopics(topics)
+	topicFilters, err := models.ValidateTopics(topics, referenceDocs.TopicLevels)
 	if err != nil {
 		log.Event(ctx, "searchData endpoint: validate topics filter", log.ERROR, log.Error(err), logData)
 		setErrorCode(w, err)
@@ -134,33 +153,173 @@ func (api *SearchAPI) searchData(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	sort, err := models.ValidateSort(requestedSort, models.SearchSortOptions)
+	if err != nil {
+		log.Event(ctx, "searchData endpoint: validate query param, sort", log.ERROR, log.Error(err), logData)
+		setErrorCode(w, err)
+		return
+	}
+
+	if page.Cursor != nil {
+		if err = page.Cursor.ForSort(sort); err != nil {
+			log.Event(ctx, "searchData endpoint: cursor was issued for another sort", log.ERROR, log.Error(err), logData)
+			setErrorCode(w, err)
+			return
+		}
+	}
+
+	autocorrect := false
+	if requestedAutocorrect != "" {
+		autocorrect, err = strconv.ParseBool(requestedAutocorrect)
+		if err != nil {
+			log.Event(ctx, "searchData endpoint: request autocorrect parameter error", log.ERROR, log.Error(err), logData)
+			setErrorCode(w, errs.ErrInvalidAutocorrect)
+			return
+		}
+	}
+
+	params := searchParams{
+		term:             term,
+		page:             page,
+		distObj:          distObj,
+		dimensionFilters: dimensionFilters,
+		hierarchyFilters: hierarchyFilters,
+		topicFilters:     topicFilters,
+		sort:             sort,
+		hierarchyLevels:  referenceDocs.HierarchyLevels,
+		postcode:         &postcodeLookup{},
+	}
+
 	log.Event(ctx, "searchData endpoint: just before querying search index", log.INFO, logData)
 
+	searchResults, err := api.search(ctx, params, logData)
+	if err != nil {
+		setErrorCode(w, err)
+		return
+	}
+
+	// Only suggest alternative search terms when the all list was retrieved
+	if searchResults.Counts.All <= suggestionsThreshold && !hasListError(searchResults, allList) {
+		suggestions := api.getSuggestions(ctx, term, logData)
+
+		if autocorrect && suggestions != nil && suggestions.Text != "" {
+			logData["suggested_term"] = suggestions.Text
+			log.Event(ctx, "searchData endpoint: requerying search index with suggested term", log.INFO, logData)
+
+			params.term = suggestions.Text
+
+			// The results for the requested term are returned if the requery fails
+			suggestedResults, err := api.search(ctx, params, logData)
+			switch {
+			case err != nil:
+				log.Event(ctx, "searchData endpoint: failed to requery search index with suggested term, returning results for requested term", log.WARN, log.Error(err), logData)
+			case suggestedResults.Counts.All > 0:
+				searchResults = suggestedResults
+				suggestions.Requeried = true
+			}
+		}
+
+		searchResults.Suggestions = suggestions
+	}
+
+	if searchResults.Counts.All == 0 {
+		metrics.ZeroResultSearch(searchRoute)
+	}
+
+	b, err := json.Marshal(searchResults)
+	if err != nil {
+		log.Event(ctx, "searchData endpoint: failed to marshal search resource into bytes", log.ERROR, log.Error(err), logData)
+		setErrorCode(w, errs.ErrInternalServer)
+		return
+	}
+
+	// Partial results are not cached so that the next request retries the failed searches
+	if !searchResults.Partial {
+		api.cache.Set(key, b)
+	}
+
+	w.WriteHeader(api.searchStatus(searchResults))
+
+	_, err = w.Write(b)
+	if err != nil {
+		log.Event(ctx, "searchData endpoint: error writing response", log.ERROR, log.Error(err), logData)
+	}
+
+	log.Event(ctx, "searchData endpoint: successfully searched index", log.INFO, logData)
+}
+
+// searchParams represents the validated parameters used to query the search indexes
+type searchParams struct {
+	term             string
+	page             *models.PageVariables
+	distObj          *models.DistObj
+	dimensionFilters []models.Filter
+	hierarchyFilters []models.Filter
+	topicFilters     []models.Filter
+	sort             string
+	hierarchyLevels  models.HierarchyLevels
+
+	// postcode is the last postcode looked up, which is shared with the requery using the
+	// suggested term so that a postcode is only looked up once for each request
+	postcode *postcodeLookup
+}
+
+// postcodeLookup represents the result of looking up the postcode found in a search term, the
+// zero value represents a term without a postcode
+type postcodeLookup struct {
+	postcode string
+	pin      *models.PinLocation
+	err      error
+}
+
+// search queries all data types, datasets, area profiles and publications concurrently
+func (api *SearchAPI) search(ctx context.Context, params searchParams, logData log.Data) (*models.AllSearchResults, error) {
 	var (
 		allChan         = make(chan models.SearchResults, 1)
 		datasetChan     = make(chan models.SearchResults, 1)
 		areaProfileChan = make(chan models.SearchResults, 1)
 		publicationChan = make(chan models.SearchResults, 1)
 
-		allReqError, datasetReqError, areaProfileReqError error
+		allReqError, datasetReqError, areaProfileReqError, publicationReqError error
 	)
 
+	q, err := parser.Parse(params.term)
+	if err != nil {
+		log.Event(ctx, "search: failed to parse search term", log.ERROR, log.Error(err), logData)
+		return nil, err
+	}
+
+	postcode := api.lookupPostcode(ctx, params, logData)
+
+	if params.sort == models.SortDistance {
+		if postcode.err != nil {
+			return nil, postcode.err
+		}
+
+		if postcode.pin == nil {
+			log.Event(ctx, "search: unable to sort by distance", log.ERROR, log.Error(errs.ErrSortDistanceNoPostcode), logData)
+			return nil, errs.ErrSortDistanceNoPostcode
+		}
+	}
+
+	sort := models.BuildSort(params.sort, postcode.pin, params.hierarchyLevels)
+	geoLocation := getPostcodeLocation(ctx, postcode.pin, params.distObj)
+
 	// find all data
 	go func() {
-		geoLocation, err := api.getPostcodeLocation(ctx, term, distObj, logData)
-		if err != nil {
-			allReqError = err
+		if postcode.err != nil {
+			allReqError = postcode.err
 			allChan <- models.SearchResults{}
 			return
 		}
 
 		// build all search query
-		allDataQuery := api.buildAllSearchQuery(term, geoLocation, dimensionFilters, hierarchyFilters, topicFilters, page)
+		allDataQuery := api.buildAllSearchQuery(q, geoLocation, params.dimensionFilters, params.hierarchyFilters, params.topicFilters, params.page, sort)
+		applyCursor(allDataQuery, params.page, allList)
 
-		response, status, err := api.elasticsearch.QuerySearchIndex(ctx, api.datasetIndex+","+api.areaProfileIndex, allDataQuery)
+		response, status, err := api.elasticsearch.QuerySearchIndex(ctx, api.datasetIndex+","+api.areaProfileIndex+","+api.publicationIndex, allDataQuery)
 		if err != nil {
-			logData["elasticsearch_status"] = status
-			log.Event(ctx, "searchData endpoint: failed to get all data type search results", log.ERROR, log.Error(err), logData)
+			log.Event(ctx, "searchData endpoint: failed to get all data type search results", log.ERROR, log.Error(err), withStatus(logData, status))
 			allReqError = err
 			allChan <- models.SearchResults{}
 			return
@@ -207,12 +366,15 @@ func (api *SearchAPI) searchData(w http.ResponseWriter, r *http.Request) {
 				Code:           result.Matches.Code,
 				Hierarchy:      result.Matches.Hierarchy,
 				Name:           result.Matches.Name,
+				Keywords:       result.Matches.Keywords,
+				Summary:        result.Matches.Summary,
 			}
 
 			allData.Items = append(allData.Items, doc)
 		}
 
 		allData.Count = len(allData.Items)
+		allData.SearchAfter = lastSortValues(response.Hits.HitList)
 
 		allChan <- allData
 	}()
@@ -220,12 +382,12 @@ func (api *SearchAPI) searchData(w http.ResponseWriter, r *http.Request) {
 	// find datasets
 	go func() {
 		// build dataset search query
-		datasetQuery := buildDatasetSearchQuery(term, dimensionFilters, topicFilters, page)
+		datasetQuery := buildDatasetSearchQuery(q, params.dimensionFilters, params.topicFilters, params.page, sort)
+		applyCursor(datasetQuery, params.page, datasetList)
 
 		response, status, err := api.elasticsearch.QuerySearchIndex(ctx, api.datasetIndex, datasetQuery)
 		if err != nil {
-			logData["elasticsearch_status"] = status
-			log.Event(ctx, "searchData endpoint: failed to get dataset search results", log.ERROR, log.Error(err), logData)
+			log.Event(ctx, "searchData endpoint: failed to get dataset search results", log.ERROR, log.Error(err), withStatus(logData, status))
 			datasetReqError = err
 			datasetChan <- models.SearchResults{}
 			return
@@ -271,25 +433,25 @@ func (api *SearchAPI) searchData(w http.ResponseWriter, r *http.Request) {
 		}
 
 		datasets.Count = len(datasets.Items)
+		datasets.SearchAfter = lastSortValues(response.Hits.HitList)
 
 		datasetChan <- datasets
 	}()
 
 	// find area profiles
 	go func() {
-		geoLocation, err := api.getPostcodeLocation(ctx, term, distObj, logData)
-		if err != nil {
-			areaProfileReqError = err
+		if postcode.err != nil {
+			areaProfileReqError = postcode.err
 			areaProfileChan <- models.SearchResults{}
 			return
 		}
 
-		areaProfileQuery := buildAreaSearchQuery(term, hierarchyFilters, geoLocation, page)
+		areaProfileQuery := buildAreaSearchQuery(q, params.hierarchyFilters, geoLocation, params.page, sort)
+		applyCursor(areaProfileQuery, params.page, areaProfileList)
 
 		response, status, err := api.elasticsearch.QuerySearchIndex(ctx, api.areaProfileIndex, areaProfileQuery)
 		if err != nil {
-			logData["elasticsearch_status"] = status
-			log.Event(ctx, "searchData endpoint: failed to get area profile search results", log.ERROR, log.Error(err), logData)
+			log.Event(ctx, "searchData endpoint: failed to get area profile search results", log.ERROR, log.Error(err), withStatus(logData, status))
 			areaProfileReqError = err
 			areaProfileChan <- models.SearchResults{}
 			return
@@ -317,15 +479,60 @@ func (api *SearchAPI) searchData(w http.ResponseWriter, r *http.Request) {
 		}
 
 		areaProfiles.Count = len(areaProfiles.Items)
+		areaProfiles.SearchAfter = lastSortValues(response.Hits.HitList)
 
 		areaProfileChan <- areaProfiles
 	}()
 
 	// find publications
 	go func() {
-		publicationChan <- models.SearchResults{
-			Items: []models.SearchResult{},
+		publicationQuery := buildPublicationSearchQuery(q, params.topicFilters, params.page, sort)
+		applyCursor(publicationQuery, params.page, publicationList)
+
+		response, status, err := api.elasticsearch.QuerySearchIndex(ctx, api.publicationIndex, publicationQuery)
+		if err != nil {
+			log.Event(ctx, "searchData endpoint: failed to get publication search results", log.ERROR, log.Error(err), withStatus(logData, status))
+			publicationReqError = err
+			publicationChan <- models.SearchResults{}
+			return
+		}
+
+		publications := models.SearchResults{
+			Aggregations: &models.Aggregations{},
+			TotalCount:   response.Hits.Total,
+			Items:        []models.SearchResult{},
+		}
+
+		if len(response.Aggregations.Topic1.Items) > 0 {
+			publications.Aggregations.Topic1 = response.Aggregations.Topic1
 		}
+
+		if len(response.Aggregations.Topic2.Items) > 0 {
+			publications.Aggregations.Topic2 = response.Aggregations.Topic2
+		}
+
+		if len(response.Aggregations.Topic3.Items) > 0 {
+			publications.Aggregations.Topic3 = response.Aggregations.Topic3
+		}
+
+		for _, result := range response.Hits.HitList {
+			doc := result.Source
+			doc.Matches = models.NewMatches{
+				Keywords: result.Matches.Keywords,
+				Summary:  result.Matches.Summary,
+				Title:    result.Matches.Title,
+				Topic1:   result.Matches.Topic1,
+				Topic2:   result.Matches.Topic2,
+				Topic3:   result.Matches.Topic3,
+			}
+
+			publications.Items = append(publications.Items, doc)
+		}
+
+		publications.Count = len(publications.Items)
+		publications.SearchAfter = lastSortValues(response.Hits.HitList)
+
+		publicationChan <- publications
 	}()
 
 	// Wait till we have results from both search requests
@@ -334,25 +541,9 @@ func (api *SearchAPI) searchData(w http.ResponseWriter, r *http.Request) {
 	areaProfiles := <-areaProfileChan
 	publications := <-publicationChan
 
-	// handle any request errors from search queries
-	if allReqError != nil {
-		setErrorCode(w, allReqError)
-		return
-	}
-
-	if datasetReqError != nil {
-		setErrorCode(w, datasetReqError)
-		return
-	}
-
-	if areaProfileReqError != nil {
-		setErrorCode(w, areaProfileReqError)
-		return
-	}
-
-	searchResults := models.AllSearchResults{
-		Limit:  page.Limit,
-		Offset: page.Offset,
+	searchResults := &models.AllSearchResults{
+		Limit:  params.page.Limit,
+		Offset: params.page.Offset,
 		Counts: models.Counts{
 			All:          all.TotalCount,
 			Datasets:     datasets.TotalCount,
@@ -365,20 +556,48 @@ func (api *SearchAPI) searchData(w http.ResponseWriter, r *http.Request) {
 		Publications: publications,
 	}
 
-	b, err := json.Marshal(searchResults)
-	if err != nil {
-		log.Event(ctx, "searchData endpoint: failed to marshal search resource into bytes", log.ERROR, log.Error(err), logData)
-		setErrorCode(w, errs.ErrInternalServer)
-		return
+	// handle any request errors from search queries, returning the lists that were retrieved
+	listErrors := map[string]error{
+		allList:         allReqError,
+		datasetList:     datasetReqError,
+		areaProfileList: areaProfileReqError,
+		publicationList: publicationReqError,
 	}
 
-	_, err = w.Write(b)
+	if err = setPartialResults(searchResults, listErrors); err != nil {
+		return nil, err
+	}
+
+	if searchResults.Partial {
+		logData["list_errors"] = searchResults.Errors
+		log.Event(ctx, "search: returning partial search results", log.WARN, logData)
+	}
+
+	nextCursor, err := getNextCursor(params.page, map[string]models.SearchResults{
+		allList:         all,
+		datasetList:     datasets,
+		areaProfileList: areaProfiles,
+		publicationList: publications,
+	}, listErrors)
 	if err != nil {
-		log.Event(ctx, "searchData endpoint: error writing response", log.ERROR, log.Error(err), logData)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Event(ctx, "search: failed to create next cursor", log.ERROR, log.Error(err), logData)
+		return nil, errs.ErrInternalServer
 	}
 
-	log.Event(ctx, "searchData endpoint: successfully searched index", log.INFO, logData)
+	searchResults.NextCursor = nextCursor
+
+	return searchResults, nil
+}
+
+// withStatus returns a copy of the log data with the status of an elasticsearch response, so
+// that queries made concurrently do not write to the same log data
+func withStatus(logData log.Data, status int) log.Data {
+	data := log.Data{"elasticsearch_status": status}
+	for key, value := range logData {
+		data[key] = value
+	}
+
+	return data
 }
 
 func setAccessControl(w http.ResponseWriter, method string) {
@@ -388,29 +607,38 @@ func setAccessControl(w http.ResponseWriter, method string) {
 	w.Header().Set("Content-Type", "application/json")
 }
 
+// setErrorCode writes an error response containing the api error for err, the details of
+// internal errors are not exposed to api users
 func setErrorCode(w http.ResponseWriter, err error) {
+	apiErr := toAPIError(err)
+
+	b, marshalErr := json.Marshal(errs.Errors{Errors: []*errs.Error{apiErr}})
+	if marshalErr != nil {
+		http.Error(w, apiErr.Message, apiErr.Status)
+		return
+	}
 
-	switch {
-	case errs.NotFoundMap[err]:
-		http.Error(w, err.Error(), http.StatusNotFound)
-	case errs.BadRequestMap[err]:
-		http.Error(w, err.Error(), http.StatusBadRequest)
-	case strings.Contains(err.Error(), exceedsDefaultMaximumOffset):
-		http.Error(w, err.Error(), http.StatusBadRequest)
-	case strings.Contains(err.Error(), exceedsDefaultMaximumLimit):
-		http.Error(w, err.Error(), http.StatusBadRequest)
-	case strings.Contains(err.Error(), topicFilterError):
-		http.Error(w, err.Error(), http.StatusBadRequest)
-	case strings.Contains(err.Error(), hierarchyFilterError):
-		http.Error(w, err.Error(), http.StatusBadRequest)
-	case strings.Contains(err.Error(), relationError):
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(apiErr.Status)
+	w.Write(b)
+}
+
+// toAPIError converts an error into the api error returned to api users
+func toAPIError(err error) *errs.Error {
+	switch e := err.(type) {
+	case *errs.Error:
+		if e.Status == http.StatusInternalServerError {
+			return errs.ErrInternalServer
+		}
+		return e
+	case *parser.SyntaxError:
+		return errs.New(errs.CodeInvalidQuerySyntax, http.StatusBadRequest, e.Error(), "q")
 	default:
-		http.Error(w, internalError, http.StatusInternalServerError)
+		return errs.ErrInternalServer
 	}
 }
 
-func (api *SearchAPI) buildAllSearchQuery(term string, geoLocation *models.GeoLocation, dimensionFilters []models.Filter, hierarchyFilters []models.Filter, topicFilters []models.Filter, page *models.PageVariables) *models.Body {
+func (api *SearchAPI) buildAllSearchQuery(q *parser.Query, geoLocation *models.GeoLocation, dimensionFilters []models.Filter, hierarchyFilters []models.Filter, topicFilters []models.Filter, page *models.PageVariables, sort []models.Scores) *models.Body {
 	var object models.Object
 	highlight := make(map[string]models.Object)
 
@@ -427,76 +655,8 @@ func (api *SearchAPI) buildAllSearchQuery(term string, geoLocation *models.GeoLo
 	highlight["hierarchy"] = object
 	highlight["name"] = object
 
-	alias := make(map[string]string)
-	description := make(map[string]string)
-	title := make(map[string]string)
-	topic1 := make(map[string]string)
-	topic2 := make(map[string]string)
-	topic3 := make(map[string]string)
-	dimensionLabels := make(map[string]string)
-	dimensionNames := make(map[string]string)
-
-	code := make(map[string]string)
-	hierarchy := make(map[string]string)
-	name := make(map[string]string)
-
-	alias["alias.raw"] = term
-	description["description.raw"] = term
-	title["title.raw"] = term
-	topic1["topic1"] = term
-	topic2["topic2"] = term
-	topic3["topic3"] = term
-	dimensionLabels["dimensions.label"] = term
-	dimensionNames["dimensions.name"] = term
-
-	code["code"] = term
-	hierarchy["hierarchy"] = term
-	name["name"] = term
-
-	aliasMatch := models.Match{
-		Match: alias,
-	}
-
-	descriptionMatch := models.Match{
-		Match: description,
-	}
-
-	titleMatch := models.Match{
-		Match: title,
-	}
-
-	topic1Match := models.Match{
-		Match: topic1,
-	}
-
-	topic2Match := models.Match{
-		Match: topic2,
-	}
-
-	topic3Match := models.Match{
-		Match: topic3,
-	}
-
-	codeMatch := models.Match{
-		Match: code,
-	}
-
-	hierarchyMatch := models.Match{
-		Match: hierarchy,
-	}
-
-	nameMatch := models.Match{
-		Match: name,
-	}
-
-	scores := models.Scores{
-		Score: models.Score{
-			Order: "desc",
-		},
-	}
-
-	listOfScores := []models.Scores{}
-	listOfScores = append(listOfScores, scores)
+	highlight["keywords.raw"] = object
+	highlight["summary.raw"] = object
 
 	query := &models.Body{
 		Aggregations: models.Aggs{
@@ -534,7 +694,7 @@ func (api *SearchAPI) buildAllSearchQuery(term string, geoLocation *models.GeoLo
 			PostTags: []string{"</b>"},
 		},
 		Query:     models.Query{},
-		Sort:      listOfScores,
+		Sort:      sort,
 		TotalHits: true,
 	}
 
@@ -579,33 +739,7 @@ func (api *SearchAPI) buildAllSearchQuery(term string, geoLocation *models.GeoLo
 			Bool: &models.Bool{
 				Should: []models.Match{
 					{
-						Bool: &models.Bool{
-							Should: []models.Match{
-								aliasMatch,
-								descriptionMatch,
-								titleMatch,
-								topic1Match,
-								topic2Match,
-								topic3Match,
-								codeMatch,
-								hierarchyMatch,
-								nameMatch,
-								{
-									Nested: &models.Nested{
-										Path: "dimensions",
-										Query: []models.NestedQuery{
-											{
-												Term: dimensionLabels,
-											},
-											{
-												Term: dimensionNames,
-											},
-										},
-									},
-								},
-							},
-							MinimumShouldMatch: 1,
-						},
+						Bool: q.Compile(allMatches),
 					},
 				},
 			},
@@ -615,68 +749,86 @@ func (api *SearchAPI) buildAllSearchQuery(term string, geoLocation *models.GeoLo
 	return query
 }
 
-func buildDatasetSearchQuery(term string, dimensionFilters []models.Filter, topicFilters []models.Filter, page *models.PageVariables) *models.Body {
-	var object models.Object
-	highlight := make(map[string]models.Object)
-
-	highlight["alias"] = object
-	highlight["description.raw"] = object
-	highlight["title.raw"] = object
-	highlight["topic1"] = object
-	highlight["topic2"] = object
-	highlight["topic3"] = object
-	highlight["dimensions.label"] = object
-	highlight["dimensions.name"] = object
-
+// allMatches creates the list of fields across datasets, area profiles and publications to match text against
+func allMatches(text string) []models.Match {
 	alias := make(map[string]string)
 	description := make(map[string]string)
 	title := make(map[string]string)
 	topic1 := make(map[string]string)
 	topic2 := make(map[string]string)
 	topic3 := make(map[string]string)
-	dimensionLabels := make(map[string]string)
-	dimensionNames := make(map[string]string)
-	alias["alias"] = term
-	description["description.raw"] = term
-	title["title.raw"] = term
-	topic1["topic1"] = term
-	topic2["topic2"] = term
-	topic3["topic3"] = term
-	dimensionLabels["dimensions.label"] = term
-	dimensionNames["dimensions.name"] = term
 
-	aliasMatch := models.Match{
-		Match: alias,
-	}
-
-	descriptionMatch := models.Match{
-		Match: description,
-	}
+	code := make(map[string]string)
+	hierarchy := make(map[string]string)
+	name := make(map[string]string)
 
-	titleMatch := models.Match{
-		Match: title,
-	}
+	keywords := make(map[string]string)
+	summary := make(map[string]string)
 
-	topic1Match := models.Match{
-		Match: topic1,
-	}
+	alias["alias.raw"] = text
+	description["description.raw"] = text
+	title["title.raw"] = text
+	topic1["topic1"] = text
+	topic2["topic2"] = text
+	topic3["topic3"] = text
 
-	topic2Match := models.Match{
-		Match: topic2,
-	}
+	code["code"] = text
+	hierarchy["hierarchy"] = text
+	name["name"] = text
 
-	topic3Match := models.Match{
-		Match: topic3,
-	}
+	keywords["keywords.raw"] = text
+	summary["summary.raw"] = text
 
-	scores := models.Scores{
-		Score: models.Score{
-			Order: "desc",
+	return []models.Match{
+		{
+			Match: alias,
+		},
+		{
+			Match: description,
+		},
+		{
+			Match: title,
+		},
+		{
+			Match: topic1,
+		},
+		{
+			Match: topic2,
 		},
+		{
+			Match: topic3,
+		},
+		{
+			Match: code,
+		},
+		{
+			Match: hierarchy,
+		},
+		{
+			Match: name,
+		},
+		{
+			Match: keywords,
+		},
+		{
+			Match: summary,
+		},
+		models.DimensionMatch(text),
 	}
+}
 
-	listOfScores := []models.Scores{}
-	listOfScores = append(listOfScores, scores)
+func buildDatasetSearchQuery(q *parser.Query, dimensionFilters []models.Filter, topicFilters []models.Filter, page *models.PageVariables, sort []models.Scores) *models.Body {
+	var object models.Object
+	highlight := make(map[string]models.Object)
+
+	highlight["alias"] = object
+	highlight["description.raw"] = object
+	highlight["title.raw"] = object
+	highlight["topic1"] = object
+	highlight["topic2"] = object
+	highlight["topic3"] = object
+	highlight["dimensions.label"] = object
+	highlight["dimensions.name"] = object
 
 	query := &models.Body{
 		Aggregations: models.Aggs{
@@ -709,32 +861,9 @@ func buildDatasetSearchQuery(term string, dimensionFilters []models.Filter, topi
 			PostTags: []string{"</b>"},
 		},
 		Query: models.Query{
-			Bool: &models.Bool{
-				Should: []models.Match{
-					aliasMatch,
-					descriptionMatch,
-					titleMatch,
-					topic1Match,
-					topic2Match,
-					topic3Match,
-					{
-						Nested: &models.Nested{
-							Path: "dimensions",
-							Query: []models.NestedQuery{
-								{
-									Term: dimensionLabels,
-								},
-								{
-									Term: dimensionNames,
-								},
-							},
-						},
-					},
-				},
-				MinimumShouldMatch: 1,
-			},
+			Bool: q.Compile(datasetMatches),
 		},
-		Sort:      listOfScores,
+		Sort:      sort,
 		TotalHits: true,
 	}
 
@@ -749,7 +878,45 @@ func buildDatasetSearchQuery(term string, dimensionFilters []models.Filter, topi
 	return query
 }
 
-func buildAreaSearchQuery(term string, hierarchyFilters []models.Filter, geoLocation *models.GeoLocation, page *models.PageVariables) *models.Body {
+// datasetMatches creates the list of dataset fields to match text against
+func datasetMatches(text string) []models.Match {
+	alias := make(map[string]string)
+	description := make(map[string]string)
+	title := make(map[string]string)
+	topic1 := make(map[string]string)
+	topic2 := make(map[string]string)
+	topic3 := make(map[string]string)
+	alias["alias"] = text
+	description["description.raw"] = text
+	title["title.raw"] = text
+	topic1["topic1"] = text
+	topic2["topic2"] = text
+	topic3["topic3"] = text
+
+	return []models.Match{
+		{
+			Match: alias,
+		},
+		{
+			Match: description,
+		},
+		{
+			Match: title,
+		},
+		{
+			Match: topic1,
+		},
+		{
+			Match: topic2,
+		},
+		{
+			Match: topic3,
+		},
+		models.DimensionMatch(text),
+	}
+}
+
+func buildAreaSearchQuery(q *parser.Query, hierarchyFilters []models.Filter, geoLocation *models.GeoLocation, page *models.PageVariables, sort []models.Scores) *models.Body {
 	var object models.Object
 	highlight := make(map[string]models.Object)
 
@@ -757,34 +924,6 @@ func buildAreaSearchQuery(term string, hierarchyFilters []models.Filter, geoLoca
 	highlight["hierarchy"] = object
 	highlight["name"] = object
 
-	code := make(map[string]string)
-	hierarchy := make(map[string]string)
-	name := make(map[string]string)
-	code["code"] = term
-	hierarchy["hierarchy"] = term
-	name["name"] = term
-
-	codeMatch := models.Match{
-		Match: code,
-	}
-
-	hierarchyMatch := models.Match{
-		Match: hierarchy,
-	}
-
-	nameMatch := models.Match{
-		Match: name,
-	}
-
-	scores := models.Scores{
-		Score: models.Score{
-			Order: "desc",
-		},
-	}
-
-	listOfScores := []models.Scores{}
-	listOfScores = append(listOfScores, scores)
-
 	query := &models.Body{
 		From: page.Offset,
 		Size: page.Limit,
@@ -793,7 +932,7 @@ func buildAreaSearchQuery(term string, hierarchyFilters []models.Filter, geoLoca
 			PreTags:  []string{"<b>"},
 			PostTags: []string{"</b>"},
 		},
-		Sort:      listOfScores,
+		Sort:      sort,
 		TotalHits: true,
 		Aggregations: models.Aggs{
 			Hierarchies: models.Agg{
@@ -821,14 +960,7 @@ func buildAreaSearchQuery(term string, hierarchyFilters []models.Filter, geoLoca
 		}
 	} else {
 		query.Query = models.Query{
-			Bool: &models.Bool{
-				Should: []models.Match{
-					codeMatch,
-					hierarchyMatch,
-					nameMatch,
-				},
-				MinimumShouldMatch: 1,
-			},
+			Bool: q.Compile(areaProfileMatches),
 		}
 	}
 
@@ -839,45 +971,191 @@ func buildAreaSearchQuery(term string, hierarchyFilters []models.Filter, geoLoca
 	return query
 }
 
-func (api *SearchAPI) getPostcodeLocation(ctx context.Context, term string, distObj *models.DistObj, logData log.Data) (*models.GeoLocation, error) {
-	var geoLocation *models.GeoLocation
-	postcodes := regPostcode.FindAllString(term, -1)
-	if len(postcodes) > 0 {
-		// Only use first postcode found
-		p := strings.ReplaceAll(postcodes[0], " ", "")
-		lcPostcode := strings.ToLower(p)
+// areaProfileMatches creates the list of area profile fields to match text against
+func areaProfileMatches(text string) []models.Match {
+	code := make(map[string]string)
+	hierarchy := make(map[string]string)
+	name := make(map[string]string)
+	code["code"] = text
+	hierarchy["hierarchy"] = text
+	name["name"] = text
 
-		postcodeResponse, _, err := api.elasticsearch.GetPostcodes(ctx, api.postcodeIndex, lcPostcode)
-		if err != nil {
-			log.Event(ctx, "getPostcodeSearch endpoint: failed to search for postcode", log.ERROR, log.Error(err), logData)
+	return []models.Match{
+		{
+			Match: code,
+		},
+		{
+			Match: hierarchy,
+		},
+		{
+			Match: name,
+		},
+	}
+}
 
-			return geoLocation, nil
-		}
+func buildPublicationSearchQuery(q *parser.Query, topicFilters []models.Filter, page *models.PageVariables, sort []models.Scores) *models.Body {
+	var object models.Object
+	highlight := make(map[string]models.Object)
 
-		if len(postcodeResponse.Hits.Hits) < 1 {
-			log.Event(ctx, "getPostcodeSearch endpoint: failed to find postcode", log.WARN, log.Error(errs.ErrPostcodeNotFound), logData)
-		}
+	highlight["keywords.raw"] = object
+	highlight["summary.raw"] = object
+	highlight["title.raw"] = object
+	highlight["topic1"] = object
+	highlight["topic2"] = object
+	highlight["topic3"] = object
 
-		// calculate distance (in metres) based on distObj
-		dist := distObj.CalculateDistanceInMetres(ctx)
+	query := &models.Body{
+		Aggregations: models.Aggs{
+			Topic1: models.Agg{
+				Terms: models.AggTerm{
+					Field: "topic1",
+				},
+			},
+			Topic2: models.Agg{
+				Terms: models.AggTerm{
+					Field: "topic2",
+				},
+			},
+			Topic3: models.Agg{
+				Terms: models.AggTerm{
+					Field: "topic3",
+				},
+			},
+		},
+		From: page.Offset,
+		Size: page.Limit,
+		Highlight: &models.Highlight{
+			Fields:   highlight,
+			PreTags:  []string{"<b>"},
+			PostTags: []string{"</b>"},
+		},
+		Query: models.Query{
+			Bool: q.Compile(publicationMatches),
+		},
+		Sort:      sort,
+		TotalHits: true,
+	}
 
-		pcCoordinate := helpers.Coordinate{
-			Lat: postcodeResponse.Hits.Hits[0].Source.Pin.Location.Lat,
-			Lon: postcodeResponse.Hits.Hits[0].Source.Pin.Location.Lon,
-		}
+	if topicFilters != nil {
+		query.Query.Bool.Filter = topicFilters
+	}
 
-		// build polygon from circle using long/lat of postcod and distance
-		polygonShape, err := helpers.CircleToPolygon(pcCoordinate, dist, defaultSegments)
-		if err != nil {
-			return geoLocation, nil
-		}
+	return query
+}
 
-		var coordinates [][][]float64
-		geoLocation = &models.GeoLocation{
-			Type:        "polygon",
-			Coordinates: append(coordinates, polygonShape.Coordinates),
-		}
+// publicationMatches creates the list of publication fields to match text against
+func publicationMatches(text string) []models.Match {
+	keywords := make(map[string]string)
+	summary := make(map[string]string)
+	title := make(map[string]string)
+	topic1 := make(map[string]string)
+	topic2 := make(map[string]string)
+	topic3 := make(map[string]string)
+	keywords["keywords.raw"] = text
+	summary["summary.raw"] = text
+	title["title.raw"] = text
+	topic1["topic1"] = text
+	topic2["topic2"] = text
+	topic3["topic3"] = text
+
+	return []models.Match{
+		{
+			Match: keywords,
+		},
+		{
+			Match: summary,
+		},
+		{
+			Match: title,
+		},
+		{
+			Match: topic1,
+		},
+		{
+			Match: topic2,
+		},
+		{
+			Match: topic3,
+		},
+	}
+}
+
+// getPostcodeLocation returns the area within the distance of the postcode location, nil is
+// returned if the term did not contain a postcode that was found
+func getPostcodeLocation(ctx context.Context, pin *models.PinLocation, distObj *models.DistObj) *models.GeoLocation {
+	if pin == nil {
+		return nil
+	}
+
+	// calculate distance (in metres) based on distObj
+	dist := distObj.CalculateDistanceInMetres(ctx)
+
+	pcCoordinate := helpers.Coordinate{
+		Lat: pin.Lat,
+		Lon: pin.Lon,
+	}
+
+	// build polygon from circle using long/lat of postcod and distance
+	polygonShape, err := helpers.CircleToPolygon(pcCoordinate, dist, defaultSegments)
+	if err != nil {
+		return nil
+	}
+
+	var coordinates [][][]float64
+	return &models.GeoLocation{
+		Type:        "polygon",
+		Coordinates: append(coordinates, polygonShape.Coordinates),
+	}
+}
+
+// lookupPostcode looks up the first postcode in the search term, the previous lookup is
+// reused if the term contains the same postcode
+func (api *SearchAPI) lookupPostcode(ctx context.Context, params searchParams, logData log.Data) postcodeLookup {
+	postcode := findPostcode(params.term)
+	if params.postcode != nil && params.postcode.postcode == postcode {
+		return *params.postcode
+	}
+
+	pin, err := api.getPostcodePin(ctx, postcode, logData)
+	lookup := postcodeLookup{postcode: postcode, pin: pin, err: err}
+
+	if params.postcode != nil {
+		*params.postcode = lookup
+	}
+
+	return lookup
+}
+
+// findPostcode returns the first postcode in the search term in lower case without spaces, an
+// empty string is returned if the term does not contain a postcode
+func findPostcode(term string) string {
+	postcode := regPostcode.FindString(term)
+
+	return strings.ToLower(strings.ReplaceAll(postcode, " ", ""))
+}
+
+// getPostcodePin finds the location of the postcode, nil is returned if there is no postcode
+// or the postcode cannot be found. An error is returned if the postcode index cannot be
+// searched, so that a search is not carried out as if the term had no postcode.
+func (api *SearchAPI) getPostcodePin(ctx context.Context, postcode string, logData log.Data) (*models.PinLocation, error) {
+	if postcode == "" {
+		return nil, nil
 	}
 
-	return geoLocation, nil
+	postcodeResponse, _, err := api.elasticsearch.GetPostcodes(ctx, api.postcodeIndex, postcode)
+	if err != nil {
+		metrics.PostcodeLookup(metrics.PostcodeError)
+		log.Event(ctx, "getPostcodeSearch endpoint: failed to search for postcode", log.ERROR, log.Error(err), logData)
+		return nil, err
+	}
+
+	if len(postcodeResponse.Hits.Hits) < 1 {
+		metrics.PostcodeLookup(metrics.PostcodeMiss)
+		log.Event(ctx, "getPostcodeSearch endpoint: failed to find postcode", log.WARN, log.Error(errs.ErrPostcodeNotFound), logData)
+		return nil, nil
+	}
+
+	metrics.PostcodeLookup(metrics.PostcodeHit)
+
+	return &postcodeResponse.Hits.Hits[0].Source.Pin.Location, nil
 }
@@ -0,0 +1,244 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	errs "github.com/ONSdigital/dp-census-alpha-search-api/apierrors"
+	"github.com/ONSdigital/dp-census-alpha-search-api/models"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSearchData(t *testing.T) {
+	Convey("Given a search api with datasets, area profiles and publications", t, func() {
+		router, es := setupAPI()
+
+		Convey("When searching for a term", func() {
+			w := get(router, "/search?q=deaths")
+
+			Convey("Then the matching documents of each data type are returned", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+
+				var results models.AllSearchResults
+				So(json.Unmarshal(w.Body.Bytes(), &results), ShouldBeNil)
+
+				So(results.Counts, ShouldResemble, models.Counts{All: 3, Datasets: 2, AreaProfiles: 0, Publications: 1})
+				So(results.Datasets.Items[0].Title, ShouldEqual, "Deaths registered by sex")
+				So(results.Datasets.Items[1].Title, ShouldEqual, "Deaths registered in London")
+				So(results.Publications.Items[0].Title, ShouldEqual, "Deaths registered weekly in England and Wales")
+				So(results.Datasets.Aggregations.Dimensions.Items, ShouldResemble, []models.Bucket{
+					{Key: "age", DocCount: 1},
+					{Key: "sex", DocCount: 1},
+				})
+				So(results.Partial, ShouldBeFalse)
+			})
+		})
+
+		Convey("When searching for a term filtered by dimension", func() {
+			w := get(router, "/search?q=deaths&dimensions=sex")
+
+			Convey("Then only the datasets with the dimension are returned", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+
+				var results models.AllSearchResults
+				So(json.Unmarshal(w.Body.Bytes(), &results), ShouldBeNil)
+
+				So(results.Counts.Datasets, ShouldEqual, 1)
+				So(results.Datasets.Items[0].Title, ShouldEqual, "Deaths registered by sex")
+			})
+		})
+
+		Convey("When searching for the label of a dimension", func() {
+			w := get(router, "/search?q=dimension:Sex")
+
+			Convey("Then the datasets with a dimension matching the label are returned", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+
+				var results models.AllSearchResults
+				So(json.Unmarshal(w.Body.Bytes(), &results), ShouldBeNil)
+
+				So(results.Counts.Datasets, ShouldEqual, 1)
+				So(results.Datasets.Items[0].Title, ShouldEqual, "Deaths registered by sex")
+			})
+		})
+
+		Convey("When searching for the name of a dimension", func() {
+			w := get(router, "/search?q=dimension:housepriceage")
+
+			Convey("Then the datasets with the dimension are returned", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+
+				var results models.AllSearchResults
+				So(json.Unmarshal(w.Body.Bytes(), &results), ShouldBeNil)
+
+				So(results.Counts.Datasets, ShouldEqual, 1)
+				So(results.Datasets.Items[0].Title, ShouldEqual, "House prices by age of property")
+			})
+		})
+
+		Convey("When searching for the name of an area", func() {
+			w := get(router, "/search?q=cardiff")
+
+			Convey("Then the area profile is returned", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+
+				var results models.AllSearchResults
+				So(json.Unmarshal(w.Body.Bytes(), &results), ShouldBeNil)
+
+				So(results.Counts.AreaProfiles, ShouldEqual, 1)
+				So(results.AreaProfiles.Items[0].ID, ShouldEqual, "W06000015")
+			})
+		})
+
+		Convey("When searching with a limit and offset", func() {
+			w := get(router, "/search?q=deaths&limit=1&offset=1")
+
+			Convey("Then the page of results is returned with the total counts", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+
+				var results models.AllSearchResults
+				So(json.Unmarshal(w.Body.Bytes(), &results), ShouldBeNil)
+
+				So(results.Counts.Datasets, ShouldEqual, 2)
+				So(results.Datasets.Count, ShouldEqual, 1)
+				So(results.Datasets.Items[0].Title, ShouldEqual, "Deaths registered in London")
+			})
+		})
+
+		Convey("When paging through the results with a cursor", func() {
+			var titles []string
+			cursor := models.StartCursor
+
+			for page := 0; cursor != "" && page < 5; page++ {
+				w := get(router, "/search?q=deaths&limit=1&cursor="+url.QueryEscape(cursor))
+				So(w.Code, ShouldEqual, http.StatusOK)
+
+				var results models.AllSearchResults
+				So(json.Unmarshal(w.Body.Bytes(), &results), ShouldBeNil)
+
+				for _, item := range results.Datasets.Items {
+					titles = append(titles, item.Title)
+				}
+				cursor = results.NextCursor
+			}
+
+			Convey("Then each dataset is returned once across the pages", func() {
+				So(cursor, ShouldBeEmpty)
+				So(titles, ShouldHaveLength, 2)
+				So(titles, ShouldContain, "Deaths registered by sex")
+				So(titles, ShouldContain, "Deaths registered in London")
+			})
+		})
+
+		Convey("When the sort is changed while paging with a cursor", func() {
+			w := get(router, "/search?q=deaths&limit=1&sort=alphabetical&cursor="+models.StartCursor)
+			So(w.Code, ShouldEqual, http.StatusOK)
+
+			var results models.AllSearchResults
+			So(json.Unmarshal(w.Body.Bytes(), &results), ShouldBeNil)
+			So(results.NextCursor, ShouldNotBeEmpty)
+
+			w = get(router, "/search?q=deaths&limit=1&sort=relevance&cursor="+url.QueryEscape(results.NextCursor))
+
+			Convey("Then the cursor is rejected as it holds the position for another sort", func() {
+				So(w.Code, ShouldEqual, http.StatusBadRequest)
+				So(decodeError(w).Code, ShouldEqual, errs.ErrInvalidCursor.Code)
+			})
+		})
+
+		Convey("When the publication index fails", func() {
+			es.Fail(testPublicationIndex, http.StatusInternalServerError, errs.ErrIndexNotFound)
+			w := get(router, "/search?q=deaths")
+
+			Convey("Then partial results are returned with the lists that failed", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+
+				var results models.AllSearchResults
+				So(json.Unmarshal(w.Body.Bytes(), &results), ShouldBeNil)
+
+				So(results.Partial, ShouldBeTrue)
+				So(results.Counts.Datasets, ShouldEqual, 2)
+				So(results.Publications.Items, ShouldBeEmpty)
+
+				var lists []string
+				for _, listErr := range results.Errors {
+					lists = append(lists, listErr.List)
+					So(listErr.Code, ShouldEqual, errs.ErrInternalServer.Code)
+				}
+				So(lists, ShouldContain, allList)
+				So(lists, ShouldContain, publicationList)
+			})
+		})
+
+		Convey("When sorting by distance from a postcode while the postcode index is unavailable", func() {
+			es.Fail(testPostcodeIndex, http.StatusServiceUnavailable, errs.ErrElasticsearchUnavailable)
+			w := get(router, "/search?q=deaths%20cf10%201aa&sort=distance")
+
+			Convey("Then the unavailable error is returned rather than a missing postcode error", func() {
+				So(w.Code, ShouldEqual, http.StatusServiceUnavailable)
+				So(w.Body.String(), ShouldContainSubstring, errs.ErrElasticsearchUnavailable.Code)
+			})
+		})
+
+		Convey("When every index fails", func() {
+			es.Fail(testDatasetIndex, http.StatusInternalServerError, errs.ErrIndexNotFound)
+			es.Fail(testAreaProfileIndex, http.StatusInternalServerError, errs.ErrIndexNotFound)
+			es.Fail(testPublicationIndex, http.StatusInternalServerError, errs.ErrIndexNotFound)
+			w := get(router, "/search?q=deaths")
+
+			Convey("Then an internal server error is returned", func() {
+				So(w.Code, ShouldEqual, http.StatusInternalServerError)
+				So(decodeError(w).Code, ShouldEqual, errs.ErrInternalServer.Code)
+			})
+		})
+	})
+}
+
+func TestSearchDataErrors(t *testing.T) {
+	Convey("Given a search api", t, func() {
+		router, _ := setupAPI()
+
+		Convey("When the search term is empty then a bad request is returned", func() {
+			w := get(router, "/search?q=")
+
+			So(w.Code, ShouldEqual, http.StatusBadRequest)
+			So(decodeError(w).Code, ShouldEqual, errs.ErrEmptySearchTerm.Code)
+		})
+
+		Convey("When the limit is not a number then a bad request is returned", func() {
+			w := get(router, "/search?q=deaths&limit=ten")
+
+			So(w.Code, ShouldEqual, http.StatusBadRequest)
+
+			err := decodeError(w)
+			So(err.Code, ShouldEqual, errs.ErrParsingQueryParameters.Code)
+			So(err.Parameter, ShouldEqual, "limit")
+		})
+
+		Convey("When the offset exceeds the maximum then a bad request is returned", func() {
+			w := get(router, "/search?q=deaths&offset=1000")
+
+			So(w.Code, ShouldEqual, http.StatusBadRequest)
+			So(decodeError(w).Code, ShouldEqual, errs.CodeMaximumOffset)
+		})
+
+		Convey("When filtering by a topic that is not in the taxonomy then a bad request is returned", func() {
+			w := get(router, "/search?q=deaths&topics=notatopic")
+
+			So(w.Code, ShouldEqual, http.StatusBadRequest)
+			So(decodeError(w).Code, ShouldEqual, errs.CodeInvalidTopics)
+		})
+
+		Convey("When the search term has invalid syntax then a bad request is returned", func() {
+			w := get(router, "/search?q=%22deaths")
+
+			So(w.Code, ShouldEqual, http.StatusBadRequest)
+
+			err := decodeError(w)
+			So(err.Code, ShouldEqual, errs.CodeInvalidQuerySyntax)
+			So(err.Parameter, ShouldEqual, "q")
+		})
+	})
+}
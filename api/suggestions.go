@@ -0,0 +1,137 @@
+package api
+
+import (
+	"context"
+	"sort"
+
+	"github.com/ONSdigital/dp-census-alpha-search-api/models"
+	"github.com/ONSdigital/log.go/log"
+)
+
+const (
+	// suggestionsThreshold is the number of results at or below which spelling suggestions are returned
+	suggestionsThreshold = 0
+
+	suggestionsPerTerm = 3
+)
+
+// suggestion represents the best option for a single term along with its position in the search term
+type suggestion struct {
+	models.SuggestionOption
+	offset int
+	length int
+}
+
+// getSuggestions retrieves spelling suggestions for each term in the search term from dataset titles,
+// area names and topic titles, returning nil if the suggestions could not be retrieved
+func (api *SearchAPI) getSuggestions(ctx context.Context, term string, logData log.Data) *models.Suggestions {
+	query := buildSuggestQuery(term)
+
+	response, status, err := api.elasticsearch.QuerySearchIndex(ctx, api.datasetIndex+","+api.areaProfileIndex+","+api.publicationIndex, query)
+	if err != nil {
+		logData["elasticsearch_status"] = status
+		log.Event(ctx, "getSuggestions: failed to get spelling suggestions, continuing without suggestions", log.WARN, log.Error(err), logData)
+		return nil
+	}
+
+	best := make(map[int]suggestion)
+
+	for field, entries := range response.Suggest {
+		for _, entry := range entries {
+			for _, option := range entry.Options {
+				current, ok := best[entry.Offset]
+				if ok && !isBetterSuggestion(field, option, current) {
+					continue
+				}
+
+				best[entry.Offset] = suggestion{
+					SuggestionOption: models.SuggestionOption{
+						Original: entry.Text,
+						Text:     option.Text,
+						Field:    field,
+						Score:    option.Score,
+						Freq:     option.Freq,
+					},
+					offset: entry.Offset,
+					length: entry.Length,
+				}
+			}
+		}
+	}
+
+	var bestSuggestions []suggestion
+	for _, s := range best {
+		bestSuggestions = append(bestSuggestions, s)
+	}
+
+	sort.Slice(bestSuggestions, func(i, j int) bool {
+		return bestSuggestions[i].offset < bestSuggestions[j].offset
+	})
+
+	suggestions := &models.Suggestions{
+		Options: []models.SuggestionOption{},
+	}
+
+	if len(bestSuggestions) == 0 {
+		return suggestions
+	}
+
+	// Replace each misspelt term with the best suggestion to build the suggested search term
+	runes := []rune(term)
+	var text []rune
+	position := 0
+	for _, s := range bestSuggestions {
+		if s.offset < position || s.offset+s.length > len(runes) {
+			continue
+		}
+
+		text = append(text, runes[position:s.offset]...)
+		text = append(text, []rune(s.Text)...)
+		position = s.offset + s.length
+
+		suggestions.Options = append(suggestions.Options, s.SuggestionOption)
+	}
+	text = append(text, runes[position:]...)
+
+	suggestions.Text = string(text)
+
+	return suggestions
+}
+
+// isBetterSuggestion compares an option against the current best suggestion by score, then frequency
+// and finally field name so the chosen suggestion does not depend on the order of the response
+func isBetterSuggestion(field string, option models.SuggestOption, current suggestion) bool {
+	if option.Score != current.Score {
+		return option.Score > current.Score
+	}
+
+	if option.Freq != current.Freq {
+		return option.Freq > current.Freq
+	}
+
+	return field < current.Field
+}
+
+func buildSuggestQuery(term string) *models.SuggestBody {
+	return &models.SuggestBody{
+		Size: 0,
+		Suggest: models.Suggest{
+			Text:   term,
+			Title:  newTermSuggester("title"),
+			Name:   newTermSuggester("name"),
+			Topic1: newTermSuggester("topic1.raw"),
+			Topic2: newTermSuggester("topic2.raw"),
+			Topic3: newTermSuggester("topic3.raw"),
+		},
+	}
+}
+
+func newTermSuggester(field string) *models.Suggester {
+	return &models.Suggester{
+		Term: &models.TermSuggester{
+			Field:       field,
+			Size:        suggestionsPerTerm,
+			SuggestMode: "missing",
+		},
+	}
+}
@@ -21,7 +21,7 @@ func (api *SearchAPI) getTaxonomy(w http.ResponseWriter, r *http.Request) {
 
 	log.Event(ctx, "getTaxonomy endpoint: incoming request", log.INFO)
 
-	b, err := json.Marshal(api.taxonomy)
+	b, err := json.Marshal(api.reference.Get().Taxonomy)
 	if err != nil {
 		log.Event(ctx, "getTaxonomy endpoint: failed to marshal taxonomy resource into bytes", log.ERROR, log.Error(err))
 		setErrorCode(w, errs.ErrInternalServer)
@@ -30,7 +30,6 @@ func (api *SearchAPI) getTaxonomy(w http.ResponseWriter, r *http.Request) {
 	_, err = w.Write(b)
 	if err != nil {
 		log.Event(ctx, "getTaxonomy endpoint: error writing response", log.ERROR, log.Error(err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 
 	log.Event(ctx, "getTaxonomy endpoint: successfully retreived taxonomy", log.INFO)
@@ -61,7 +60,7 @@ func (api *SearchAPI) getTopic(w http.ResponseWriter, r *http.Request) {
 
 	var result *Topic
 	var hasValidTopic bool
-	for _, taxonomy := range api.taxonomy.Topics {
+	for _, taxonomy := range api.reference.Get().Taxonomy.Topics {
 		if topic == taxonomy.FormattedTitle {
 			hasValidTopic = true
 
@@ -101,7 +100,6 @@ func (api *SearchAPI) getTopic(w http.ResponseWriter, r *http.Request) {
 	_, err = w.Write(b)
 	if err != nil {
 		log.Event(ctx, "getTopic endpoint: error writing response", log.ERROR, log.Error(err), logData)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 
 	log.Event(ctx, "getTopic endpoint: successfully retrieved topic", log.INFO, logData)
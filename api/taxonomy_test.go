@@ -0,0 +1,52 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	errs "github.com/ONSdigital/dp-census-alpha-search-api/apierrors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestGetTopic(t *testing.T) {
+	Convey("Given a search api with the taxonomy in the data directory", t, func() {
+		router, _ := setupAPI()
+
+		Convey("When a top level topic is requested", func() {
+			w := get(router, "/taxonomy/economy")
+
+			Convey("Then the topic is returned with its child topics", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+
+				var topic Topic
+				So(json.Unmarshal(w.Body.Bytes(), &topic), ShouldBeNil)
+				So(topic.Topic, ShouldEqual, "economy")
+				So(topic.ParentTopic, ShouldBeEmpty)
+				So(topic.ChildTopics, ShouldContain, "economicoutputandproductivity")
+			})
+		})
+
+		Convey("When a child topic is requested", func() {
+			w := get(router, "/taxonomy/birthsdeathsandmarriages")
+
+			Convey("Then the topic is returned with its parent topic", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+
+				var topic Topic
+				So(json.Unmarshal(w.Body.Bytes(), &topic), ShouldBeNil)
+				So(topic.Topic, ShouldEqual, "birthsdeathsandmarriages")
+				So(topic.ParentTopic, ShouldEqual, "peoplepopulationandcommunity")
+			})
+		})
+
+		Convey("When a topic that is not in the taxonomy is requested", func() {
+			w := get(router, "/taxonomy/notatopic")
+
+			Convey("Then a not found error is returned", func() {
+				So(w.Code, ShouldEqual, http.StatusNotFound)
+				So(decodeError(w).Code, ShouldEqual, errs.ErrTopicNotFound.Code)
+			})
+		})
+	})
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/ONSdigital/dp-census-alpha-search-api/models"
+)
+
+// Timeouts are the longest time each elasticsearch operation can take before it is cancelled,
+// an operation without a timeout only ends when the incoming request does
+type Timeouts struct {
+	Search      time.Duration
+	AreaProfile time.Duration
+	Postcode    time.Duration
+}
+
+// timeoutElasticsearch cancels calls to elasticsearch that take longer than the timeout for
+// the operation
+type timeoutElasticsearch struct {
+	elasticsearch Elasticsearcher
+	timeouts      Timeouts
+}
+
+// withTimeouts wraps elasticsearch so that each operation is cancelled after its timeout
+func withTimeouts(elasticsearch Elasticsearcher, timeouts Timeouts) Elasticsearcher {
+	if timeouts == (Timeouts{}) {
+		return elasticsearch
+	}
+
+	return &timeoutElasticsearch{
+		elasticsearch: elasticsearch,
+		timeouts:      timeouts,
+	}
+}
+
+func (t *timeoutElasticsearch) QuerySearchIndex(ctx context.Context, indexName string, query interface{}) (*models.SearchResponse, int, error) {
+	ctx, cancel := withTimeout(ctx, t.timeouts.Search)
+	defer cancel()
+
+	return t.elasticsearch.QuerySearchIndex(ctx, indexName, query)
+}
+
+func (t *timeoutElasticsearch) GetAreaProfile(ctx context.Context, indexName string, query interface{}) (*models.AreaProfile, int, error) {
+	ctx, cancel := withTimeout(ctx, t.timeouts.AreaProfile)
+	defer cancel()
+
+	return t.elasticsearch.GetAreaProfile(ctx, indexName, query)
+}
+
+func (t *timeoutElasticsearch) GetPostcodes(ctx context.Context, indexName, postcode string) (*models.PostcodeResponse, int, error) {
+	ctx, cancel := withTimeout(ctx, t.timeouts.Postcode)
+	defer cancel()
+
+	return t.elasticsearch.GetPostcodes(ctx, indexName, postcode)
+}
+
+// IndexDocument is not cancelled by a timeout, admin writes only end when the request does
+func (t *timeoutElasticsearch) IndexDocument(ctx context.Context, indexName, id string, document interface{}) (int, error) {
+	return t.elasticsearch.IndexDocument(ctx, indexName, id, document)
+}
+
+// DeleteDocument is not cancelled by a timeout, admin writes only end when the request does
+func (t *timeoutElasticsearch) DeleteDocument(ctx context.Context, indexName, id string) (int, error) {
+	return t.elasticsearch.DeleteDocument(ctx, indexName, id)
+}
+
+// withTimeout returns a context that is cancelled after the timeout, the context is returned
+// unchanged if the timeout is not positive
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
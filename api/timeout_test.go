@@ -0,0 +1,85 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	errs "github.com/ONSdigital/dp-census-alpha-search-api/apierrors"
+	"github.com/ONSdigital/dp-census-alpha-search-api/models"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTimeouts(t *testing.T) {
+	Convey("Given a search api with timeouts shorter than elasticsearch takes to respond", t, func() {
+		router, es := setupAPIWithTimeouts(Timeouts{
+			Search:      10 * time.Millisecond,
+			AreaProfile: 10 * time.Millisecond,
+			Postcode:    10 * time.Millisecond,
+		})
+
+		Convey("When an area profile is requested and the area profile index is slow", func() {
+			es.Delay(testAreaProfileIndex, time.Second)
+			w := get(router, "/area-profiles/W06000015")
+
+			Convey("Then a gateway timeout is returned", func() {
+				So(w.Code, ShouldEqual, http.StatusGatewayTimeout)
+				So(decodeError(w).Code, ShouldEqual, errs.ErrElasticsearchTimeout.Code)
+			})
+		})
+
+		Convey("When an area profile search is made and the dataset index is slow", func() {
+			es.Delay(testDatasetIndex, time.Second)
+			w := get(router, "/area-profiles/W06000015/search?q=deaths")
+
+			Convey("Then a gateway timeout is returned", func() {
+				So(w.Code, ShouldEqual, http.StatusGatewayTimeout)
+				So(decodeError(w).Code, ShouldEqual, errs.ErrElasticsearchTimeout.Code)
+			})
+		})
+
+		Convey("When a postcode is requested and the postcode index is slow", func() {
+			es.Delay(testPostcodeIndex, time.Second)
+			w := get(router, "/postcodes/cf101aa")
+
+			Convey("Then a gateway timeout is returned", func() {
+				So(w.Code, ShouldEqual, http.StatusGatewayTimeout)
+				So(decodeError(w).Code, ShouldEqual, errs.ErrElasticsearchTimeout.Code)
+			})
+		})
+
+		Convey("When a search is made and only the publication index is slow", func() {
+			es.Delay(testPublicationIndex, time.Second)
+			w := get(router, "/search?q=deaths")
+
+			Convey("Then the lists that searched the publication index are reported as timed out", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+
+				var body models.AllSearchResults
+				So(json.Unmarshal(w.Body.Bytes(), &body), ShouldBeNil)
+				So(body.Partial, ShouldBeTrue)
+				So(body.Errors, ShouldHaveLength, 2)
+
+				for _, listErr := range body.Errors {
+					So(listErr.List, ShouldBeIn, allList, publicationList)
+					So(listErr.Error.Code, ShouldEqual, errs.ErrElasticsearchTimeout.Code)
+				}
+
+				So(body.Counts.Datasets, ShouldEqual, 2)
+			})
+		})
+
+		Convey("When a search is made and elasticsearch responds within the timeouts", func() {
+			w := get(router, "/search?q=deaths")
+
+			Convey("Then the search results are returned", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+
+				var body models.AllSearchResults
+				So(json.Unmarshal(w.Body.Bytes(), &body), ShouldBeNil)
+				So(body.Partial, ShouldBeFalse)
+			})
+		})
+	})
+}
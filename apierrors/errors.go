@@ -1,61 +1,117 @@
 package apierrors
 
-import "errors"
+import "net/http"
 
-// A list of error messages for Search API
-var (
-	ErrAreaProfileNotFound = errors.New("area profile not found")
-	ErrBadSearchQuery      = errors.New("bad query sent to elasticsearch index")
-	// ErrBoundaryFileNotFound    = errors.New("invalid id, boundary file does not exist")
-	// ErrEmptyCoordinates        = errors.New("missing coordinates in array")
-	// ErrEmptyDistanceTerm       = errors.New("empty query term: distance")
-	ErrEmptySearchTerm = errors.New("empty search term")
-	// ErrEmptyShape              = errors.New("empty shape")
-	ErrIndexNotFound  = errors.New("search index not found")
-	ErrInternalServer = errors.New("internal server error")
-	// ErrInvalidCoordinates      = errors.New("should contain two coordinates, representing [latitude, longitude]")
-	// ErrInvalidShape            = errors.New("invalid list of coordinates, the first and last coordinates should be the same to complete boundary line")
-	// ErrLessThanFourCoordinates = errors.New("invalid number of coordinates, need a minimum of 4 values")
-	// ErrLessThanTwoPolygons     = errors.New("invalid number of polygons, needs a minimum of 2 values if the geometry type is set to multipolygon")
-	ErrMarshallingQuery = errors.New("failed to marshal query to bytes for request body to send to elastic")
-	// ErrMissingShapeFile        = errors.New("missing shapefile value in request")
-	// ErrMissingType             = errors.New("missing type value in request")
-	ErrNegativeLimit           = errors.New("limit needs to be a positive number, limit cannot be lower than 0")
-	ErrNegativeOffset          = errors.New("offset needs to be a positive number, offset cannot be lower than 0")
-	ErrParsingQueryParameters  = errors.New("failed to parse query parameters, values must be an integer")
-	ErrPostcodeNotFound        = errors.New("postcode not found")
-	ErrTooManyDimensionFilters = errors.New("Too many dimension filters, limited to a maximum of 10")
-	ErrTooManyHierarchyFilters = errors.New("Too many hierarchy filters, limited to a maximum of 5")
-	ErrTooManyTopicFilters     = errors.New("Too many topic filters, limited to a maximum of 10")
-	ErrTopicNotFound           = errors.New("Topic not found")
-	ErrUnableToParseJSON       = errors.New("failed to parse json body")
-	ErrUnableToReadMessage     = errors.New("failed to read message body")
-	// ErrUnexpectedStatusCode    = errors.New("unexpected status code from elastic api")
-	ErrUnmarshallingJSON = errors.New("failed to unmarshal data")
-
-	NotFoundMap = map[error]bool{
-		// ErrBoundaryFileNotFound: true,
-		ErrAreaProfileNotFound: true,
-		ErrTopicNotFound:       true,
+// Error represents an error returned to api users, the code is a machine readable identifier
+// for the error that does not change when the wording of the message changes
+type Error struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Parameter string `json:"parameter,omitempty"`
+	// Suggestions are valid values similar to an invalid parameter value
+	Suggestions []string `json:"suggestions,omitempty"`
+	Status      int      `json:"-"`
+}
+
+// Errors represents the body of an error response
+type Errors struct {
+	Errors []*Error `json:"errors"`
+}
+
+// New creates an error to return to api users, parameter is the request parameter
+// that caused the error and can be empty
+func New(code string, status int, message, parameter string) *Error {
+	return &Error{
+		Code:      code,
+		Message:   message,
+		Parameter: parameter,
+		Status:    status,
 	}
+}
 
-	BadRequestMap = map[error]bool{
-		// ErrEmptyCoordinates:        true,
-		// ErrEmptyDistanceTerm:       true,
-		ErrEmptySearchTerm: true,
-		// ErrEmptyShape:              true,
-		// ErrInvalidCoordinates:      true,
-		// ErrInvalidShape:            true,
-		// ErrLessThanFourCoordinates: true,
-		// ErrLessThanTwoPolygons:     true,
-		// ErrMissingType:             true,
-		ErrNegativeLimit:           true,
-		ErrNegativeOffset:          true,
-		ErrParsingQueryParameters:  true,
-		ErrTooManyDimensionFilters: true,
-		ErrTooManyHierarchyFilters: true,
-		ErrTooManyTopicFilters:     true,
-		ErrUnableToParseJSON:       true,
-		ErrUnableToReadMessage:     true,
+// Error returns the message of the error
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is checks whether the target is an error with the same code
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// WithParameter returns a copy of an api error for the request parameter that caused it, any
+// other error is returned unchanged
+func WithParameter(err error, parameter string) error {
+	e, ok := err.(*Error)
+	if !ok {
+		return err
 	}
+
+	copied := *e
+	copied.Parameter = parameter
+
+	return &copied
+}
+
+// Codes of errors that are created with the invalid value from the request
+const (
+	CodeInvalidDimensions  = "invalid_dimensions"
+	CodeInvalidDistance    = "invalid_distance"
+	CodeInvalidField       = "invalid_field"
+	CodeInvalidHierarchies = "invalid_hierarchies"
+	CodeInvalidQuerySyntax = "invalid_query_syntax"
+	CodeInvalidRelation    = "invalid_relation"
+	CodeInvalidSort        = "invalid_sort"
+	CodeInvalidTopics      = "invalid_topics"
+	CodeMaximumLimit       = "maximum_limit_exceeded"
+	CodeMaximumOffset      = "maximum_offset_exceeded"
+	CodeMissingField       = "missing_field"
+	CodeReloadFailed       = "reload_failed"
+)
+
+// A list of error messages for Search API
+var (
+	ErrAreaProfileNotFound = New("area_profile_not_found", http.StatusNotFound, "area profile not found", "")
+	ErrBadSearchQuery      = New("bad_search_query", http.StatusInternalServerError, "bad query sent to elasticsearch index", "")
+	// ErrBoundaryFileNotFound    = New("boundary_file_not_found", http.StatusNotFound, "invalid id, boundary file does not exist", "id")
+	// ErrEmptyCoordinates        = New("empty_coordinates", http.StatusBadRequest, "missing coordinates in array", "")
+	// ErrEmptyDistanceTerm       = New("empty_distance", http.StatusBadRequest, "empty query term: distance", "distance")
+	ErrCursorWithOffset         = New("cursor_with_offset", http.StatusBadRequest, "offset cannot be used when paging with a cursor", "offset")
+	ErrDatasetNotFound          = New("dataset_not_found", http.StatusNotFound, "dataset not found", "")
+	ErrDocumentRejected         = New("document_rejected", http.StatusBadRequest, "document rejected by elasticsearch, check the values of each field", "")
+	ErrEmptyPostcode            = New("empty_postcode", http.StatusBadRequest, "empty postcode", "postcode")
+	ErrEmptySearchTerm          = New("empty_search_term", http.StatusBadRequest, "empty search term", "q")
+	ErrElasticsearchUnavailable = New("elasticsearch_unavailable", http.StatusServiceUnavailable, "elasticsearch is unavailable, try again later", "")
+	ErrElasticsearchTimeout     = New("elasticsearch_timeout", http.StatusGatewayTimeout, "elasticsearch did not respond in time, try again later", "")
+	// ErrEmptyShape              = New("empty_shape", http.StatusBadRequest, "empty shape", "")
+	ErrIndexNotFound      = New("index_not_found", http.StatusInternalServerError, "search index not found", "")
+	ErrInternalServer     = New("internal_server_error", http.StatusInternalServerError, "internal server error", "")
+	ErrInvalidAutocorrect = New("invalid_autocorrect", http.StatusBadRequest, "invalid autocorrect value, should be true or false", "autocorrect")
+	ErrInvalidCursor      = New("invalid_cursor", http.StatusBadRequest, "invalid cursor, use * to start paging or the next_cursor value from the previous page", "cursor")
+	ErrInvalidLatitude    = New("invalid_latitude", http.StatusBadRequest, "invalid lat, should be a number between -90 and 90", "lat")
+	ErrInvalidLongitude   = New("invalid_longitude", http.StatusBadRequest, "invalid lon, should be a number between -180 and 180", "lon")
+	ErrInvalidPostcode    = New("invalid_postcode", http.StatusBadRequest, "invalid postcode, should be a full uk postcode e.g. CF10 1AA", "postcode")
+	// ErrInvalidCoordinates      = New("invalid_coordinates", http.StatusBadRequest, "should contain two coordinates, representing [latitude, longitude]", "")
+	// ErrInvalidShape            = New("invalid_shape", http.StatusBadRequest, "invalid list of coordinates, the first and last coordinates should be the same to complete boundary line", "")
+	// ErrLessThanFourCoordinates = New("too_few_coordinates", http.StatusBadRequest, "invalid number of coordinates, need a minimum of 4 values", "")
+	// ErrLessThanTwoPolygons     = New("too_few_polygons", http.StatusBadRequest, "invalid number of polygons, needs a minimum of 2 values if the geometry type is set to multipolygon", "")
+	ErrMarshallingQuery = New("marshalling_query", http.StatusInternalServerError, "failed to marshal query to bytes for request body to send to elastic", "")
+	ErrMissingLatLon    = New("missing_lat_lon", http.StatusBadRequest, "missing query parameters, both lat and lon are required", "")
+	// ErrMissingShapeFile        = New("missing_shapefile", http.StatusBadRequest, "missing shapefile value in request", "")
+	// ErrMissingType             = New("missing_type", http.StatusBadRequest, "missing type value in request", "")
+	ErrNegativeLimit           = New("negative_limit", http.StatusBadRequest, "limit needs to be a positive number, limit cannot be lower than 0", "limit")
+	ErrNegativeOffset          = New("negative_offset", http.StatusBadRequest, "offset needs to be a positive number, offset cannot be lower than 0", "offset")
+	ErrParsingQueryParameters  = New("invalid_integer", http.StatusBadRequest, "failed to parse query parameters, values must be an integer", "")
+	ErrPostcodeNotFound        = New("postcode_not_found", http.StatusNotFound, "postcode not found", "postcode")
+	ErrSortDistanceNoPostcode  = New("sort_distance_no_postcode", http.StatusBadRequest, "sorting by distance requires a valid postcode in the search term", "sort")
+	ErrTooManyDimensionFilters = New("too_many_dimensions", http.StatusBadRequest, "Too many dimension filters, limited to a maximum of 10", "dimensions")
+	ErrTooManyHierarchyFilters = New("too_many_hierarchies", http.StatusBadRequest, "Too many hierarchy filters, limited to a maximum of 5", "hierarchies")
+	ErrTooManyTopicFilters     = New("too_many_topics", http.StatusBadRequest, "Too many topic filters, limited to a maximum of 10", "topics")
+	ErrTopicNotFound           = New("topic_not_found", http.StatusNotFound, "Topic not found", "topic")
+	ErrUnableToParseJSON       = New("invalid_json", http.StatusBadRequest, "failed to parse json body", "")
+	ErrUnableToReadMessage     = New("unreadable_body", http.StatusBadRequest, "failed to read message body", "")
+	ErrUnauthorised            = New("unauthorised", http.StatusUnauthorized, "missing or invalid admin auth token", "")
+	// ErrUnexpectedStatusCode    = New("unexpected_status_code", http.StatusInternalServerError, "unexpected status code from elastic api", "")
+	ErrUnmarshallingJSON = New("unmarshalling_json", http.StatusInternalServerError, "failed to unmarshal data", "")
 )
@@ -0,0 +1,136 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/ONSdigital/dp-census-alpha-search-api/metrics"
+)
+
+// Cache holds a bounded number of responses in memory for a fixed time, the least recently
+// used response is evicted when the cache is full. A nil cache is disabled and never holds
+// any responses. Cache is safe for concurrent use.
+type Cache struct {
+	mutex    sync.Mutex
+	capacity int
+	ttl      time.Duration
+	entries  map[string]*list.Element
+	// order holds entries from most to least recently used
+	order *list.List
+	now   func() time.Time
+}
+
+// entry represents a single cached response
+type entry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// New creates a cache holding up to capacity responses for the ttl, nil is returned if
+// either the capacity or the ttl is not positive as caching is disabled
+func New(capacity int, ttl time.Duration) *Cache {
+	if capacity <= 0 || ttl <= 0 {
+		return nil
+	}
+
+	return &Cache{
+		capacity: capacity,
+		ttl:      ttl,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+		now:      time.Now,
+	}
+}
+
+// Get returns the response for the key if it has not expired
+func (c *Cache) Get(key string) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	e := element.Value.(*entry)
+	if !c.now().Before(e.expires) {
+		c.remove(element)
+		metrics.CacheEvicted(metrics.CacheExpired)
+		return nil, false
+	}
+
+	c.order.MoveToFront(element)
+
+	return e.value, true
+}
+
+// Set stores the response for the key, evicting the least recently used response if the
+// cache is full
+func (c *Cache) Set(key string, value []byte) {
+	if c == nil {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	expires := c.now().Add(c.ttl)
+
+	if element, ok := c.entries[key]; ok {
+		e := element.Value.(*entry)
+		e.value = value
+		e.expires = expires
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&entry{key: key, value: value, expires: expires})
+
+	for c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+		metrics.CacheEvicted(metrics.CacheFull)
+	}
+
+	metrics.SetCacheEntries(c.order.Len())
+}
+
+// Purge removes every response from the cache
+func (c *Cache) Purge() {
+	if c == nil {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.entries = make(map[string]*list.Element)
+	c.order.Init()
+
+	metrics.SetCacheEntries(0)
+}
+
+// Len returns the number of responses in the cache, including expired responses that have
+// not been evicted yet
+func (c *Cache) Len() int {
+	if c == nil {
+		return 0
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.order.Len()
+}
+
+func (c *Cache) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*entry).key)
+
+	metrics.SetCacheEntries(c.order.Len())
+}
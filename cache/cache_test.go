@@ -0,0 +1,134 @@
+package cache
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// newCache creates a cache with a clock that is moved forward by the returned function
+func newCache(capacity int, ttl time.Duration) (*Cache, func(time.Duration)) {
+	c := New(capacity, ttl)
+
+	now := time.Date(2020, time.June, 1, 12, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	return c, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestNew(t *testing.T) {
+	Convey("When a cache is created without a capacity or ttl", t, func() {
+		disabled := []*Cache{New(0, time.Minute), New(10, 0)}
+
+		Convey("Then caching is disabled and responses are never returned", func() {
+			for _, c := range disabled {
+				So(c, ShouldBeNil)
+
+				c.Set("key", []byte("response"))
+				value, ok := c.Get("key")
+				So(ok, ShouldBeFalse)
+				So(value, ShouldBeNil)
+				So(c.Len(), ShouldEqual, 0)
+				c.Purge()
+			}
+		})
+	})
+}
+
+func TestGet(t *testing.T) {
+	Convey("Given a cache holding a response", t, func() {
+		c, advance := newCache(10, time.Minute)
+		c.Set("key", []byte("response"))
+
+		Convey("When the response is requested before it expires", func() {
+			advance(59 * time.Second)
+			value, ok := c.Get("key")
+
+			Convey("Then the response is returned", func() {
+				So(ok, ShouldBeTrue)
+				So(string(value), ShouldEqual, "response")
+			})
+		})
+
+		Convey("When the response is requested once it has expired", func() {
+			advance(time.Minute)
+			value, ok := c.Get("key")
+
+			Convey("Then the response is not returned and is evicted", func() {
+				So(ok, ShouldBeFalse)
+				So(value, ShouldBeNil)
+				So(c.Len(), ShouldEqual, 0)
+			})
+		})
+
+		Convey("When the response is replaced just before it expires", func() {
+			advance(59 * time.Second)
+			c.Set("key", []byte("new response"))
+			advance(59 * time.Second)
+			value, ok := c.Get("key")
+
+			Convey("Then the new response is returned with a new expiry", func() {
+				So(ok, ShouldBeTrue)
+				So(string(value), ShouldEqual, "new response")
+				So(c.Len(), ShouldEqual, 1)
+			})
+		})
+
+		Convey("When a different key is requested", func() {
+			_, ok := c.Get("other")
+
+			Convey("Then no response is returned", func() {
+				So(ok, ShouldBeFalse)
+			})
+		})
+	})
+}
+
+func TestSet(t *testing.T) {
+	Convey("Given a full cache", t, func() {
+		c, _ := newCache(3, time.Minute)
+		for i := 0; i < 3; i++ {
+			c.Set(strconv.Itoa(i), []byte(strconv.Itoa(i)))
+		}
+
+		Convey("When the oldest response is used and another response is stored", func() {
+			_, ok := c.Get("0")
+			So(ok, ShouldBeTrue)
+
+			c.Set("3", []byte("3"))
+
+			Convey("Then the least recently used response is evicted", func() {
+				So(c.Len(), ShouldEqual, 3)
+
+				_, ok := c.Get("1")
+				So(ok, ShouldBeFalse)
+
+				for _, key := range []string{"0", "2", "3"} {
+					_, ok := c.Get(key)
+					So(ok, ShouldBeTrue)
+				}
+			})
+		})
+	})
+}
+
+func TestPurge(t *testing.T) {
+	Convey("Given a cache holding responses", t, func() {
+		c, _ := newCache(10, time.Minute)
+		c.Set("a", []byte("a"))
+		c.Set("b", []byte("b"))
+
+		Convey("When the cache is purged", func() {
+			c.Purge()
+
+			Convey("Then every response is removed", func() {
+				So(c.Len(), ShouldEqual, 0)
+
+				_, ok := c.Get("a")
+				So(ok, ShouldBeFalse)
+			})
+		})
+	})
+}
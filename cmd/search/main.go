@@ -2,16 +2,19 @@ package main
 
 import (
 	"context"
-	"encoding/json"
-	"io/ioutil"
+	"errors"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 
 	"github.com/ONSdigital/dp-census-alpha-search-api/api"
+	"github.com/ONSdigital/dp-census-alpha-search-api/cache"
 	"github.com/ONSdigital/dp-census-alpha-search-api/config"
+	"github.com/ONSdigital/dp-census-alpha-search-api/health"
+	"github.com/ONSdigital/dp-census-alpha-search-api/internal/awsauth"
 	es "github.com/ONSdigital/dp-census-alpha-search-api/internal/elasticsearch"
-	"github.com/ONSdigital/dp-census-alpha-search-api/models"
+	"github.com/ONSdigital/dp-census-alpha-search-api/reference"
 	dphttp "github.com/ONSdigital/dp-net/http"
 	"github.com/ONSdigital/log.go/log"
 )
@@ -40,60 +43,84 @@ func run(ctx context.Context) error {
 
 	log.Event(ctx, "config on startup", log.INFO, log.Data{"config": cfg})
 
-	// Read in Taxonomy JSON into memory
-	taxonomyFile, err := ioutil.ReadFile(cfg.TaxonomyFilename)
-	if err != nil {
-		log.Event(ctx, "failed to read taxonomy file", log.ERROR, log.Error(err), log.Data{"taxonomy_filename": cfg.TaxonomyFilename})
-		return err
-	}
-
-	var taxonomy models.Taxonomy
-
-	if err = json.Unmarshal([]byte(taxonomyFile), &taxonomy); err != nil {
-		log.Event(ctx, "unable to unmarshal taxonomy into struct", log.ERROR, log.Error(err), log.Data{"taxonomy_filename": cfg.TaxonomyFilename})
+	if http.StatusText(cfg.PartialResultsStatus) == "" {
+		err = errors.New("invalid partial results status code")
+		log.Event(ctx, "failed to validate configuration", log.FATAL, log.Error(err), log.Data{"partial_results_status": cfg.PartialResultsStatus})
 		return err
 	}
 
-	// Read in Dimensions JSON into memory
-	dimensionsFile, err := ioutil.ReadFile(cfg.DimensionsFilename)
+	// Read in taxonomy, dimensions and hierarchies JSON into memory
+	referenceDocs, err := reference.New(reference.Files{
+		Dimensions:  cfg.DimensionsFilename,
+		Hierarchies: cfg.HierarchiesFilename,
+		Taxonomy:    cfg.TaxonomyFilename,
+	})
 	if err != nil {
-		log.Event(ctx, "failed to read dimensions file", log.ERROR, log.Error(err), log.Data{"dimensions_filename": cfg.DimensionsFilename})
+		log.Event(ctx, "failed to load reference files", log.ERROR, log.Error(err), log.Data{"taxonomy_filename": cfg.TaxonomyFilename, "dimensions_filename": cfg.DimensionsFilename, "hierarchies_filename": cfg.HierarchiesFilename})
 		return err
 	}
 
-	var dimensions models.DimensionsDoc
-
-	if err = json.Unmarshal([]byte(dimensionsFile), &dimensions); err != nil {
-		log.Event(ctx, "unable to unmarshal taxonomy into struct", log.ERROR, log.Error(err), log.Data{"dimensions_filename": cfg.DimensionsFilename})
-		return err
+	// Retries are made by the elasticsearch api, which only retries idempotent calls
+	var cli dphttp.Clienter = dphttp.NewClient()
+	cli.SetMaxRetries(0)
+
+	// Requests to elasticsearch hosted by aws are signed with the credentials from the environment
+	if cfg.SignElasticsearchRequests {
+		signer, err := awsauth.NewSigner(awsauth.Credentials{
+			AccessKeyID:     cfg.AWSAccessKeyID,
+			SecretAccessKey: cfg.AWSSecretAccessKey,
+			SessionToken:    cfg.AWSSessionToken,
+		}, cfg.AWSRegion)
+		if err != nil {
+			log.Event(ctx, "failed to create aws request signer", log.FATAL, log.Error(err), log.Data{"aws_region": cfg.AWSRegion})
+			return err
+		}
+
+		cli = awsauth.NewClient(cli, signer)
 	}
 
-	// Read in Hierarchies JSON into memory
-	hierarchiesFile, err := ioutil.ReadFile(cfg.HierarchiesFilename)
+	esAPI := es.NewElasticSearchAPI(cli, cfg.ElasticSearchAPIURL)
+	esAPI.SetRetries(es.Retries{
+		MaxRetries:     cfg.ElasticSearchMaxRetries,
+		InitialBackoff: cfg.ElasticSearchRetryBackoff,
+		MaxBackoff:     cfg.ElasticSearchMaxRetryBackoff,
+	})
+	esAPI.SetCircuitBreaker(es.NewCircuitBreaker(cfg.ElasticSearchCircuitBreakerThreshold, cfg.ElasticSearchCircuitBreakerCooldown))
+
+	// Requests to create indices and add documents depend on the version of the cluster
+	version, status, err := esAPI.DetectVersion(ctx)
 	if err != nil {
-		log.Event(ctx, "failed to read hierarchies file", log.ERROR, log.Error(err), log.Data{"hierarchies_filename": cfg.HierarchiesFilename})
+		log.Event(ctx, "failed to start up, unable to connect to elastic search instance", log.ERROR, log.Error(err), log.Data{"http_status": status})
 		return err
 	}
 
-	var hierarchies models.GeoHierarchiesDoc
+	log.Event(ctx, "connected to elastic search instance", log.INFO, log.Data{"version": version.String()})
 
-	if err = json.Unmarshal([]byte(hierarchiesFile), &hierarchies); err != nil {
-		log.Event(ctx, "unable to unmarshal taxonomy into struct", log.ERROR, log.Error(err), log.Data{"hierarchies_filename": cfg.HierarchiesFilename})
-		return err
-	}
+	// Caching is disabled when either the size or ttl is zero
+	responseCache := cache.New(cfg.SearchCacheSize, cfg.SearchCacheTTL)
 
-	cli := dphttp.NewClient()
-	esAPI := es.NewElasticSearchAPI(cli, cfg.ElasticSearchAPIURL)
+	healthChecker := health.New(esAPI, referenceDocs, cfg.HealthCheckCacheInterval, cfg.HealthCheckWarmingPeriod, cfg.DatasetIndex, cfg.AreaProfileIndex, cfg.PoscodeIndex)
 
-	_, status, err := esAPI.CallElastic(ctx, cfg.ElasticSearchAPIURL, "GET", nil)
-	if err != nil {
-		log.Event(ctx, "failed to start up, unable to connect to elastic search instance", log.ERROR, log.Error(err), log.Data{"http_status": status})
-		return err
+	// Calls to elasticsearch are cancelled after the timeout for the operation
+	timeouts := api.Timeouts{
+		Search:      cfg.ElasticSearchSearchTimeout,
+		AreaProfile: cfg.ElasticSearchAreaProfileTimeout,
+		Postcode:    cfg.ElasticSearchPostcodeTimeout,
 	}
 
 	apiErrors := make(chan error, 1)
 
-	api.CreateAndInitialiseSearchAPI(ctx, cfg.BindAddr, esAPI, cfg.MaxSearchResultsOffset, cfg.DatasetIndex, cfg.AreaProfileIndex, cfg.PoscodeIndex, dimensions, taxonomy, hierarchies, apiErrors)
+	api.CreateAndInitialiseSearchAPI(ctx, cfg.BindAddr, esAPI, cfg.MaxSearchResultsOffset, cfg.DatasetIndex, cfg.AreaProfileIndex, cfg.PoscodeIndex, cfg.PublicationIndex, cfg.PartialResultsStatus, cfg.AdminAuthToken, referenceDocs, healthChecker, responseCache, timeouts, apiErrors)
+
+	// reload reference files on SIGHUP or whenever the files change
+	reloadCtx, cancelReload := context.WithCancel(ctx)
+	defer cancelReload()
+
+	go reloadOnSignal(reloadCtx, referenceDocs)
+
+	if cfg.ReloadInterval > 0 {
+		go referenceDocs.Watch(reloadCtx, cfg.ReloadInterval)
+	}
 
 	// block until a fatal error occurs
 	select {
@@ -106,3 +133,19 @@ func run(ctx context.Context) error {
 
 	return nil
 }
+
+func reloadOnSignal(ctx context.Context, referenceDocs *reference.Store) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			log.Event(ctx, "SIGHUP received, reloading reference files", log.INFO)
+			referenceDocs.Reload(ctx)
+		}
+	}
+}
@@ -1,21 +1,43 @@
 package config
 
 import (
+	"time"
+
 	"github.com/kelseyhightower/envconfig"
 )
 
 // Config is the filing resource handler config
 type Config struct {
-	AreaProfileIndex          string `envconfig:"AREA_PROFILE_SEARCH_INDEX"`
-	BindAddr                  string `envconfig:"BIND_ADDR"                  json:"-"`
-	DatasetIndex              string `envconfig:"DATASET_SEARCH_INDEX"`
-	DimensionsFilename        string `envconfig:"DIMENSIONS_FILENAME"`
-	ElasticSearchAPIURL       string `envconfig:"ELASTIC_SEARCH_URL"         json:"-"`
-	HierarchiesFilename       string `envconfig:"HIERARCHIES_FILENAME"`
-	MaxSearchResultsOffset    int    `envconfig:"MAX_SEARCH_RESULTS_OFFSET"`
-	PoscodeIndex              string `envconfig:"POSTCODE_SEARCH_INDEX"`
-	SignElasticsearchRequests bool   `envconfig:"SIGN_ELASTICSEARCH_REQUESTS"`
-	TaxonomyFilename          string `envconfig:"TAXONOMY_FILENAME"`
+	AWSAccessKeyID                       string        `envconfig:"AWS_ACCESS_KEY_ID"          json:"-"`
+	AWSRegion                            string        `envconfig:"AWS_REGION"`
+	AWSSecretAccessKey                   string        `envconfig:"AWS_SECRET_ACCESS_KEY"      json:"-"`
+	AWSSessionToken                      string        `envconfig:"AWS_SESSION_TOKEN"          json:"-"`
+	AdminAuthToken                       string        `envconfig:"ADMIN_AUTH_TOKEN"           json:"-"`
+	AreaProfileIndex                     string        `envconfig:"AREA_PROFILE_SEARCH_INDEX"`
+	BindAddr                             string        `envconfig:"BIND_ADDR"                  json:"-"`
+	DatasetIndex                         string        `envconfig:"DATASET_SEARCH_INDEX"`
+	DimensionsFilename                   string        `envconfig:"DIMENSIONS_FILENAME"`
+	ElasticSearchAPIURL                  string        `envconfig:"ELASTIC_SEARCH_URL"         json:"-"`
+	ElasticSearchAreaProfileTimeout      time.Duration `envconfig:"ELASTIC_SEARCH_AREA_PROFILE_TIMEOUT"`
+	ElasticSearchCircuitBreakerCooldown  time.Duration `envconfig:"ELASTIC_SEARCH_CIRCUIT_BREAKER_COOLDOWN"`
+	ElasticSearchCircuitBreakerThreshold int           `envconfig:"ELASTIC_SEARCH_CIRCUIT_BREAKER_THRESHOLD"`
+	ElasticSearchMaxRetries              int           `envconfig:"ELASTIC_SEARCH_MAX_RETRIES"`
+	ElasticSearchMaxRetryBackoff         time.Duration `envconfig:"ELASTIC_SEARCH_MAX_RETRY_BACKOFF"`
+	ElasticSearchPostcodeTimeout         time.Duration `envconfig:"ELASTIC_SEARCH_POSTCODE_TIMEOUT"`
+	ElasticSearchRetryBackoff            time.Duration `envconfig:"ELASTIC_SEARCH_RETRY_BACKOFF"`
+	ElasticSearchSearchTimeout           time.Duration `envconfig:"ELASTIC_SEARCH_SEARCH_TIMEOUT"`
+	HealthCheckCacheInterval             time.Duration `envconfig:"HEALTH_CHECK_CACHE_INTERVAL"`
+	HealthCheckWarmingPeriod             time.Duration `envconfig:"HEALTH_CHECK_WARMING_PERIOD"`
+	HierarchiesFilename                  string        `envconfig:"HIERARCHIES_FILENAME"`
+	MaxSearchResultsOffset               int           `envconfig:"MAX_SEARCH_RESULTS_OFFSET"`
+	PartialResultsStatus                 int           `envconfig:"PARTIAL_RESULTS_STATUS"`
+	PoscodeIndex                         string        `envconfig:"POSTCODE_SEARCH_INDEX"`
+	PublicationIndex                     string        `envconfig:"PUBLICATION_SEARCH_INDEX"`
+	ReloadInterval                       time.Duration `envconfig:"RELOAD_INTERVAL"`
+	SearchCacheSize                      int           `envconfig:"SEARCH_CACHE_SIZE"`
+	SearchCacheTTL                       time.Duration `envconfig:"SEARCH_CACHE_TTL"`
+	SignElasticsearchRequests            bool          `envconfig:"SIGN_ELASTICSEARCH_REQUESTS"`
+	TaxonomyFilename                     string        `envconfig:"TAXONOMY_FILENAME"`
 }
 
 var cfg *Config
@@ -27,16 +49,32 @@ func Get() (*Config, error) {
 	}
 
 	cfg = &Config{
-		AreaProfileIndex:          "area-profiles",
-		BindAddr:                  ":10300",
-		DatasetIndex:              "datasets",
-		DimensionsFilename:        "data/dimensions.json",
-		ElasticSearchAPIURL:       "http://localhost:9200",
-		HierarchiesFilename:       "data/hierarchy.json",
-		MaxSearchResultsOffset:    1000,
-		PoscodeIndex:              "postcodes",
-		SignElasticsearchRequests: false,
-		TaxonomyFilename:          "data/taxonomy.json",
+		AWSRegion:                            "eu-west-1",
+		AreaProfileIndex:                     "area-profiles",
+		BindAddr:                             ":10300",
+		DatasetIndex:                         "datasets",
+		DimensionsFilename:                   "data/dimensions.json",
+		ElasticSearchAPIURL:                  "http://localhost:9200",
+		ElasticSearchAreaProfileTimeout:      5 * time.Second,
+		ElasticSearchCircuitBreakerCooldown:  30 * time.Second,
+		ElasticSearchCircuitBreakerThreshold: 5,
+		ElasticSearchMaxRetries:              2,
+		ElasticSearchMaxRetryBackoff:         time.Second,
+		ElasticSearchPostcodeTimeout:         2 * time.Second,
+		ElasticSearchRetryBackoff:            50 * time.Millisecond,
+		ElasticSearchSearchTimeout:           10 * time.Second,
+		HealthCheckCacheInterval:             10 * time.Second,
+		HealthCheckWarmingPeriod:             2 * time.Minute,
+		HierarchiesFilename:                  "data/hierarchy.json",
+		MaxSearchResultsOffset:               1000,
+		PartialResultsStatus:                 200,
+		PoscodeIndex:                         "postcodes",
+		PublicationIndex:                     "publications",
+		ReloadInterval:                       30 * time.Second,
+		SearchCacheSize:                      1000,
+		SearchCacheTTL:                       time.Minute,
+		SignElasticsearchRequests:            false,
+		TaxonomyFilename:                     "data/taxonomy.json",
 	}
 
 	return cfg, envconfig.Process("", cfg)
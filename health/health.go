@@ -0,0 +1,257 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ONSdigital/dp-census-alpha-search-api/models"
+	"github.com/ONSdigital/dp-census-alpha-search-api/reference"
+	"github.com/ONSdigital/log.go/log"
+)
+
+// A list of statuses for the api and each of its checks, a check is critical if the api cannot
+// serve requests without it and is a warning if requests are served with degraded results
+const (
+	StatusOK       = "OK"
+	StatusWarning  = "WARNING"
+	StatusWarming  = "WARMING"
+	StatusCritical = "CRITICAL"
+)
+
+// A list of elasticsearch cluster health statuses
+const (
+	clusterGreen  = "green"
+	clusterYellow = "yellow"
+)
+
+// severity orders check statuses from best to worst
+var severity = map[string]int{
+	StatusOK:       0,
+	StatusWarning:  1,
+	StatusCritical: 2,
+}
+
+// Elasticsearcher - An interface used to check the health of elasticsearch
+type Elasticsearcher interface {
+	GetClusterHealth(ctx context.Context) (*models.ClusterHealth, int, error)
+	CountDocuments(ctx context.Context, indexName string) (int, int, error)
+	CircuitBreakerState() string
+}
+
+// Result represents the health of the api and each of its checks
+type Result struct {
+	Status    string    `json:"status"`
+	StartTime time.Time `json:"start_time"`
+	Uptime    int64     `json:"uptime"`
+	Checks    []Check   `json:"checks"`
+}
+
+// Check represents the result of a single health check
+type Check struct {
+	Name           string     `json:"name"`
+	Status         string     `json:"status"`
+	Message        string     `json:"message"`
+	LastChecked    time.Time  `json:"last_checked"`
+	ClusterStatus  string     `json:"cluster_status,omitempty"`
+	CircuitBreaker string     `json:"circuit_breaker,omitempty"`
+	DocCount       *int       `json:"doc_count,omitempty"`
+	LoadedAt       *time.Time `json:"loaded_at,omitempty"`
+}
+
+// Checker checks the health of elasticsearch, the search indexes and the reference files,
+// caching the result so that frequent health requests do not each query elasticsearch
+type Checker struct {
+	elasticsearch Elasticsearcher
+	indexes       []string
+	reference     *reference.Store
+	cacheInterval time.Duration
+	warmingPeriod time.Duration
+	startTime     time.Time
+	now           func() time.Time
+
+	mutex     sync.Mutex
+	result    *Result
+	checkedAt time.Time
+	// healthy is set once every check has passed without a critical failure
+	healthy bool
+}
+
+// New creates a checker for elasticsearch, the reference documents and each of the indexes.
+// Critical failures are reported as warming rather than critical until every check has
+// passed or the warming period has elapsed since the checker was created.
+func New(elasticsearch Elasticsearcher, referenceDocs *reference.Store, cacheInterval, warmingPeriod time.Duration, indexes ...string) *Checker {
+	return &Checker{
+		elasticsearch: elasticsearch,
+		indexes:       indexes,
+		reference:     referenceDocs,
+		cacheInterval: cacheInterval,
+		warmingPeriod: warmingPeriod,
+		startTime:     time.Now().UTC(),
+		now:           func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Get returns the health of the api, the checks are only run again once the cached result
+// is older than the cache interval
+func (c *Checker) Get(ctx context.Context) *Result {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := c.now()
+
+	if c.result != nil && now.Sub(c.checkedAt) < c.cacheInterval {
+		result := *c.result
+		result.Uptime = now.Sub(c.startTime).Milliseconds()
+		return &result
+	}
+
+	checks := []Check{c.checkCluster(ctx, now)}
+	for _, index := range c.indexes {
+		checks = append(checks, c.checkIndex(ctx, index, now))
+	}
+	checks = append(checks, c.checkReferenceFiles(now))
+
+	status := worstStatus(checks)
+
+	if status != StatusCritical {
+		c.healthy = true
+	} else if !c.healthy && now.Sub(c.startTime) < c.warmingPeriod {
+		status = StatusWarming
+	}
+
+	c.result = &Result{
+		Status:    status,
+		StartTime: c.startTime,
+		Uptime:    now.Sub(c.startTime).Milliseconds(),
+		Checks:    checks,
+	}
+	c.checkedAt = now
+
+	if status != StatusOK {
+		log.Event(ctx, "health check is not ok", log.WARN, log.Data{"health": c.result})
+	}
+
+	result := *c.result
+	return &result
+}
+
+// checkCluster checks that elasticsearch can be reached and that every shard is allocated, a
+// yellow cluster still serves requests as only replica shards are unallocated. Elasticsearch
+// cannot be reached while the circuit breaker is open.
+func (c *Checker) checkCluster(ctx context.Context, now time.Time) Check {
+	check := Check{
+		Name:        "elasticsearch",
+		LastChecked: now,
+	}
+
+	clusterHealth, status, err := c.elasticsearch.GetClusterHealth(ctx)
+
+	// The cluster health call is the trial call that closes a circuit breaker whose cooldown has
+	// passed, so the state is read afterwards
+	check.CircuitBreaker = c.elasticsearch.CircuitBreakerState()
+
+	if err != nil {
+		check.Status = StatusCritical
+		check.Message = "unable to reach elasticsearch: " + err.Error()
+		if status != 0 {
+			check.Message += ", status " + strconv.Itoa(status)
+		}
+		return check
+	}
+
+	check.ClusterStatus = clusterHealth.Status
+	check.Message = "cluster health is " + clusterHealth.Status
+
+	switch clusterHealth.Status {
+	case clusterGreen:
+		check.Status = StatusOK
+	case clusterYellow:
+		check.Status = StatusWarning
+	default:
+		check.Status = StatusCritical
+	}
+
+	return check
+}
+
+// checkIndex checks that an index exists and contains documents, an empty index is only a
+// warning as searches still succeed
+func (c *Checker) checkIndex(ctx context.Context, index string, now time.Time) Check {
+	check := Check{
+		Name:        index + " index",
+		LastChecked: now,
+	}
+
+	count, status, err := c.elasticsearch.CountDocuments(ctx, index)
+	if err != nil {
+		check.Status = StatusCritical
+		if status == http.StatusNotFound {
+			check.Message = "index does not exist"
+		} else {
+			check.Message = "unable to count documents in index: " + err.Error()
+		}
+		return check
+	}
+
+	check.DocCount = &count
+
+	if count == 0 {
+		check.Status = StatusWarning
+		check.Message = "index contains no documents"
+		return check
+	}
+
+	check.Status = StatusOK
+	check.Message = "index contains " + strconv.Itoa(count) + " documents"
+
+	return check
+}
+
+// checkReferenceFiles reports when the reference files were loaded, a failed reload is a
+// warning as the previous version of the files is still in use
+func (c *Checker) checkReferenceFiles(now time.Time) Check {
+	loadedAt := c.reference.Get().LoadedAt
+
+	check := Check{
+		Name:        "reference files",
+		Status:      StatusOK,
+		Message:     "taxonomy, dimensions and hierarchies files are loaded",
+		LastChecked: now,
+		LoadedAt:    &loadedAt,
+	}
+
+	if failedAt, err := c.reference.LastFailure(); err != nil {
+		check.Status = StatusWarning
+		check.Message = "failed to reload reference files at " + failedAt.Format(time.RFC3339) + ", the previous version is still in use: " + err.Error()
+	}
+
+	return check
+}
+
+func worstStatus(checks []Check) string {
+	status := StatusOK
+	for _, check := range checks {
+		if severity[check.Status] > severity[status] {
+			status = check.Status
+		}
+	}
+
+	return status
+}
+
+// StatusCode returns the http status code for the health of the api. Warnings are returned
+// as 200 as requests are still served, and warming is returned as 429 so that it can be told
+// apart from a critical failure.
+func StatusCode(status string) int {
+	switch status {
+	case StatusWarming:
+		return http.StatusTooManyRequests
+	case StatusCritical:
+		return http.StatusInternalServerError
+	default:
+		return http.StatusOK
+	}
+}
@@ -0,0 +1,150 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	errs "github.com/ONSdigital/dp-census-alpha-search-api/apierrors"
+	"github.com/ONSdigital/dp-census-alpha-search-api/internal/elasticsearch"
+	"github.com/ONSdigital/dp-census-alpha-search-api/internal/elasticsearch/memory"
+	"github.com/ONSdigital/dp-census-alpha-search-api/models"
+	"github.com/ONSdigital/dp-census-alpha-search-api/reference"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const (
+	datasetIndex  = "datasets"
+	postcodeIndex = "postcodes"
+
+	cacheInterval = 10 * time.Second
+	warmingPeriod = time.Minute
+)
+
+// newChecker creates a checker for an in memory elasticsearch with a clock that is moved
+// forward by the returned function
+func newChecker() (*Checker, *memory.Elasticsearch, func(time.Duration)) {
+	es := memory.New()
+	So(es.AddDocuments(datasetIndex, models.SearchResult{Title: "Deaths registered by sex"}), ShouldBeNil)
+	So(es.AddDocuments(postcodeIndex, models.PostcodeDoc{Postcode: "cf101aa"}), ShouldBeNil)
+
+	referenceDocs, err := reference.New(reference.Files{
+		Dimensions:  "../data/dimensions.json",
+		Hierarchies: "../data/hierarchy.json",
+		Taxonomy:    "../data/taxonomy.json",
+	})
+	So(err, ShouldBeNil)
+
+	checker := New(es, referenceDocs, cacheInterval, warmingPeriod, datasetIndex, postcodeIndex)
+
+	now := checker.startTime
+	checker.now = func() time.Time { return now }
+
+	return checker, es, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestGet(t *testing.T) {
+	ctx := context.Background()
+
+	Convey("Given elasticsearch is green and every index contains documents", t, func() {
+		checker, es, advance := newChecker()
+
+		Convey("When the health is checked", func() {
+			result := checker.Get(ctx)
+
+			Convey("Then the api and every check is ok", func() {
+				So(result.Status, ShouldEqual, StatusOK)
+				So(StatusCode(result.Status), ShouldEqual, http.StatusOK)
+				So(result.Checks, ShouldHaveLength, 4)
+
+				for _, check := range result.Checks {
+					So(check.Status, ShouldEqual, StatusOK)
+				}
+
+				So(result.Checks[0].ClusterStatus, ShouldEqual, "green")
+				So(result.Checks[0].CircuitBreaker, ShouldEqual, elasticsearch.CircuitClosed)
+				So(*result.Checks[1].DocCount, ShouldEqual, 1)
+				So(result.Checks[3].LoadedAt, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When the cluster turns yellow", func() {
+			es.SetClusterStatus("yellow")
+			result := checker.Get(ctx)
+
+			Convey("Then the api is reported as a warning", func() {
+				So(result.Status, ShouldEqual, StatusWarning)
+				So(StatusCode(result.Status), ShouldEqual, http.StatusOK)
+			})
+		})
+
+		Convey("When the elasticsearch circuit breaker opens after the api has been healthy", func() {
+			checker.Get(ctx)
+			es.SetCircuitBreakerState(elasticsearch.CircuitOpen)
+			advance(cacheInterval)
+			result := checker.Get(ctx)
+
+			Convey("Then elasticsearch is reported as critical with the state of the circuit breaker", func() {
+				So(result.Status, ShouldEqual, StatusCritical)
+				So(result.Checks[0].Status, ShouldEqual, StatusCritical)
+				So(result.Checks[0].CircuitBreaker, ShouldEqual, elasticsearch.CircuitOpen)
+				So(result.Checks[0].Message, ShouldContainSubstring, elasticsearch.ErrCircuitOpen.Error())
+			})
+		})
+
+		Convey("When an index fails after the api has been healthy", func() {
+			checker.Get(ctx)
+			es.Fail(datasetIndex, http.StatusInternalServerError, errs.ErrIndexNotFound)
+
+			Convey("Then the cached result is returned until the cache interval has passed", func() {
+				advance(cacheInterval / 2)
+				So(checker.Get(ctx).Status, ShouldEqual, StatusOK)
+
+				advance(cacheInterval)
+				result := checker.Get(ctx)
+				So(result.Status, ShouldEqual, StatusCritical)
+				So(StatusCode(result.Status), ShouldEqual, http.StatusInternalServerError)
+			})
+		})
+	})
+
+	Convey("Given an index does not exist when the api starts", t, func() {
+		checker, es, advance := newChecker()
+		es.Fail(postcodeIndex, http.StatusNotFound, errs.ErrIndexNotFound)
+
+		Convey("When the health is checked during the warming period", func() {
+			result := checker.Get(ctx)
+
+			Convey("Then the api is warming", func() {
+				So(result.Status, ShouldEqual, StatusWarming)
+				So(StatusCode(result.Status), ShouldEqual, http.StatusTooManyRequests)
+				So(result.Checks[2].Status, ShouldEqual, StatusCritical)
+				So(result.Checks[2].Message, ShouldEqual, "index does not exist")
+			})
+		})
+
+		Convey("When the health is checked after the warming period", func() {
+			advance(warmingPeriod)
+			result := checker.Get(ctx)
+
+			Convey("Then the api is critical", func() {
+				So(result.Status, ShouldEqual, StatusCritical)
+			})
+		})
+	})
+
+	Convey("Given an index without any documents", t, func() {
+		checker, es, _ := newChecker()
+		es.CreateIndex("publications")
+		checker.indexes = append(checker.indexes, "publications")
+
+		Convey("When the health is checked then the index is reported as a warning", func() {
+			result := checker.Get(ctx)
+
+			So(result.Status, ShouldEqual, StatusWarning)
+			So(*result.Checks[3].DocCount, ShouldEqual, 0)
+			So(result.Checks[3].Status, ShouldEqual, StatusWarning)
+		})
+	})
+}
@@ -0,0 +1,76 @@
+package awsauth
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	dphttp "github.com/ONSdigital/dp-net/http"
+)
+
+// Client wraps a client so that every request is signed before it is sent, including each
+// retry made by the wrapped client
+type Client struct {
+	dphttp.Clienter
+	signer *Signer
+}
+
+// NewClient creates a client that signs requests with the signer before sending them with the
+// wrapped client
+func NewClient(clienter dphttp.Clienter, signer *Signer) *Client {
+	return &Client{
+		Clienter: clienter,
+		signer:   signer,
+	}
+}
+
+// Do signs the request and sends it with the wrapped client
+func (c *Client) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	if err := c.signer.Sign(req); err != nil {
+		return nil, err
+	}
+
+	return c.Clienter.Do(ctx, req)
+}
+
+// Get calls Do with a GET.
+func (c *Client) Get(ctx context.Context, url string) (*http.Response, error) {
+	return c.send(ctx, http.MethodGet, url, "", nil)
+}
+
+// Head calls Do with a HEAD.
+func (c *Client) Head(ctx context.Context, url string) (*http.Response, error) {
+	return c.send(ctx, http.MethodHead, url, "", nil)
+}
+
+// Post calls Do with a POST and the content type and body.
+func (c *Client) Post(ctx context.Context, url string, contentType string, body io.Reader) (*http.Response, error) {
+	return c.send(ctx, http.MethodPost, url, contentType, body)
+}
+
+// Put calls Do with a PUT and the content type and body.
+func (c *Client) Put(ctx context.Context, url string, contentType string, body io.Reader) (*http.Response, error) {
+	return c.send(ctx, http.MethodPut, url, contentType, body)
+}
+
+// PostForm calls Post with the form content type.
+func (c *Client) PostForm(ctx context.Context, uri string, data url.Values) (*http.Response, error) {
+	return c.Post(ctx, uri, "application/x-www-form-urlencoded", strings.NewReader(data.Encode()))
+}
+
+// send builds a request so that it is signed by Do, the helpers of the wrapped client would
+// send the request without signing it
+func (c *Client) send(ctx context.Context, method, url, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	return c.Do(ctx, req)
+}
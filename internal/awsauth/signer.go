@@ -0,0 +1,264 @@
+// Package awsauth signs requests to AWS services with signature version 4, so that the api can
+// query an elasticsearch domain hosted by the AWS Elasticsearch service whose access policy
+// requires signed requests.
+package awsauth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A list of values used to sign requests
+const (
+	algorithm      = "AWS4-HMAC-SHA256"
+	amzDateFormat  = "20060102T150405Z"
+	dateFormat     = "20060102"
+	requestType    = "aws4_request"
+	dateHeader     = "X-Amz-Date"
+	tokenHeader    = "X-Amz-Security-Token"
+	authHeader     = "Authorization"
+	emptyBodyHash  = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	defaultService = "es"
+)
+
+// ErrMissingCredentials is returned when a signer is created without an access key id or secret
+var ErrMissingCredentials = errors.New("missing aws credentials, both an access key id and secret access key are required")
+
+// ErrMissingRegion is returned when a signer is created without a region
+var ErrMissingRegion = errors.New("missing aws region")
+
+// Credentials represents the aws credentials used to sign requests, the session token is only
+// set for temporary credentials
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// Signer signs requests for an aws service in a region
+type Signer struct {
+	credentials Credentials
+	region      string
+	service     string
+	now         func() time.Time
+}
+
+// NewSigner creates a signer for requests to the AWS Elasticsearch service in the region
+func NewSigner(credentials Credentials, region string) (*Signer, error) {
+	if credentials.AccessKeyID == "" || credentials.SecretAccessKey == "" {
+		return nil, ErrMissingCredentials
+	}
+
+	if region == "" {
+		return nil, ErrMissingRegion
+	}
+
+	return &Signer{
+		credentials: credentials,
+		region:      region,
+		service:     defaultService,
+		now:         time.Now,
+	}, nil
+}
+
+// Sign adds the date, security token and authorization headers to the request. Only the host
+// and these headers are signed, so headers added after signing, such as request ids, do not
+// invalidate the signature.
+func (s *Signer) Sign(req *http.Request) error {
+	bodyHash, err := hashBody(req)
+	if err != nil {
+		return err
+	}
+
+	now := s.now().UTC()
+
+	req.Header.Del(authHeader)
+	req.Header.Set(dateHeader, now.Format(amzDateFormat))
+	if s.credentials.SessionToken != "" {
+		req.Header.Set(tokenHeader, s.credentials.SessionToken)
+	} else {
+		req.Header.Del(tokenHeader)
+	}
+
+	signedHeaders, canonicalHeaders := canonicalHeaders(req)
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI(req.URL),
+		canonicalQuery(req.URL),
+		canonicalHeaders,
+		signedHeaders,
+		bodyHash,
+	}, "\n")
+
+	scope := strings.Join([]string{now.Format(dateFormat), s.region, s.service, requestType}, "/")
+
+	stringToSign := strings.Join([]string{
+		algorithm,
+		now.Format(amzDateFormat),
+		scope,
+		hashHex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signature := hex.EncodeToString(hmacSHA256(s.signingKey(now), stringToSign))
+
+	req.Header.Set(authHeader, algorithm+" Credential="+s.credentials.AccessKeyID+"/"+scope+", SignedHeaders="+signedHeaders+", Signature="+signature)
+
+	return nil
+}
+
+// signingKey derives the key for the date, region and service from the secret access key
+func (s *Signer) signingKey(now time.Time) []byte {
+	key := hmacSHA256([]byte("AWS4"+s.credentials.SecretAccessKey), now.Format(dateFormat))
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, s.service)
+
+	return hmacSHA256(key, requestType)
+}
+
+// hashBody returns the hex encoded sha256 hash of the request body, the body is replaced so
+// that it can still be sent
+func hashBody(req *http.Request) (string, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return emptyBodyHash, nil
+	}
+
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return "", err
+		}
+		defer body.Close()
+
+		b, err := ioutil.ReadAll(body)
+		if err != nil {
+			return "", err
+		}
+
+		return hashHex(b), nil
+	}
+
+	b, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return "", err
+	}
+
+	req.Body = ioutil.NopCloser(bytes.NewReader(b))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(b)), nil
+	}
+
+	return hashHex(b), nil
+}
+
+// canonicalHeaders returns the names of the signed headers and the canonical form of the host
+// and amz headers, sorted by lower case name
+func canonicalHeaders(req *http.Request) (string, string) {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.Join(strings.Fields(strings.Join(values, ",")), " ")
+		}
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonical strings.Builder
+	for _, name := range names {
+		canonical.WriteString(name + ":" + headers[name] + "\n")
+	}
+
+	return strings.Join(names, ";"), canonical.String()
+}
+
+// canonicalURI returns the escaped path, every segment of the path is escaped again as aws
+// services other than s3 expect paths to be double encoded
+func canonicalURI(u *url.URL) string {
+	path := u.EscapedPath()
+	if path == "" {
+		return "/"
+	}
+
+	return escape(path, true)
+}
+
+// canonicalQuery returns the query parameters escaped and sorted by name and then value
+func canonicalQuery(u *url.URL) string {
+	query := u.Query()
+
+	params := make([][2]string, 0, len(query))
+	for name, values := range query {
+		for _, value := range values {
+			params = append(params, [2]string{escape(name, false), escape(value, false)})
+		}
+	}
+
+	sort.Slice(params, func(i, j int) bool {
+		if params[i][0] != params[j][0] {
+			return params[i][0] < params[j][0]
+		}
+		return params[i][1] < params[j][1]
+	})
+
+	encoded := make([]string, len(params))
+	for i, param := range params {
+		encoded[i] = param[0] + "=" + param[1]
+	}
+
+	return strings.Join(encoded, "&")
+}
+
+// escape percent encodes every byte other than unreserved characters, slashes are kept if the
+// value is a path
+func escape(value string, path bool) string {
+	var escaped strings.Builder
+
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if unreserved(c) || (path && c == '/') {
+			escaped.WriteByte(c)
+			continue
+		}
+
+		escaped.WriteString("%" + strings.ToUpper(hex.EncodeToString([]byte{c})))
+	}
+
+	return escaped.String()
+}
+
+func unreserved(c byte) bool {
+	return 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~'
+}
+
+func hashHex(b []byte) string {
+	hash := sha256.Sum256(b)
+	return hex.EncodeToString(hash[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+
+	return mac.Sum(nil)
+}
@@ -0,0 +1,169 @@
+package awsauth
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	dphttp "github.com/ONSdigital/dp-net/http"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// Credentials and time used by the aws signature version 4 test suite
+var (
+	testCredentials = Credentials{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}
+	testTime = time.Date(2015, time.August, 30, 12, 36, 0, 0, time.UTC)
+)
+
+// newTestSigner creates a signer with a fixed clock
+func newTestSigner(credentials Credentials, region, service string) *Signer {
+	signer, err := NewSigner(credentials, region)
+	So(err, ShouldBeNil)
+
+	signer.service = service
+	signer.now = func() time.Time { return testTime }
+
+	return signer
+}
+
+func TestNewSigner(t *testing.T) {
+	Convey("When a signer is created without credentials then an error is returned", t, func() {
+		_, err := NewSigner(Credentials{AccessKeyID: "AKIDEXAMPLE"}, "eu-west-1")
+		So(err, ShouldEqual, ErrMissingCredentials)
+	})
+
+	Convey("When a signer is created without a region then an error is returned", t, func() {
+		_, err := NewSigner(testCredentials, "")
+		So(err, ShouldEqual, ErrMissingRegion)
+	})
+}
+
+func TestSign(t *testing.T) {
+	Convey("Given the get-vanilla request from the aws signature version 4 test suite", t, func() {
+		signer := newTestSigner(testCredentials, "us-east-1", "service")
+
+		req, err := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+		So(err, ShouldBeNil)
+
+		Convey("When the request is signed", func() {
+			So(signer.Sign(req), ShouldBeNil)
+
+			Convey("Then the signature matches the test suite", func() {
+				So(req.Header.Get(dateHeader), ShouldEqual, "20150830T123600Z")
+				So(req.Header.Get(authHeader), ShouldEqual, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+					"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31")
+			})
+		})
+	})
+
+	Convey("Given temporary credentials", t, func() {
+		credentials := testCredentials
+		credentials.SessionToken = "session-token"
+		signer := newTestSigner(credentials, "eu-west-1", defaultService)
+
+		req, err := http.NewRequest(http.MethodGet, "https://search.eu-west-1.es.amazonaws.com/datasets/_search", nil)
+		So(err, ShouldBeNil)
+
+		Convey("When the request is signed then the session token is sent and signed", func() {
+			So(signer.Sign(req), ShouldBeNil)
+
+			So(req.Header.Get(tokenHeader), ShouldEqual, "session-token")
+			So(req.Header.Get(authHeader), ShouldContainSubstring, "SignedHeaders=host;x-amz-date;x-amz-security-token,")
+		})
+	})
+
+	Convey("Given a path and query that need escaping", t, func() {
+		req, err := http.NewRequest(http.MethodPost, "https://example.com/datasets,area-profiles/_search?b=2&a=x%20y&a=1", nil)
+		So(err, ShouldBeNil)
+
+		Convey("When the canonical forms are created then the path is double encoded and the query sorted", func() {
+			So(canonicalURI(req.URL), ShouldEqual, "/datasets%2Carea-profiles/_search")
+			So(canonicalQuery(req.URL), ShouldEqual, "a=1&a=x%20y&b=2")
+		})
+	})
+}
+
+func TestClient(t *testing.T) {
+	Convey("Given an elasticsearch stub that verifies request signatures", t, func() {
+		credentials := testCredentials
+		credentials.SessionToken = "session-token"
+
+		var (
+			received  *http.Request
+			signature string
+			body      string
+		)
+
+		// the stub signs a copy of the request it received with the same credentials, the
+		// signature only matches if the host, path, query, amz headers and body were unchanged
+		verifier := newTestSigner(credentials, "eu-west-1", defaultService)
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r
+			signature = r.Header.Get(authHeader)
+
+			b, _ := ioutil.ReadAll(r.Body)
+			body = string(b)
+
+			copied, _ := http.NewRequest(r.Method, "http://"+r.Host+r.URL.RequestURI(), strings.NewReader(body))
+			for name, values := range r.Header {
+				copied.Header[name] = values
+			}
+
+			if verifier.Sign(copied) != nil || copied.Header.Get(authHeader) != signature {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		client := NewClient(dphttp.NewClient(), newTestSigner(credentials, "eu-west-1", defaultService))
+
+		Convey("When a search with a body is sent", func() {
+			req, err := http.NewRequest(http.MethodGet, server.URL+"/datasets,area-profiles/_search?size=10", strings.NewReader(`{"query":{"match_all":{}}}`))
+			So(err, ShouldBeNil)
+
+			resp, err := client.Do(context.Background(), req)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+
+			Convey("Then the request is signed and the signature is verified by the stub", func() {
+				So(resp.StatusCode, ShouldEqual, http.StatusOK)
+				So(signature, ShouldStartWith, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/eu-west-1/es/aws4_request, ")
+				So(received.Header.Get(dateHeader), ShouldEqual, "20150830T123600Z")
+				So(received.Header.Get(tokenHeader), ShouldEqual, "session-token")
+				So(body, ShouldEqual, `{"query":{"match_all":{}}}`)
+			})
+		})
+
+		Convey("When a request is sent with a helper of the client", func() {
+			resp, err := client.Post(context.Background(), server.URL+"/_bulk", "application/x-ndjson", strings.NewReader("{}\n"))
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+
+			Convey("Then the request is signed and the signature is verified by the stub", func() {
+				So(resp.StatusCode, ShouldEqual, http.StatusOK)
+				So(body, ShouldEqual, "{}\n")
+			})
+		})
+
+		Convey("When the request is sent without signing it", func() {
+			resp, err := dphttp.NewClient().Get(context.Background(), server.URL+"/_cluster/health")
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+
+			Convey("Then the stub rejects the request", func() {
+				So(resp.StatusCode, ShouldEqual, http.StatusForbidden)
+			})
+		})
+	})
+}
@@ -0,0 +1,290 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	errs "github.com/ONSdigital/dp-census-alpha-search-api/apierrors"
+	"github.com/ONSdigital/dp-census-alpha-search-api/models"
+	"github.com/ONSdigital/log.go/log"
+)
+
+// ErrDocumentCountMismatch is returned instead of publishing an index that does not contain
+// the expected number of documents
+var ErrDocumentCountMismatch = errors.New("index does not contain the expected number of documents")
+
+// versionFormat is the format of the timestamp suffixed to the alias to name each generation
+// of an index, e.g. datasets-20261017093000
+const versionFormat = "20060102150405"
+
+// VersionedIndex returns the name of a new generation of the index behind the alias
+func VersionedIndex(alias string, t time.Time) string {
+	return alias + "-" + t.UTC().Format(versionFormat)
+}
+
+// versionedIndexPattern matches every generation of the index behind the alias, but not
+// other indices that share the prefix such as the temporary index of the reindex script
+func versionedIndexPattern(alias string) *regexp.Regexp {
+	return regexp.MustCompile("^" + regexp.QuoteMeta(alias) + "-[0-9]{" + strconv.Itoa(len(versionFormat)) + "}$")
+}
+
+// GetAliasedIndices retrieves the indices the alias points at, a 404 status is returned if the
+// alias does not exist
+func (api *API) GetAliasedIndices(ctx context.Context, alias string) ([]string, int, error) {
+	path := api.url + "/_alias/" + alias
+
+	responseBody, status, err := api.CallElastic(ctx, path, "GET", nil)
+	if err != nil {
+		return nil, status, err
+	}
+
+	response := map[string]json.RawMessage{}
+
+	if err = json.Unmarshal(responseBody, &response); err != nil {
+		log.Event(ctx, "unable to unmarshal json body", log.ERROR, log.Error(err))
+		return nil, status, errs.ErrUnmarshallingJSON
+	}
+
+	indices := make([]string, 0, len(response))
+	for index := range response {
+		indices = append(indices, index)
+	}
+	sort.Strings(indices)
+
+	return indices, status, nil
+}
+
+// ListIndices retrieves the names of the indices that match the pattern, e.g. datasets-*
+func (api *API) ListIndices(ctx context.Context, pattern string) ([]string, int, error) {
+	path := api.url + "/_cat/indices/" + pattern + "?format=json&h=index"
+
+	responseBody, status, err := api.CallElastic(ctx, path, "GET", nil)
+	if err != nil {
+		return nil, status, err
+	}
+
+	var response []models.IndexSummary
+
+	if err = json.Unmarshal(responseBody, &response); err != nil {
+		log.Event(ctx, "unable to unmarshal json body", log.ERROR, log.Error(err))
+		return nil, status, errs.ErrUnmarshallingJSON
+	}
+
+	indices := make([]string, 0, len(response))
+	for _, index := range response {
+		indices = append(indices, index.Index)
+	}
+	sort.Strings(indices)
+
+	return indices, status, nil
+}
+
+// RefreshIndex makes every document added to the index searchable, so that documents can be
+// counted as soon as they have been added
+func (api *API) RefreshIndex(ctx context.Context, indexName string) (int, error) {
+	path := api.url + "/" + indexName + "/_refresh"
+
+	_, status, err := api.CallElastic(ctx, path, "POST", nil)
+	if err != nil {
+		return status, err
+	}
+
+	return status, nil
+}
+
+// UpdateAliases applies the alias changes in a single request, so that searches on an alias
+// never see it pointing at no index or at both the old and new index
+func (api *API) UpdateAliases(ctx context.Context, actions []models.AliasAction) (int, error) {
+	path := api.url + "/_aliases"
+
+	bytes, err := json.Marshal(models.AliasRequest{Actions: actions})
+	if err != nil {
+		return 0, err
+	}
+
+	_, status, err := api.CallElastic(ctx, path, "POST", bytes)
+	if err != nil {
+		return status, err
+	}
+
+	return status, nil
+}
+
+// SwapAlias points the alias at the index and away from every index it pointed at before. An
+// index with the same name as the alias, left over from before indices were versioned, is
+// deleted in the same request as an alias cannot share its name with an index.
+func (api *API) SwapAlias(ctx context.Context, alias, indexName string) ([]string, int, error) {
+	logData := log.Data{"alias": alias, "index": indexName}
+
+	var actions []models.AliasAction
+
+	previous, status, err := api.GetAliasedIndices(ctx, alias)
+	switch {
+	case err == nil:
+		for _, index := range previous {
+			actions = append(actions, models.AliasAction{Remove: &models.AliasIndex{Index: index, Alias: alias}})
+		}
+	case status == http.StatusNotFound:
+		// an index named after the alias is found by counting its documents
+		if _, status, err = api.CountDocuments(ctx, alias); err == nil {
+			log.Event(ctx, "replacing unversioned index with alias", log.WARN, logData)
+			actions = append(actions, models.AliasAction{RemoveIndex: &models.AliasIndex{Index: alias}})
+		} else if status != http.StatusNotFound {
+			log.Event(ctx, "failed to check for unversioned index", log.ERROR, log.Error(err), logData)
+			return nil, status, err
+		}
+	default:
+		log.Event(ctx, "failed to get indices of alias", log.ERROR, log.Error(err), logData)
+		return nil, status, err
+	}
+
+	actions = append(actions, models.AliasAction{Add: &models.AliasIndex{Index: indexName, Alias: alias}})
+
+	if status, err = api.UpdateAliases(ctx, actions); err != nil {
+		log.Event(ctx, "failed to swap alias", log.ERROR, log.Error(err), logData)
+		return nil, status, err
+	}
+
+	return previous, status, nil
+}
+
+// PublishIndex swaps the alias to a new generation of the index once it contains at least the
+// expected number of documents, and at least one document, then deletes the generations older
+// than the number of previous generations retained for rollback
+func (api *API) PublishIndex(ctx context.Context, alias, indexName string, expectedDocuments, generations int) error {
+	logData := log.Data{"alias": alias, "index": indexName, "expected_documents": expectedDocuments}
+
+	if status, err := api.RefreshIndex(ctx, indexName); err != nil {
+		log.Event(ctx, "failed to refresh index", log.ERROR, log.Error(err), log.Data{"status": status, "index": indexName})
+		return err
+	}
+
+	count, status, err := api.CountDocuments(ctx, indexName)
+	if err != nil {
+		log.Event(ctx, "failed to count documents in index", log.ERROR, log.Error(err), log.Data{"status": status, "index": indexName})
+		return err
+	}
+
+	logData["documents"] = count
+
+	if count == 0 || count < expectedDocuments {
+		log.Event(ctx, "not publishing index as it is missing documents", log.ERROR, log.Error(ErrDocumentCountMismatch), logData)
+		return ErrDocumentCountMismatch
+	}
+
+	previous, status, err := api.SwapAlias(ctx, alias, indexName)
+	if err != nil {
+		log.Event(ctx, "failed to publish index", log.ERROR, log.Error(err), log.Data{"status": status, "alias": alias, "index": indexName})
+		return err
+	}
+
+	logData["previous_indices"] = previous
+	log.Event(ctx, "published index", log.INFO, logData)
+
+	return api.DeleteGenerations(ctx, alias, indexName, generations)
+}
+
+// DeleteGenerations deletes the generations of the index behind the alias that are older than
+// the number of previous generations to retain, the published index is never deleted
+func (api *API) DeleteGenerations(ctx context.Context, alias, publishedIndex string, generations int) error {
+	indices, status, err := api.ListIndices(ctx, alias+"-*")
+	if err != nil {
+		log.Event(ctx, "failed to list generations of index", log.ERROR, log.Error(err), log.Data{"status": status, "alias": alias})
+		return err
+	}
+
+	for _, index := range expiredGenerations(alias, publishedIndex, indices, generations) {
+		if status, err := api.DeleteSearchIndex(ctx, index); err != nil {
+			log.Event(ctx, "failed to delete previous generation of index", log.ERROR, log.Error(err), log.Data{"status": status, "index": index})
+			return err
+		}
+
+		log.Event(ctx, "deleted previous generation of index", log.INFO, log.Data{"alias": alias, "index": index})
+	}
+
+	return nil
+}
+
+// expiredGenerations returns the generations older than the published index beyond the number
+// to retain, generations newer than the published index are being built so are kept until the
+// next generation is built
+func expiredGenerations(alias, publishedIndex string, indices []string, generations int) []string {
+	pattern := versionedIndexPattern(alias)
+
+	var older []string
+	for _, index := range indices {
+		if pattern.MatchString(index) && index < publishedIndex {
+			older = append(older, index)
+		}
+	}
+
+	// names sort in the order the generations were created
+	sort.Sort(sort.Reverse(sort.StringSlice(older)))
+
+	if generations < 0 {
+		generations = 0
+	}
+
+	if len(older) <= generations {
+		return nil
+	}
+
+	return older[generations:]
+}
+
+// DeleteUnpublishedGenerations deletes the generations of the index behind the alias that are
+// newer than the published index, which are left behind by runs that failed before publishing.
+// Loaders call it before building a new generation, so failed runs do not hold on to disk and
+// are never retained for rollback, which means two loaders must not build the same index at once.
+func (api *API) DeleteUnpublishedGenerations(ctx context.Context, alias string) error {
+	published, status, err := api.GetAliasedIndices(ctx, alias)
+	if err != nil && status != http.StatusNotFound {
+		log.Event(ctx, "failed to get indices of alias", log.ERROR, log.Error(err), log.Data{"status": status, "alias": alias})
+		return err
+	}
+
+	indices, status, err := api.ListIndices(ctx, alias+"-*")
+	if err != nil {
+		log.Event(ctx, "failed to list generations of index", log.ERROR, log.Error(err), log.Data{"status": status, "alias": alias})
+		return err
+	}
+
+	for _, index := range unpublishedGenerations(alias, published, indices) {
+		if status, err := api.DeleteSearchIndex(ctx, index); err != nil {
+			log.Event(ctx, "failed to delete unpublished generation of index", log.ERROR, log.Error(err), log.Data{"status": status, "index": index})
+			return err
+		}
+
+		log.Event(ctx, "deleted unpublished generation of index", log.INFO, log.Data{"alias": alias, "index": index})
+	}
+
+	return nil
+}
+
+// unpublishedGenerations returns the generations newer than the newest generation the alias
+// points at, every generation is unpublished if the alias does not point at a generation
+func unpublishedGenerations(alias string, published, indices []string) []string {
+	pattern := versionedIndexPattern(alias)
+
+	newest := ""
+	for _, index := range published {
+		if pattern.MatchString(index) && index > newest {
+			newest = index
+		}
+	}
+
+	var unpublished []string
+	for _, index := range indices {
+		if pattern.MatchString(index) && index > newest {
+			unpublished = append(unpublished, index)
+		}
+	}
+
+	return unpublished
+}
@@ -0,0 +1,225 @@
+package elasticsearch
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestVersionedIndex(t *testing.T) {
+	Convey("Given the time a loader started", t, func() {
+		started := time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC)
+
+		Convey("When the name of the new index is created then the time is suffixed to the alias", func() {
+			So(VersionedIndex("datasets", started), ShouldEqual, "datasets-20261017093000")
+		})
+	})
+}
+
+func TestExpiredGenerations(t *testing.T) {
+	Convey("Given the generations of an index and other indices sharing its prefix", t, func() {
+		indices := []string{
+			"datasets-20261001000000",
+			"datasets-20261008000000",
+			"datasets-20261015000000",
+			"datasets-20261017000000",
+			"datasets-20261018000000",
+			"datasets-reindex",
+			"datasets-20261001000000-old",
+		}
+
+		Convey("When two previous generations are retained", func() {
+			expired := expiredGenerations("datasets", "datasets-20261017000000", indices, 2)
+
+			Convey("Then only older generations beyond the two most recent are expired", func() {
+				So(expired, ShouldResemble, []string{"datasets-20261001000000"})
+			})
+		})
+
+		Convey("When no previous generations are retained", func() {
+			expired := expiredGenerations("datasets", "datasets-20261017000000", indices, 0)
+
+			Convey("Then every older generation is expired but newer generations being built are kept", func() {
+				So(expired, ShouldResemble, []string{"datasets-20261015000000", "datasets-20261008000000", "datasets-20261001000000"})
+			})
+		})
+
+		Convey("When more generations are retained than exist then none are expired", func() {
+			So(expiredGenerations("datasets", "datasets-20261017000000", indices, 10), ShouldBeEmpty)
+		})
+	})
+}
+
+// aliasServer is an elasticsearch server with a single alias, or an unversioned index named
+// after the alias if no indices are aliased, that records the requests that change indices
+type aliasServer struct {
+	mutex       sync.Mutex
+	alias       string
+	aliased     []string
+	unversioned bool
+	indices     []string
+	count       int
+	requests    []string
+}
+
+func (s *aliasServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	body, _ := ioutil.ReadAll(r.Body)
+
+	switch {
+	case r.URL.Path == "/":
+		w.Write([]byte(`{"version":{"number":"7.10.2"}}`))
+	case strings.HasSuffix(r.URL.Path, "/_refresh"):
+		w.Write([]byte(`{}`))
+	case strings.HasSuffix(r.URL.Path, "/_count"):
+		if r.URL.Path == "/"+s.alias+"/_count" && !s.unversioned {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"count":` + strconv.Itoa(s.count) + `}`))
+	case r.URL.Path == "/_alias/"+s.alias:
+		if len(s.aliased) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"alias [` + s.alias + `] missing","status":404}`))
+			return
+		}
+		var aliased []string
+		for _, index := range s.aliased {
+			aliased = append(aliased, `"`+index+`":{"aliases":{"`+s.alias+`":{}}}`)
+		}
+		w.Write([]byte(`{` + strings.Join(aliased, ",") + `}`))
+	case strings.HasPrefix(r.URL.Path, "/_cat/indices/"):
+		var indices []string
+		for _, index := range s.indices {
+			indices = append(indices, `{"index":"`+index+`"}`)
+		}
+		w.Write([]byte(`[` + strings.Join(indices, ",") + `]`))
+	default:
+		s.requests = append(s.requests, r.Method+" "+r.URL.Path+" "+string(body))
+		w.Write([]byte(`{}`))
+	}
+}
+
+func (s *aliasServer) changes() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]string(nil), s.requests...)
+}
+
+func TestPublishIndex(t *testing.T) {
+	ctx := context.Background()
+
+	Convey("Given an alias pointing at the current generation of an index", t, func() {
+		es := &aliasServer{
+			alias:   "datasets",
+			aliased: []string{"datasets-20261010000000"},
+			indices: []string{"datasets-20261001000000", "datasets-20261005000000", "datasets-20261010000000", "datasets-20261017000000"},
+			count:   3,
+		}
+		server := httptest.NewServer(es)
+		defer server.Close()
+
+		api := newTestAPI(server.URL)
+
+		Convey("When a new generation containing every document is published", func() {
+			err := api.PublishIndex(ctx, "datasets", "datasets-20261017000000", 3, 1)
+
+			Convey("Then the alias is swapped in one request and generations beyond the one retained are deleted", func() {
+				So(err, ShouldBeNil)
+				So(es.changes(), ShouldResemble, []string{
+					`POST /_aliases {"actions":[{"remove":{"index":"datasets-20261010000000","alias":"datasets"}},{"add":{"index":"datasets-20261017000000","alias":"datasets"}}]}`,
+					"DELETE /datasets-20261005000000 ",
+					"DELETE /datasets-20261001000000 ",
+				})
+			})
+		})
+
+		Convey("When a new generation missing documents is published", func() {
+			err := api.PublishIndex(ctx, "datasets", "datasets-20261017000000", 4, 1)
+
+			Convey("Then the alias is not swapped and no generations are deleted", func() {
+				So(err, ShouldEqual, ErrDocumentCountMismatch)
+				So(es.changes(), ShouldBeEmpty)
+			})
+		})
+	})
+
+	Convey("Given an unversioned index named after the alias", t, func() {
+		es := &aliasServer{
+			alias:       "postcodes",
+			unversioned: true,
+			indices:     []string{"postcodes-20261017000000"},
+			count:       10,
+		}
+		server := httptest.NewServer(es)
+		defer server.Close()
+
+		api := newTestAPI(server.URL)
+
+		Convey("When the first generation is published", func() {
+			err := api.PublishIndex(ctx, "postcodes", "postcodes-20261017000000", 10, 2)
+
+			Convey("Then the unversioned index is replaced by the alias in one request", func() {
+				So(err, ShouldBeNil)
+				So(es.changes(), ShouldResemble, []string{
+					`POST /_aliases {"actions":[{"remove_index":{"index":"postcodes"}},{"add":{"index":"postcodes-20261017000000","alias":"postcodes"}}]}`,
+				})
+			})
+		})
+	})
+}
+
+func TestUnpublishedGenerations(t *testing.T) {
+	Convey("Given the generations of an index and other indices sharing its prefix", t, func() {
+		indices := []string{
+			"datasets-20261010000000",
+			"datasets-20261015000000",
+			"datasets-20261017000000",
+			"datasets-reindex",
+		}
+
+		Convey("When the alias points at a generation then only newer generations are unpublished", func() {
+			unpublished := unpublishedGenerations("datasets", []string{"datasets-20261010000000"}, indices)
+			So(unpublished, ShouldResemble, []string{"datasets-20261015000000", "datasets-20261017000000"})
+		})
+
+		Convey("When the alias does not point at a generation then every generation is unpublished", func() {
+			unpublished := unpublishedGenerations("datasets", nil, indices)
+			So(unpublished, ShouldResemble, []string{"datasets-20261010000000", "datasets-20261015000000", "datasets-20261017000000"})
+		})
+	})
+}
+
+func TestDeleteUnpublishedGenerations(t *testing.T) {
+	ctx := context.Background()
+
+	Convey("Given generations left behind by runs that failed before publishing", t, func() {
+		es := &aliasServer{
+			alias:   "area-profiles",
+			aliased: []string{"area-profiles-20261010000000"},
+			indices: []string{"area-profiles-20261001000000", "area-profiles-20261010000000", "area-profiles-20261015000000"},
+		}
+		server := httptest.NewServer(es)
+		defer server.Close()
+
+		Convey("When the unpublished generations are deleted", func() {
+			err := newTestAPI(server.URL).DeleteUnpublishedGenerations(ctx, "area-profiles")
+
+			Convey("Then only the generations newer than the published index are deleted", func() {
+				So(err, ShouldBeNil)
+				So(es.changes(), ShouldResemble, []string{"DELETE /area-profiles-20261015000000 "})
+			})
+		})
+	})
+}
@@ -1,4 +1,4 @@
 //go:generate go get github.com/jteeuwen/go-bindata/go-bindata
-//go:generate go-bindata -pkg elasticsearch ./postcode-mappings.json ./geography-mappings.json ./dataset-mappings.json
+//go:generate go-bindata -pkg elasticsearch ./postcode-mappings.json ./geography-mappings.json ./dataset-mappings.json ./publication-mappings.json
 
 package elasticsearch
@@ -0,0 +1,147 @@
+package elasticsearch
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ONSdigital/dp-census-alpha-search-api/metrics"
+)
+
+// ErrCircuitOpen is returned instead of calling elasticsearch while the circuit breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker is open, elasticsearch is failing")
+
+// A list of circuit breaker states
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half-open"
+)
+
+// CircuitBreaker stops calls to elasticsearch once a number of consecutive calls have failed,
+// so that requests fail fast while the cluster is down rather than waiting on each call. After
+// the cooldown a single trial call is let through, closing the circuit if it succeeds and
+// opening it again if it fails. A nil circuit breaker lets every call through.
+type CircuitBreaker struct {
+	mutex     sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	state     string
+	failures  int
+	openedAt  time.Time
+	// trial is set while the call let through a half open circuit is in progress
+	trial bool
+	now   func() time.Time
+}
+
+// NewCircuitBreaker creates a circuit breaker that opens after threshold consecutive failures
+// for the cooldown, nil is returned if the threshold is not positive as the breaker is disabled
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold <= 0 {
+		return nil
+	}
+
+	metrics.SetCircuitState(CircuitClosed)
+
+	return &CircuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		state:     CircuitClosed,
+		now:       time.Now,
+	}
+}
+
+// Allow checks whether a call can be made, every call that is allowed must be followed by
+// one of Success, Failure or Cancelled
+func (b *CircuitBreaker) Allow() bool {
+	if b == nil {
+		return true
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.setState(CircuitHalfOpen)
+		b.trial = true
+		return true
+	case CircuitHalfOpen:
+		if b.trial {
+			return false
+		}
+		b.trial = true
+		return true
+	default:
+		return true
+	}
+}
+
+// Success records a call that reached elasticsearch, closing the circuit
+func (b *CircuitBreaker) Success() {
+	if b == nil {
+		return
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.failures = 0
+	b.trial = false
+	b.setState(CircuitClosed)
+}
+
+// Failure records a call that failed because elasticsearch is unavailable, opening the circuit
+// once the threshold is reached or if the trial call of a half open circuit failed
+func (b *CircuitBreaker) Failure() {
+	if b == nil {
+		return
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.failures++
+	b.trial = false
+
+	if b.state == CircuitHalfOpen || b.failures >= b.threshold {
+		b.openedAt = b.now()
+		b.setState(CircuitOpen)
+	}
+}
+
+// Cancelled records a call that was cancelled by the client before elasticsearch responded,
+// leaving the circuit as it was but letting another trial call through a half open circuit
+func (b *CircuitBreaker) Cancelled() {
+	if b == nil {
+		return
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.trial = false
+}
+
+// State returns the current state of the circuit, an open circuit whose cooldown has passed is
+// still reported as open until the next call is allowed
+func (b *CircuitBreaker) State() string {
+	if b == nil {
+		return CircuitClosed
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.state
+}
+
+func (b *CircuitBreaker) setState(state string) {
+	if b.state != state {
+		b.state = state
+		metrics.SetCircuitState(state)
+	}
+}
@@ -0,0 +1,92 @@
+package elasticsearch
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// newBreaker creates a circuit breaker with a clock that is moved forward by the returned function
+func newBreaker(threshold int, cooldown time.Duration) (*CircuitBreaker, func(time.Duration)) {
+	b := NewCircuitBreaker(threshold, cooldown)
+
+	now := time.Date(2020, time.June, 1, 12, 0, 0, 0, time.UTC)
+	b.now = func() time.Time { return now }
+
+	return b, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	Convey("Given a circuit breaker that opens after 3 failures", t, func() {
+		b, advance := newBreaker(3, time.Minute)
+
+		Convey("When fewer failures than the threshold are followed by a success", func() {
+			b.Failure()
+			b.Failure()
+			b.Success()
+			b.Failure()
+			b.Failure()
+
+			Convey("Then the circuit stays closed as the failures were not consecutive", func() {
+				So(b.State(), ShouldEqual, CircuitClosed)
+				So(b.Allow(), ShouldBeTrue)
+			})
+		})
+
+		Convey("When the threshold of consecutive failures is reached", func() {
+			b.Failure()
+			b.Failure()
+			b.Failure()
+
+			Convey("Then the circuit is open and calls are not allowed until the cooldown has passed", func() {
+				So(b.State(), ShouldEqual, CircuitOpen)
+				So(b.Allow(), ShouldBeFalse)
+
+				advance(59 * time.Second)
+				So(b.Allow(), ShouldBeFalse)
+			})
+
+			Convey("Then a single trial call is allowed once the cooldown has passed", func() {
+				advance(time.Minute)
+
+				So(b.Allow(), ShouldBeTrue)
+				So(b.State(), ShouldEqual, CircuitHalfOpen)
+				So(b.Allow(), ShouldBeFalse)
+
+				Convey("And the circuit closes if the trial call succeeds", func() {
+					b.Success()
+
+					So(b.State(), ShouldEqual, CircuitClosed)
+					So(b.Allow(), ShouldBeTrue)
+				})
+
+				Convey("And the circuit opens again if the trial call fails", func() {
+					b.Failure()
+
+					So(b.State(), ShouldEqual, CircuitOpen)
+					So(b.Allow(), ShouldBeFalse)
+				})
+
+				Convey("And the circuit stays half open with another trial call allowed if the trial call is cancelled", func() {
+					b.Cancelled()
+
+					So(b.State(), ShouldEqual, CircuitHalfOpen)
+					So(b.Allow(), ShouldBeTrue)
+				})
+			})
+		})
+	})
+
+	Convey("When a circuit breaker is created without a threshold", t, func() {
+		b := NewCircuitBreaker(0, time.Minute)
+
+		Convey("Then it is disabled and every call is allowed", func() {
+			So(b, ShouldBeNil)
+
+			b.Failure()
+			So(b.Allow(), ShouldBeTrue)
+			So(b.State(), ShouldEqual, CircuitClosed)
+		})
+	})
+}
@@ -0,0 +1,252 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ONSdigital/dp-census-alpha-search-api/models"
+)
+
+// ErrBulkItemsMismatch is returned when the bulk response does not have an item for every
+// document in the request, so failures cannot be matched to their documents
+var ErrBulkItemsMismatch = errors.New("bulk response does not have an item for every document")
+
+// bulkBackoff is the wait between resending documents rejected because elasticsearch was
+// overloaded, bulk requests are not retried by the api retries as they are not idempotent
+var bulkBackoff = Retries{
+	InitialBackoff: time.Second,
+	MaxBackoff:     30 * time.Second,
+}
+
+// BulkResult represents the outcome of a bulk request, documents that failed are listed
+// rather than failing the request as elasticsearch indexes the rest of the documents
+type BulkResult struct {
+	Indexed int
+	Failed  []BulkFailure
+}
+
+// BulkFailure represents a document that elasticsearch failed to index, the position is the
+// position of the document in the documents sent in the bulk request
+type BulkFailure struct {
+	Position int
+	ID       string
+	Status   int
+	Type     string
+	Reason   string
+	Document interface{}
+}
+
+// Retriable checks whether the document was rejected because elasticsearch was overloaded, so
+// may be indexed if it is sent again
+func (f BulkFailure) Retriable() bool {
+	return f.Status == http.StatusTooManyRequests
+}
+
+// bulkResult matches each item of the bulk response to its document, items are returned in
+// the same order as the documents were sent
+func bulkResult(body []byte, documents []interface{}) (*BulkResult, error) {
+	response := &models.BulkResponse{}
+	if err := json.Unmarshal(body, response); err != nil {
+		return nil, err
+	}
+
+	if len(response.Items) != len(documents) {
+		return nil, ErrBulkItemsMismatch
+	}
+
+	result := &BulkResult{}
+
+	for position, action := range response.Items {
+		for _, item := range action {
+			if item.Error == nil && item.Status < http.StatusMultipleChoices {
+				result.Indexed++
+				continue
+			}
+
+			failure := BulkFailure{
+				Position: position,
+				ID:       item.ID,
+				Status:   item.Status,
+				Document: documents[position],
+			}
+
+			if item.Error != nil {
+				failure.Type = item.Error.Type
+				failure.Reason = bulkReason(item.Error)
+			}
+
+			result.Failed = append(result.Failed, failure)
+		}
+	}
+
+	return result, nil
+}
+
+// bulkReason joins the reason of the error with the reasons it was caused by, the underlying
+// reason is usually the one that explains what is wrong with the document
+func bulkReason(bulkErr *models.BulkError) string {
+	var reasons []string
+	for e := bulkErr; e != nil; e = e.CausedBy {
+		if e.Reason != "" {
+			reasons = append(reasons, e.Reason)
+		}
+	}
+
+	return strings.Join(reasons, ": ")
+}
+
+// BulkRequestWithRetries adds documents to an index in bulk, documents rejected because
+// elasticsearch was overloaded are sent again up to retries times after a backoff. The
+// positions of failures refer to the documents passed in.
+func (api *API) BulkRequestWithRetries(ctx context.Context, indexName string, documents []interface{}, retries int) (*BulkResult, int, error) {
+	result, status, err := api.BulkRequest(ctx, indexName, documents)
+	if err != nil {
+		return nil, status, err
+	}
+
+	return api.retryBulk(ctx, indexName, result, status, retries)
+}
+
+// retryBulk sends the documents of the result that were rejected because elasticsearch was
+// overloaded again, up to retries times after a backoff
+func (api *API) retryBulk(ctx context.Context, indexName string, result *BulkResult, status, retries int) (*BulkResult, int, error) {
+	for retry := 0; retry < retries; retry++ {
+		var (
+			retriable []BulkFailure
+			failed    []BulkFailure
+		)
+
+		for _, failure := range result.Failed {
+			if failure.Retriable() {
+				retriable = append(retriable, failure)
+			} else {
+				failed = append(failed, failure)
+			}
+		}
+
+		if len(retriable) == 0 {
+			break
+		}
+
+		if err := bulkBackoff.wait(ctx, retry); err != nil {
+			return result, status, err
+		}
+
+		resend := make([]interface{}, len(retriable))
+		for i, failure := range retriable {
+			resend[i] = failure.Document
+		}
+
+		retried, retryStatus, err := api.BulkRequest(ctx, indexName, resend)
+		if err != nil {
+			return result, retryStatus, err
+		}
+
+		for _, failure := range retried.Failed {
+			failure.Position = retriable[failure.Position].Position
+			failed = append(failed, failure)
+		}
+
+		result.Indexed += retried.Indexed
+		result.Failed = failed
+	}
+
+	return result, status, nil
+}
+
+// rejectedDocument represents a line of the reject file
+type rejectedDocument struct {
+	Position int         `json:"position"`
+	ID       string      `json:"id,omitempty"`
+	Status   int         `json:"status"`
+	Type     string      `json:"type,omitempty"`
+	Reason   string      `json:"reason"`
+	Document interface{} `json:"document"`
+}
+
+// RejectFile writes documents that elasticsearch failed to index to a file with a json object
+// on each line, so that they can be corrected and loaded again. The file is only created
+// once the first document is rejected and is safe for concurrent use.
+type RejectFile struct {
+	mutex    sync.Mutex
+	filename string
+	file     *os.File
+	rejected int
+}
+
+// NewRejectFile creates a reject file that writes to the filename, an existing file is
+// replaced when the first document is rejected
+func NewRejectFile(filename string) *RejectFile {
+	return &RejectFile{filename: filename}
+}
+
+// Write adds the failures to the file, offset is the position in the source of the first
+// document in the bulk request so that each line records the position of the document in
+// the source, e.g. the feature of a geojson file
+func (r *RejectFile) Write(offset int, failures []BulkFailure) error {
+	if len(failures) == 0 {
+		return nil
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.file == nil {
+		file, err := os.Create(r.filename)
+		if err != nil {
+			return err
+		}
+		r.file = file
+	}
+
+	encoder := json.NewEncoder(r.file)
+
+	for _, failure := range failures {
+		err := encoder.Encode(rejectedDocument{
+			Position: offset + failure.Position,
+			ID:       failure.ID,
+			Status:   failure.Status,
+			Type:     failure.Type,
+			Reason:   failure.Reason,
+			Document: failure.Document,
+		})
+		if err != nil {
+			return err
+		}
+
+		r.rejected++
+	}
+
+	return nil
+}
+
+// Rejected returns the number of documents written to the file
+func (r *RejectFile) Rejected() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.rejected
+}
+
+// Filename returns the name of the file that rejected documents are written to
+func (r *RejectFile) Filename() string {
+	return r.filename
+}
+
+// Close closes the file if any documents were rejected
+func (r *RejectFile) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+
+	return r.file.Close()
+}
@@ -0,0 +1,189 @@
+package elasticsearch
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const (
+	bulkIndexed  = `{"index":{"_id":"%s","status":201}}`
+	bulkRejected = `{"index":{"_id":"%s","status":429,"error":{"type":"es_rejected_execution_exception","reason":"rejected execution of bulk"}}}`
+	bulkInvalid  = `{"index":{"_id":"%s","status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse field [location] of type [geo_shape]","caused_by":{"type":"invalid_shape_exception","reason":"Self-intersection at or near point (-3.2, 51.5)"}}}}`
+)
+
+// newBulkServer creates a cluster that responds to each bulk request with the next list of
+// items, the ids of the documents in each request are recorded
+func newBulkServer(responses ...[]string) (*httptest.Server, func() [][]string) {
+	var (
+		mutex    sync.Mutex
+		requests [][]string
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Write([]byte(`{"version":{"number":"7.10.2"}}`))
+			return
+		}
+
+		var ids []string
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var line map[string]map[string]string
+			json.Unmarshal(scanner.Bytes(), &line)
+			if action, ok := line["index"]; ok {
+				ids = append(ids, action["_id"])
+			}
+		}
+
+		mutex.Lock()
+		call := len(requests)
+		requests = append(requests, ids)
+		mutex.Unlock()
+
+		var items []string
+		for i, item := range responses[call] {
+			items = append(items, strings.Replace(item, "%s", ids[i], 1))
+		}
+
+		w.Write([]byte(`{"errors":true,"items":[` + strings.Join(items, ",") + `]}`))
+	}))
+
+	return server, func() [][]string {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return append([][]string(nil), requests...)
+	}
+}
+
+// bulkDocument is a document stored with its own id
+type bulkDocument struct {
+	ID string `json:"id"`
+}
+
+func (d bulkDocument) DocumentID() string {
+	return d.ID
+}
+
+func TestBulkRequestFailures(t *testing.T) {
+	ctx := context.Background()
+	documents := []interface{}{bulkDocument{ID: "a"}, bulkDocument{ID: "b"}, bulkDocument{ID: "c"}}
+
+	Convey("Given a cluster that fails to index some documents of a bulk request", t, func() {
+		server, requests := newBulkServer([]string{bulkIndexed, bulkInvalid, bulkRejected})
+		defer server.Close()
+
+		api := newTestAPI(server.URL)
+
+		Convey("When the documents are added in bulk", func() {
+			result, status, err := api.BulkRequest(ctx, "area-profiles", documents)
+
+			Convey("Then each failed document is listed with its position and reason", func() {
+				So(err, ShouldBeNil)
+				So(status, ShouldEqual, http.StatusOK)
+				So(requests(), ShouldResemble, [][]string{{"a", "b", "c"}})
+				So(result.Indexed, ShouldEqual, 1)
+				So(result.Failed, ShouldResemble, []BulkFailure{
+					{
+						Position: 1,
+						ID:       "b",
+						Status:   http.StatusBadRequest,
+						Type:     "mapper_parsing_exception",
+						Reason:   "failed to parse field [location] of type [geo_shape]: Self-intersection at or near point (-3.2, 51.5)",
+						Document: documents[1],
+					},
+					{
+						Position: 2,
+						ID:       "c",
+						Status:   http.StatusTooManyRequests,
+						Type:     "es_rejected_execution_exception",
+						Reason:   "rejected execution of bulk",
+						Document: documents[2],
+					},
+				})
+				So(result.Failed[0].Retriable(), ShouldBeFalse)
+				So(result.Failed[1].Retriable(), ShouldBeTrue)
+			})
+		})
+	})
+
+	Convey("Given a cluster that is overloaded by the first bulk request", t, func() {
+		backoff := bulkBackoff
+		bulkBackoff = Retries{}
+		defer func() { bulkBackoff = backoff }()
+
+		server, requests := newBulkServer(
+			[]string{bulkRejected, bulkInvalid, bulkRejected},
+			[]string{bulkIndexed, bulkRejected},
+			[]string{bulkIndexed},
+		)
+		defer server.Close()
+
+		api := newTestAPI(server.URL)
+
+		Convey("When the documents are added in bulk with retries", func() {
+			result, _, err := api.BulkRequestWithRetries(ctx, "area-profiles", documents, 2)
+
+			Convey("Then only the rejected documents are sent again until they are indexed", func() {
+				So(err, ShouldBeNil)
+				So(requests(), ShouldResemble, [][]string{{"a", "b", "c"}, {"a", "c"}, {"c"}})
+				So(result.Indexed, ShouldEqual, 2)
+				So(result.Failed, ShouldHaveLength, 1)
+				So(result.Failed[0].Position, ShouldEqual, 1)
+				So(result.Failed[0].ID, ShouldEqual, "b")
+			})
+		})
+	})
+
+	Convey("Given a bulk response without an item for every document", t, func() {
+		body := []byte(`{"errors":false,"items":[{"index":{"_id":"a","status":201}}]}`)
+
+		Convey("When the response is read then the failures cannot be matched to documents", func() {
+			_, err := bulkResult(body, documents)
+			So(err, ShouldEqual, ErrBulkItemsMismatch)
+		})
+	})
+}
+
+func TestRejectFile(t *testing.T) {
+	Convey("Given a reject file", t, func() {
+		dir, err := ioutil.TempDir("", "rejects")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		filename := filepath.Join(dir, "rejects.jsonl")
+		rejects := NewRejectFile(filename)
+
+		Convey("When no documents are rejected then the file is not created", func() {
+			So(rejects.Write(100, nil), ShouldBeNil)
+			So(rejects.Close(), ShouldBeNil)
+
+			_, err := os.Stat(filename)
+			So(os.IsNotExist(err), ShouldBeTrue)
+		})
+
+		Convey("When documents are rejected", func() {
+			So(rejects.Write(100, []BulkFailure{{Position: 1, ID: "b", Status: http.StatusBadRequest, Reason: "invalid shape", Document: bulkDocument{ID: "b"}}}), ShouldBeNil)
+			So(rejects.Write(200, []BulkFailure{{Position: 0, ID: "c", Status: http.StatusTooManyRequests, Reason: "rejected", Document: bulkDocument{ID: "c"}}}), ShouldBeNil)
+			So(rejects.Close(), ShouldBeNil)
+
+			Convey("Then a line is written for each document with its position in the source", func() {
+				b, err := ioutil.ReadFile(filename)
+				So(err, ShouldBeNil)
+				So(rejects.Rejected(), ShouldEqual, 2)
+				So(string(b), ShouldEqual, `{"position":101,"id":"b","status":400,"reason":"invalid shape","document":{"id":"b"}}`+"\n"+
+					`{"position":200,"id":"c","status":429,"reason":"rejected","document":{"id":"c"}}`+"\n")
+			})
+		})
+	})
+}
@@ -8,8 +8,12 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
+	"time"
 
 	errs "github.com/ONSdigital/dp-census-alpha-search-api/apierrors"
+	"github.com/ONSdigital/dp-census-alpha-search-api/metrics"
 	"github.com/ONSdigital/dp-census-alpha-search-api/models"
 	dphttp "github.com/ONSdigital/dp-net/http"
 	"github.com/ONSdigital/log.go/log"
@@ -19,10 +23,23 @@ import (
 // the status received from elastic is not as expected
 var ErrorUnexpectedStatusCode = errors.New("unexpected status code from api")
 
+// ErrDocumentNotFound is returned when deleting a document that does not exist
+var ErrDocumentNotFound = errors.New("document not found")
+
+// Document is implemented by documents that are stored with their own id rather than an id
+// generated by elasticsearch, so that they can later be replaced or deleted by id
+type Document interface {
+	DocumentID() string
+}
+
 // API aggregates a client and URL and other common data for accessing the API
 type API struct {
+	breaker  *CircuitBreaker
 	clienter dphttp.Clienter
+	mutex    sync.Mutex
+	retries  Retries
 	url      string
+	version  *Version
 }
 
 // NewElasticSearchAPI creates an ElasticSearchAPI object
@@ -34,16 +51,44 @@ func NewElasticSearchAPI(clienter dphttp.Clienter, elasticSearchAPIURL string) *
 	}
 }
 
-// CreateSearchIndex creates a new index in elastic search
+// SetRetries sets how idempotent calls that fail with a transient error are retried, calls
+// are not retried by default
+func (api *API) SetRetries(retries Retries) {
+	api.retries = retries
+}
+
+// SetCircuitBreaker sets the circuit breaker that stops calls while elasticsearch is failing
+func (api *API) SetCircuitBreaker(breaker *CircuitBreaker) {
+	api.breaker = breaker
+}
+
+// CircuitBreakerState returns the state of the circuit breaker, which is always closed if a
+// circuit breaker has not been set
+func (api *API) CircuitBreakerState() string {
+	return api.breaker.State()
+}
+
+// CreateSearchIndex creates a new index in elastic search, the typeless mappings file is
+// adapted to the version of the cluster
 func (api *API) CreateSearchIndex(ctx context.Context, indexName string, mappingsFile string) (int, error) {
 	path := api.url + "/" + indexName
 
-	indexMappings, err := Asset(mappingsFile)
+	mappings, err := Asset(mappingsFile)
+	if err != nil {
+		return 0, err
+	}
+
+	version, err := api.clusterVersion(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	body, err := indexMappings(mappings, version)
 	if err != nil {
 		return 0, err
 	}
 
-	_, status, err := api.CallElastic(ctx, path, "PUT", indexMappings)
+	_, status, err := api.CallElastic(ctx, path, "PUT", body)
 	if err != nil {
 		return status, err
 	}
@@ -63,6 +108,59 @@ func (api *API) DeleteSearchIndex(ctx context.Context, indexName string) (int, e
 	return status, nil
 }
 
+// Reindex starts a task in elastic search to copy all documents from the source index
+// into the destination index, returning the id of the task
+func (api *API) Reindex(ctx context.Context, sourceIndex, destinationIndex string) (string, int, error) {
+	path := api.url + "/_reindex?wait_for_completion=false"
+
+	body := models.ReindexRequest{
+		Source: models.ReindexIndex{
+			Index: sourceIndex,
+		},
+		Dest: models.ReindexIndex{
+			Index: destinationIndex,
+		},
+	}
+
+	bytes, err := json.Marshal(body)
+	if err != nil {
+		return "", 0, err
+	}
+
+	responseBody, status, err := api.CallElastic(ctx, path, "POST", bytes)
+	if err != nil {
+		return "", status, err
+	}
+
+	response := &models.ReindexResponse{}
+
+	if err = json.Unmarshal(responseBody, response); err != nil {
+		log.Event(ctx, "unable to unmarshal json body", log.ERROR, log.Error(err))
+		return "", status, errs.ErrUnmarshallingJSON
+	}
+
+	return response.Task, status, nil
+}
+
+// GetTask retrieves the current state of a long running task in elastic search
+func (api *API) GetTask(ctx context.Context, taskID string) (*models.TaskResponse, int, error) {
+	path := api.url + "/_tasks/" + taskID
+
+	responseBody, status, err := api.CallElastic(ctx, path, "GET", nil)
+	if err != nil {
+		return nil, status, err
+	}
+
+	response := &models.TaskResponse{}
+
+	if err = json.Unmarshal(responseBody, response); err != nil {
+		log.Event(ctx, "unable to unmarshal json body", log.ERROR, log.Error(err))
+		return nil, status, errs.ErrUnmarshallingJSON
+	}
+
+	return response, status, nil
+}
+
 // AddDocument adds a document to an elasticsearch index
 func (api *API) AddDocument(ctx context.Context, indexName string, bytes []byte) (int, error) {
 	path := api.url + "/" + indexName + "/_doc"
@@ -78,27 +176,85 @@ func (api *API) AddDocument(ctx context.Context, indexName string, bytes []byte)
 	return status, nil
 }
 
-// BulkRequest ...
-func (api *API) BulkRequest(ctx context.Context, indexName string, documents []interface{}) (int, error) {
-	path := api.url + "/_bulk"
+// BulkRequest adds documents to an index in a single request, the mapping type of each
+// document is only set for 6.x clusters and documents implementing Document keep their id.
+// Elasticsearch indexes each document separately, so documents that fail are listed in the
+// result rather than returned as an error.
+func (api *API) BulkRequest(ctx context.Context, indexName string, documents []interface{}) (*BulkResult, int, error) {
+	version, err := api.clusterVersion(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
 
 	var bulk []byte
 
 	for _, doc := range documents {
-
-		b, err := json.Marshal(doc)
+		line, err := bulkLine(indexName, doc, version)
 		if err != nil {
-			return 0, err
+			return nil, 0, err
 		}
 
-		bulk = append(bulk, []byte("{ \"index\": {\"_index\": \""+indexName+"\", \"_type\": \"_doc\"} }\n")...) // It may need an ID?
-		bulk = append(bulk, b...)
-		bulk = append(bulk, []byte("\n")...)
+		bulk = append(bulk, line...)
 	}
 
-	_, status, err := api.CallElastic(ctx, path, "POST", bulk)
+	return api.sendBulk(ctx, bulk, documents)
+}
+
+// sendBulk sends the body of a bulk request holding the documents, in the same order
+func (api *API) sendBulk(ctx context.Context, bulk []byte, documents []interface{}) (*BulkResult, int, error) {
+	path := api.url + "/_bulk"
+
+	responseBody, status, err := api.CallElastic(ctx, path, "POST", bulk)
 	if err != nil {
-		return status, err
+		return nil, status, err
+	}
+
+	result, err := bulkResult(responseBody, documents)
+	if err != nil {
+		log.Event(ctx, "unable to read bulk response", log.ERROR, log.Error(err), log.Data{"documents": len(documents)})
+		return nil, status, errs.ErrUnmarshallingJSON
+	}
+
+	return result, status, nil
+}
+
+// waitForRefresh holds the response to a write until the next refresh makes the change
+// searchable, so that searches made once the response is received do not see the old document
+const waitForRefresh = "?refresh=wait_for"
+
+// IndexDocument stores the document with the id, replacing any document with the same id. The
+// status is 201 when the document was created and 200 when it replaced an existing document.
+// The response waits until the document is searchable, so it is only used for single changes
+// made through the admin api, loaders add documents with a bulk indexer.
+func (api *API) IndexDocument(ctx context.Context, indexName, id string, document interface{}) (int, error) {
+	path := api.url + "/" + indexName + "/_doc/" + url.PathEscape(id) + waitForRefresh
+	logData := log.Data{"path": path, "id": id}
+
+	bytes, err := json.Marshal(document)
+	if err != nil {
+		log.Event(ctx, "unable to marshal document to bytes", log.ERROR, log.Error(err), logData)
+		return 0, err
+	}
+
+	_, status, err := api.CallElastic(ctx, path, "PUT", bytes)
+	if err != nil {
+		logData["status"] = status
+		return status, documentError(ctx, status, err, logData)
+	}
+
+	return status, nil
+}
+
+// DeleteDocument removes the document with the id, ErrDocumentNotFound is returned if the
+// document does not exist. The response waits until the document is no longer searchable.
+func (api *API) DeleteDocument(ctx context.Context, indexName, id string) (int, error) {
+	path := api.url + "/" + indexName + "/_doc/" + url.PathEscape(id) + waitForRefresh
+	logData := log.Data{"path": path, "id": id}
+
+	_, status, err := api.CallElastic(ctx, path, "DELETE", nil)
+	if err != nil {
+		logData["status"] = status
+		return status, documentError(ctx, status, err, logData)
 	}
 
 	return status, nil
@@ -141,14 +297,7 @@ func (api *API) QuerySearchIndex(ctx context.Context, indexName string, query in
 	responseBody, status, err := api.CallElastic(ctx, path, "GET", bytes)
 	logData["status"] = status
 	if err != nil {
-		if status >= 500 {
-			log.Event(ctx, "failed to call elasticsearch", log.ERROR, log.Error(err), logData)
-			return nil, status, errs.ErrIndexNotFound
-		}
-
-		logData["response"] = responseBody
-		log.Event(ctx, "unexpected response from elasticsearch index", log.ERROR, log.Error(err), logData)
-		return nil, status, errs.ErrBadSearchQuery
+		return nil, status, searchError(ctx, status, err, logData)
 	}
 
 	response := &models.SearchResponse{}
@@ -177,14 +326,7 @@ func (api *API) GetAreaProfile(ctx context.Context, indexName string, query inte
 	responseBody, status, err := api.CallElastic(ctx, path, "GET", bytes)
 	logData["status"] = status
 	if err != nil {
-		if status >= 500 {
-			log.Event(ctx, "failed to call elasticsearch", log.ERROR, log.Error(err), logData)
-			return nil, status, errs.ErrIndexNotFound
-		}
-
-		logData["response"] = responseBody
-		log.Event(ctx, "unexpected response from elasticsearch index", log.ERROR, log.Error(err), logData)
-		return nil, status, errs.ErrBadSearchQuery
+		return nil, status, searchError(ctx, status, err, logData)
 	}
 
 	response := &models.AreaProfileResponse{}
@@ -224,7 +366,8 @@ func (api *API) GetPostcodes(ctx context.Context, indexName, postcode string) (*
 
 	responseBody, status, err := api.CallElastic(ctx, path, "GET", bytes)
 	if err != nil {
-		return nil, status, err
+		logData["status"] = status
+		return nil, status, searchError(ctx, status, err, logData)
 	}
 
 	response := &models.PostcodeResponse{}
@@ -237,7 +380,48 @@ func (api *API) GetPostcodes(ctx context.Context, indexName, postcode string) (*
 	return response, status, nil
 }
 
-// CallElastic builds a request to elastic search based on the method, path and payload
+// GetClusterHealth retrieves the health of the elasticsearch cluster
+func (api *API) GetClusterHealth(ctx context.Context) (*models.ClusterHealth, int, error) {
+	path := api.url + "/_cluster/health"
+
+	responseBody, status, err := api.CallElastic(ctx, path, "GET", nil)
+	if err != nil {
+		return nil, status, err
+	}
+
+	response := &models.ClusterHealth{}
+
+	if err = json.Unmarshal(responseBody, response); err != nil {
+		log.Event(ctx, "unable to unmarshal json body", log.ERROR, log.Error(err))
+		return nil, status, errs.ErrUnmarshallingJSON
+	}
+
+	return response, status, nil
+}
+
+// CountDocuments retrieves the number of documents in an index, a 404 status is returned
+// if the index does not exist
+func (api *API) CountDocuments(ctx context.Context, indexName string) (int, int, error) {
+	path := api.url + "/" + indexName + "/_count"
+
+	responseBody, status, err := api.CallElastic(ctx, path, "GET", nil)
+	if err != nil {
+		return 0, status, err
+	}
+
+	response := &models.CountResponse{}
+
+	if err = json.Unmarshal(responseBody, response); err != nil {
+		log.Event(ctx, "unable to unmarshal json body", log.ERROR, log.Error(err))
+		return 0, status, errs.ErrUnmarshallingJSON
+	}
+
+	return response.Count, status, nil
+}
+
+// CallElastic builds a request to elastic search based on the method, path and payload.
+// Idempotent calls that fail with a transient error are retried, and calls fail fast with
+// ErrCircuitOpen while the circuit breaker is open.
 func (api *API) CallElastic(ctx context.Context, path, method string, payload interface{}) ([]byte, int, error) {
 	logData := log.Data{"url": path, "method": method}
 
@@ -249,14 +433,61 @@ func (api *API) CallElastic(ctx context.Context, path, method string, payload in
 	path = URL.String()
 	logData["url"] = path
 
-	var req *http.Request
+	index, operation := requestLabels(strings.TrimPrefix(path, api.url), method)
 
+	maxRetries := 0
+	if idempotent(method) {
+		maxRetries = api.retries.MaxRetries
+	}
+
+	for retry := 0; ; retry++ {
+		if !api.breaker.Allow() {
+			metrics.CircuitRejected(index, operation)
+			log.Event(ctx, "circuit breaker is open, not calling elastic", log.ERROR, log.Error(ErrCircuitOpen), logData)
+			return nil, 0, ErrCircuitOpen
+		}
+
+		body, status, err := api.call(ctx, path, method, payload, index, operation, logData)
+
+		switch {
+		case cancelled(ctx, err):
+			api.breaker.Cancelled()
+		case unavailable(status, err):
+			api.breaker.Failure()
+		default:
+			api.breaker.Success()
+		}
+
+		if retry >= maxRetries || !retryable(ctx, status, err) {
+			return body, status, err
+		}
+
+		metrics.ElasticsearchRetry(index, operation)
+		log.Event(ctx, "retrying call to elastic", log.WARN, log.Data{"url": path, "method": method, "status": status, "retry": retry + 1})
+
+		if err = api.retries.wait(ctx, retry); err != nil {
+			return nil, status, err
+		}
+	}
+}
+
+// call makes a single request to elastic search
+func (api *API) call(ctx context.Context, path, method string, payload interface{}, index, operation string, logData log.Data) ([]byte, int, error) {
+	var (
+		req *http.Request
+		err error
+	)
+
+	// The request is cancelled when the context is done, e.g. the client has gone away or the
+	// timeout for the operation has passed
 	if payload != nil {
-		req, err = http.NewRequest(method, path, bytes.NewReader(payload.([]byte)))
-		req.Header.Add("Content-type", "application/json")
+		req, err = http.NewRequestWithContext(ctx, method, path, bytes.NewReader(payload.([]byte)))
+		if err == nil {
+			req.Header.Add("Content-type", "application/json")
+		}
 		logData["payload"] = string(payload.([]byte))
 	} else {
-		req, err = http.NewRequest(method, path, nil)
+		req, err = http.NewRequestWithContext(ctx, method, path, nil)
 	}
 	// check req, above, didn't error
 	if err != nil {
@@ -264,19 +495,23 @@ func (api *API) CallElastic(ctx context.Context, path, method string, payload in
 		return nil, 0, err
 	}
 
+	start := time.Now()
+
 	resp, err := api.clienter.Do(ctx, req)
 	if err != nil {
+		metrics.ObserveElasticsearch(index, operation, 0, time.Since(start))
 		log.Event(ctx, "failed to call elastic", log.ERROR, log.Error(err), logData)
-		return nil, 0, err
+		return nil, 0, contextError(ctx, err)
 	}
 	defer resp.Body.Close()
 
 	logData["http_code"] = resp.StatusCode
 
 	jsonBody, err := ioutil.ReadAll(resp.Body)
+	metrics.ObserveElasticsearch(index, operation, resp.StatusCode, time.Since(start))
 	if err != nil {
 		log.Event(ctx, "failed to read response body from call to elastic", log.ERROR, log.Error(err), logData)
-		return nil, resp.StatusCode, err
+		return nil, resp.StatusCode, contextError(ctx, err)
 	}
 	logData["json_body"] = string(jsonBody)
 	logData["status_code"] = resp.StatusCode
@@ -288,3 +523,83 @@ func (api *API) CallElastic(ctx context.Context, path, method string, payload in
 
 	return jsonBody, resp.StatusCode, nil
 }
+
+// searchError converts the error from a failed search into the error returned to api users
+func searchError(ctx context.Context, status int, err error, logData log.Data) error {
+	switch {
+	case errors.Is(err, errs.ErrElasticsearchTimeout):
+		log.Event(ctx, "elasticsearch did not respond before the request was cancelled", log.ERROR, log.Error(err), logData)
+		return err
+	case err == ErrCircuitOpen, status == 0, status == http.StatusTooManyRequests, status >= http.StatusInternalServerError:
+		log.Event(ctx, "elasticsearch is unavailable", log.ERROR, log.Error(err), logData)
+		return errs.ErrElasticsearchUnavailable
+	case status == http.StatusNotFound:
+		log.Event(ctx, "elasticsearch index not found", log.ERROR, log.Error(err), logData)
+		return errs.ErrIndexNotFound
+	default:
+		log.Event(ctx, "unexpected response from elasticsearch index", log.ERROR, log.Error(err), logData)
+		return errs.ErrBadSearchQuery
+	}
+}
+
+// documentError converts the error from a failed write of a single document into the error
+// returned to api users
+func documentError(ctx context.Context, status int, err error, logData log.Data) error {
+	switch {
+	case errors.Is(err, errs.ErrElasticsearchTimeout):
+		log.Event(ctx, "elasticsearch did not respond before the request was cancelled", log.ERROR, log.Error(err), logData)
+		return err
+	case err == ErrCircuitOpen, status == 0, status == http.StatusTooManyRequests, status >= http.StatusInternalServerError:
+		log.Event(ctx, "elasticsearch is unavailable", log.ERROR, log.Error(err), logData)
+		return errs.ErrElasticsearchUnavailable
+	case status == http.StatusNotFound:
+		log.Event(ctx, "document not found", log.WARN, log.Error(err), logData)
+		return ErrDocumentNotFound
+	case status == http.StatusBadRequest:
+		log.Event(ctx, "elasticsearch rejected document", log.ERROR, log.Error(err), logData)
+		return errs.ErrDocumentRejected
+	default:
+		log.Event(ctx, "unexpected response from elasticsearch", log.ERROR, log.Error(err), logData)
+		return err
+	}
+}
+
+// contextError returns the timeout error if a call to elastic failed because the context was
+// cancelled or its deadline passed, otherwise the error is returned unchanged
+func contextError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return errs.ErrElasticsearchTimeout
+	}
+
+	return err
+}
+
+// requestLabels returns the index and operation of a request to elasticsearch from the path
+// relative to the elasticsearch url, e.g. /datasets/_search is the search operation on the
+// datasets index. Requests to an index without an operation, such as creating an index, are
+// labelled with the request method.
+func requestLabels(path, method string) (string, string) {
+	if i := strings.Index(path, "?"); i >= 0 {
+		path = path[:i]
+	}
+
+	var index, operation string
+	for _, segment := range strings.Split(strings.Trim(path, "/"), "/") {
+		switch {
+		case segment == "":
+			continue
+		case strings.HasPrefix(segment, "_"):
+			if operation == "" {
+				operation = strings.TrimPrefix(segment, "_")
+			}
+		case index == "" && operation == "":
+			index = segment
+		}
+	}
+
+	if operation == "" {
+		operation = strings.ToLower(method)
+	}
+
+	return index, operation
+}
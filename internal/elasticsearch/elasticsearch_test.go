@@ -0,0 +1,334 @@
+package elasticsearch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	errs "github.com/ONSdigital/dp-census-alpha-search-api/apierrors"
+	"github.com/ONSdigital/dp-census-alpha-search-api/models"
+	dphttp "github.com/ONSdigital/dp-net/http"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRequestLabels(t *testing.T) {
+	Convey("Given the paths of requests to elasticsearch", t, func() {
+		tests := []struct {
+			path      string
+			method    string
+			index     string
+			operation string
+		}{
+			{path: "/datasets,area-profiles/_search", method: "GET", index: "datasets,area-profiles", operation: "search"},
+			{path: "/postcodes/_count", method: "GET", index: "postcodes", operation: "count"},
+			{path: "/_bulk", method: "POST", index: "", operation: "bulk"},
+			{path: "/_reindex?wait_for_completion=false", method: "POST", index: "", operation: "reindex"},
+			{path: "/_tasks/node:1", method: "GET", index: "", operation: "tasks"},
+			{path: "/datasets", method: "PUT", index: "datasets", operation: "put"},
+			{path: "", method: "GET", index: "", operation: "get"},
+		}
+
+		Convey("When the labels are created then each request has its index and operation", func() {
+			for _, test := range tests {
+				index, operation := requestLabels(test.path, test.method)
+				So(index, ShouldEqual, test.index)
+				So(operation, ShouldEqual, test.operation)
+			}
+		})
+	})
+}
+
+func TestCallElasticTimeout(t *testing.T) {
+	Convey("Given elasticsearch does not respond until the request is cancelled", t, func() {
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-release:
+			}
+		}))
+		defer server.Close()
+		defer close(release)
+
+		api := NewElasticSearchAPI(dphttp.NewClient(), server.URL)
+
+		Convey("When a search is cancelled by its deadline", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			response, status, err := api.QuerySearchIndex(ctx, "datasets", models.Body{})
+
+			Convey("Then the timeout error is returned without waiting for elasticsearch", func() {
+				So(err, ShouldEqual, errs.ErrElasticsearchTimeout)
+				So(status, ShouldEqual, 0)
+				So(response, ShouldBeNil)
+				So(time.Since(start), ShouldBeLessThan, time.Second)
+			})
+		})
+	})
+}
+
+// newTestServer creates an elasticsearch server that responds with each status in turn, the
+// last status is repeated once every status has been used
+func newTestServer(statuses ...int) (*httptest.Server, *int32) {
+	var calls int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := int(atomic.AddInt32(&calls, 1)) - 1
+		if call >= len(statuses) {
+			call = len(statuses) - 1
+		}
+
+		w.WriteHeader(statuses[call])
+		w.Write([]byte(`{"hits":{"total":0,"hits":[]}}`))
+	}))
+
+	return server, &calls
+}
+
+// newTestAPI creates an api that retries calls twice without waiting and does not retry in the client
+func newTestAPI(url string) *API {
+	cli := dphttp.NewClient()
+	cli.SetMaxRetries(0)
+
+	api := NewElasticSearchAPI(cli, url)
+	api.SetRetries(Retries{MaxRetries: 2})
+
+	return api
+}
+
+func TestCallElasticRetries(t *testing.T) {
+	ctx := context.Background()
+
+	Convey("Given elasticsearch is unavailable before recovering", t, func() {
+		server, calls := newTestServer(http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK)
+		defer server.Close()
+
+		api := newTestAPI(server.URL)
+
+		Convey("When a search is made", func() {
+			response, status, err := api.QuerySearchIndex(ctx, "datasets", models.Body{})
+
+			Convey("Then the search is retried until it succeeds", func() {
+				So(err, ShouldBeNil)
+				So(status, ShouldEqual, http.StatusOK)
+				So(response, ShouldNotBeNil)
+				So(atomic.LoadInt32(calls), ShouldEqual, 3)
+			})
+		})
+
+		Convey("When a document is added", func() {
+			status, err := api.AddDocument(ctx, "datasets", []byte(`{}`))
+
+			Convey("Then the call is not retried as it is not idempotent", func() {
+				So(err, ShouldEqual, ErrorUnexpectedStatusCode)
+				So(status, ShouldEqual, http.StatusServiceUnavailable)
+				So(atomic.LoadInt32(calls), ShouldEqual, 1)
+			})
+		})
+	})
+
+	Convey("Given elasticsearch stays unavailable", t, func() {
+		server, calls := newTestServer(http.StatusBadGateway)
+		defer server.Close()
+
+		api := newTestAPI(server.URL)
+
+		Convey("When a search is made", func() {
+			_, status, err := api.QuerySearchIndex(ctx, "datasets", models.Body{})
+
+			Convey("Then the retries are used up and elasticsearch is reported as unavailable", func() {
+				So(err, ShouldEqual, errs.ErrElasticsearchUnavailable)
+				So(status, ShouldEqual, http.StatusBadGateway)
+				So(atomic.LoadInt32(calls), ShouldEqual, 3)
+			})
+		})
+	})
+
+	Convey("Given elasticsearch rejects the query", t, func() {
+		server, calls := newTestServer(http.StatusBadRequest)
+		defer server.Close()
+
+		api := newTestAPI(server.URL)
+
+		Convey("When a search is made", func() {
+			_, status, err := api.QuerySearchIndex(ctx, "datasets", models.Body{})
+
+			Convey("Then the search is not retried", func() {
+				So(err, ShouldEqual, errs.ErrBadSearchQuery)
+				So(status, ShouldEqual, http.StatusBadRequest)
+				So(atomic.LoadInt32(calls), ShouldEqual, 1)
+			})
+		})
+	})
+
+	Convey("Given the index does not exist", t, func() {
+		server, _ := newTestServer(http.StatusNotFound)
+		defer server.Close()
+
+		api := newTestAPI(server.URL)
+
+		Convey("When a search is made then the index is reported as not found", func() {
+			_, status, err := api.QuerySearchIndex(ctx, "datasets", models.Body{})
+			So(err, ShouldEqual, errs.ErrIndexNotFound)
+			So(status, ShouldEqual, http.StatusNotFound)
+		})
+	})
+}
+
+func TestCallElasticCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+
+	Convey("Given elasticsearch is down and a circuit breaker that opens after 2 failures", t, func() {
+		server, calls := newTestServer(http.StatusServiceUnavailable)
+		defer server.Close()
+
+		api := newTestAPI(server.URL)
+		api.SetCircuitBreaker(NewCircuitBreaker(2, time.Minute))
+
+		Convey("When a search is made", func() {
+			_, _, err := api.QuerySearchIndex(ctx, "datasets", models.Body{})
+
+			Convey("Then the retries stop once the circuit opens", func() {
+				So(err, ShouldEqual, errs.ErrElasticsearchUnavailable)
+				So(atomic.LoadInt32(calls), ShouldEqual, 2)
+				So(api.CircuitBreakerState(), ShouldEqual, CircuitOpen)
+			})
+
+			Convey("Then later calls fail fast without calling elasticsearch", func() {
+				_, status, err := api.CallElastic(ctx, server.URL+"/_cluster/health", "GET", nil)
+				So(err, ShouldEqual, ErrCircuitOpen)
+				So(status, ShouldEqual, 0)
+				So(atomic.LoadInt32(calls), ShouldEqual, 2)
+			})
+		})
+	})
+}
+
+func TestCallElasticCircuitBreakerTimeouts(t *testing.T) {
+	Convey("Given elasticsearch does not respond and a circuit breaker that opens after 1 failure", t, func() {
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-release:
+			}
+		}))
+		defer server.Close()
+		defer close(release)
+
+		api := newTestAPI(server.URL)
+		api.SetCircuitBreaker(NewCircuitBreaker(1, time.Minute))
+
+		Convey("When a search is stopped by the deadline of the operation", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+
+			_, _, err := api.QuerySearchIndex(ctx, "datasets", models.Body{})
+
+			Convey("Then the timeout counts as a failure and the circuit opens", func() {
+				So(err, ShouldEqual, errs.ErrElasticsearchTimeout)
+				So(api.CircuitBreakerState(), ShouldEqual, CircuitOpen)
+			})
+		})
+
+		Convey("When a search is cancelled by the client", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(20*time.Millisecond, cancel)
+
+			_, _, err := api.QuerySearchIndex(ctx, "datasets", models.Body{})
+
+			Convey("Then the cancellation is not counted and the circuit stays closed", func() {
+				So(err, ShouldEqual, errs.ErrElasticsearchTimeout)
+				So(api.CircuitBreakerState(), ShouldEqual, CircuitClosed)
+			})
+		})
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	Convey("Given retries that back off from 100ms up to 1s", t, func() {
+		retries := Retries{MaxRetries: 10, InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+
+		Convey("When the backoff is calculated then it is at most the doubled backoff capped at the maximum", func() {
+			for i := 0; i < 100; i++ {
+				So(retries.backoff(0), ShouldBeBetweenOrEqual, 0, 100*time.Millisecond)
+				So(retries.backoff(2), ShouldBeBetweenOrEqual, 0, 400*time.Millisecond)
+				So(retries.backoff(8), ShouldBeBetweenOrEqual, 0, time.Second)
+			}
+		})
+	})
+}
+
+func TestDocumentErrors(t *testing.T) {
+	ctx := context.Background()
+
+	Convey("Given elasticsearch responds to requests for a single document with an error", t, func() {
+		tests := []struct {
+			name   string
+			status int
+			err    error
+		}{
+			{name: "the document is missing", status: http.StatusNotFound, err: ErrDocumentNotFound},
+			{name: "the document is rejected", status: http.StatusBadRequest, err: errs.ErrDocumentRejected},
+			{name: "elasticsearch is unavailable", status: http.StatusServiceUnavailable, err: errs.ErrElasticsearchUnavailable},
+		}
+
+		for _, test := range tests {
+			Convey("When "+test.name+" then the error is returned once without retrying", func() {
+				server, calls := newTestServer(test.status)
+				defer server.Close()
+
+				api := newTestAPI(server.URL)
+
+				status, err := api.IndexDocument(ctx, "datasets", "deaths-by-sex", map[string]string{"title": "Deaths"})
+				So(status, ShouldEqual, test.status)
+				So(err, ShouldEqual, test.err)
+
+				status, err = api.DeleteDocument(ctx, "datasets", "deaths-by-sex")
+				So(status, ShouldEqual, test.status)
+				So(err, ShouldEqual, test.err)
+
+				So(atomic.LoadInt32(calls), ShouldEqual, 2)
+			})
+		}
+	})
+}
+
+func TestDocumentRefresh(t *testing.T) {
+	ctx := context.Background()
+
+	Convey("Given elasticsearch stores and deletes single documents", t, func() {
+		var mutex sync.Mutex
+		var queries []string
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mutex.Lock()
+			queries = append(queries, r.Method+" "+r.URL.Path+"?"+r.URL.RawQuery)
+			mutex.Unlock()
+
+			w.Write([]byte(`{}`))
+		}))
+		defer server.Close()
+
+		api := newTestAPI(server.URL)
+
+		Convey("When a document is stored and deleted then each request waits for the change to be searchable", func() {
+			_, err := api.IndexDocument(ctx, "datasets", "deaths-by-sex", map[string]string{"title": "Deaths"})
+			So(err, ShouldBeNil)
+
+			_, err = api.DeleteDocument(ctx, "datasets", "deaths-by-sex")
+			So(err, ShouldBeNil)
+
+			So(queries, ShouldResemble, []string{
+				"PUT /datasets/_doc/deaths-by-sex?refresh=wait_for",
+				"DELETE /datasets/_doc/deaths-by-sex?refresh=wait_for",
+			})
+		})
+	})
+}
@@ -0,0 +1,288 @@
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ONSdigital/log.go/log"
+)
+
+// A list of defaults used by bulk indexers for any setting that is not positive
+const (
+	DefaultBulkWorkers = 4
+	DefaultBulkSize    = 500
+	DefaultBulkBytes   = 5 << 20
+)
+
+// ErrIndexerClosed is returned when a document is added to a bulk indexer that has been closed
+var ErrIndexerClosed = errors.New("bulk indexer is closed")
+
+// BulkIndexerConfig configures how a bulk indexer batches documents and sends them to
+// elasticsearch. A batch is sent once it holds Size documents or its body would grow past
+// Bytes, a single document larger than Bytes is sent on its own.
+type BulkIndexerConfig struct {
+	Index   string
+	Workers int
+	Size    int
+	Bytes   int
+	Retries int
+
+	// Rejects, if set, is the file that documents elasticsearch failed to index are written to
+	Rejects *RejectFile
+
+	// OnProgress, if set, is called with the running totals once each bulk request completes,
+	// calls are never made at the same time
+	OnProgress func(BulkStats)
+}
+
+// BulkStats represents the running totals of a bulk indexer, added documents that are neither
+// indexed nor failed are waiting to be sent
+type BulkStats struct {
+	Added    int
+	Indexed  int
+	Failed   int
+	Requests int
+}
+
+// BulkIndexer adds documents to an index in batches sent by a pool of workers. Adding a
+// document blocks while every worker is busy and the next batch is full, so a loader reading
+// faster than elasticsearch can index does not hold its whole source in memory. It is safe for
+// concurrent use.
+type BulkIndexer struct {
+	api     *API
+	config  BulkIndexerConfig
+	version Version
+
+	mutex   sync.Mutex
+	batch   *bulkBatch
+	closed  bool
+	batches chan *bulkBatch
+	workers sync.WaitGroup
+
+	// progressMutex serialises progress callbacks, statsMutex guards the totals and the error
+	progressMutex sync.Mutex
+	statsMutex    sync.Mutex
+	stats         BulkStats
+	err           error
+}
+
+// bulkBatch represents the body of a bulk request along with its documents and the position
+// of each document in the source
+type bulkBatch struct {
+	body      []byte
+	documents []interface{}
+	positions []int
+}
+
+// NewBulkIndexer creates a bulk indexer and starts its workers, which send batches until the
+// indexer is closed or the context is done
+func NewBulkIndexer(ctx context.Context, api *API, config BulkIndexerConfig) (*BulkIndexer, error) {
+	if config.Workers <= 0 {
+		config.Workers = DefaultBulkWorkers
+	}
+
+	if config.Size <= 0 {
+		config.Size = DefaultBulkSize
+	}
+
+	if config.Bytes <= 0 {
+		config.Bytes = DefaultBulkBytes
+	}
+
+	version, err := api.clusterVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	indexer := &BulkIndexer{
+		api:     api,
+		config:  config,
+		version: version,
+		batch:   &bulkBatch{},
+		batches: make(chan *bulkBatch),
+	}
+
+	for i := 0; i < config.Workers; i++ {
+		indexer.workers.Add(1)
+		go indexer.work(ctx)
+	}
+
+	return indexer, nil
+}
+
+// Add queues the document to be indexed, position is the position of the document in the
+// source, e.g. the row of a csv file, which is reported if elasticsearch fails to index it.
+// The error of a failed bulk request is returned and no more documents are sent once a bulk
+// request has failed.
+func (b *BulkIndexer) Add(ctx context.Context, position int, document interface{}) error {
+	if err := b.Err(); err != nil {
+		return err
+	}
+
+	line, err := bulkLine(b.config.Index, document, b.version)
+	if err != nil {
+		return err
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.closed {
+		return ErrIndexerClosed
+	}
+
+	if len(b.batch.documents) > 0 && len(b.batch.body)+len(line) > b.config.Bytes {
+		if err = b.send(ctx); err != nil {
+			return err
+		}
+	}
+
+	b.batch.body = append(b.batch.body, line...)
+	b.batch.documents = append(b.batch.documents, document)
+	b.batch.positions = append(b.batch.positions, position)
+
+	b.statsMutex.Lock()
+	b.stats.Added++
+	b.statsMutex.Unlock()
+
+	if len(b.batch.documents) >= b.config.Size {
+		return b.send(ctx)
+	}
+
+	return nil
+}
+
+// send hands the current batch to the next free worker, the mutex must be held
+func (b *BulkIndexer) send(ctx context.Context) error {
+	batch := b.batch
+	b.batch = &bulkBatch{}
+
+	select {
+	case b.batches <- batch:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close sends the documents waiting in the current batch and waits for every bulk request to
+// complete, returning the error of the first bulk request that failed
+func (b *BulkIndexer) Close(ctx context.Context) error {
+	b.mutex.Lock()
+
+	if b.closed {
+		b.mutex.Unlock()
+		return b.Err()
+	}
+
+	var err error
+	if len(b.batch.documents) > 0 {
+		err = b.send(ctx)
+	}
+
+	b.closed = true
+	close(b.batches)
+	b.mutex.Unlock()
+
+	b.workers.Wait()
+
+	if indexErr := b.Err(); indexErr != nil {
+		return indexErr
+	}
+
+	return err
+}
+
+// Stats returns the running totals of the indexer
+func (b *BulkIndexer) Stats() BulkStats {
+	b.statsMutex.Lock()
+	defer b.statsMutex.Unlock()
+
+	return b.stats
+}
+
+// Err returns the error of the first bulk request that failed
+func (b *BulkIndexer) Err() error {
+	b.statsMutex.Lock()
+	defer b.statsMutex.Unlock()
+
+	return b.err
+}
+
+// work sends batches until the indexer is closed, batches are discarded once a bulk request
+// has failed
+func (b *BulkIndexer) work(ctx context.Context) {
+	defer b.workers.Done()
+
+	for batch := range b.batches {
+		if b.Err() != nil {
+			continue
+		}
+
+		if err := b.index(ctx, batch); err != nil {
+			b.statsMutex.Lock()
+			if b.err == nil {
+				b.err = err
+			}
+			b.statsMutex.Unlock()
+		}
+	}
+}
+
+// index sends a batch and records the outcome, positions of failures are replaced with the
+// position of the document in the source
+func (b *BulkIndexer) index(ctx context.Context, batch *bulkBatch) error {
+	result, status, err := b.api.sendBulk(ctx, batch.body, batch.documents)
+	if err == nil {
+		result, status, err = b.api.retryBulk(ctx, b.config.Index, result, status, b.config.Retries)
+	}
+	if err != nil {
+		log.Event(ctx, "bulk request failed", log.ERROR, log.Error(err), log.Data{"status": status, "index": b.config.Index, "documents": len(batch.documents)})
+		return err
+	}
+
+	for i, failure := range result.Failed {
+		result.Failed[i].Position = batch.positions[failure.Position]
+		log.Event(ctx, "elasticsearch failed to index document", log.WARN, log.Data{"position": result.Failed[i].Position, "id": failure.ID, "status": failure.Status, "type": failure.Type, "reason": failure.Reason})
+	}
+
+	if b.config.Rejects != nil {
+		if err = b.config.Rejects.Write(0, result.Failed); err != nil {
+			log.Event(ctx, "failed to write rejected documents", log.ERROR, log.Error(err), log.Data{"filename": b.config.Rejects.Filename()})
+			return err
+		}
+	}
+
+	b.progressMutex.Lock()
+	defer b.progressMutex.Unlock()
+
+	b.statsMutex.Lock()
+	b.stats.Indexed += result.Indexed
+	b.stats.Failed += len(result.Failed)
+	b.stats.Requests++
+	stats := b.stats
+	b.statsMutex.Unlock()
+
+	if b.config.OnProgress != nil {
+		b.config.OnProgress(stats)
+	}
+
+	return nil
+}
+
+// LogProgress returns a progress callback that logs the running totals at most once every
+// interval
+func LogProgress(ctx context.Context, interval time.Duration) func(BulkStats) {
+	var logged time.Time
+
+	return func(stats BulkStats) {
+		if time.Since(logged) < interval {
+			return
+		}
+		logged = time.Now()
+
+		log.Event(ctx, "bulk indexing progress", log.INFO, log.Data{"added": stats.Added, "indexed": stats.Indexed, "failed": stats.Failed, "requests": stats.Requests})
+	}
+}
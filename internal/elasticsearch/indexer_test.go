@@ -0,0 +1,242 @@
+package elasticsearch
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// indexerServer is a cluster that indexes every document of a bulk request apart from the
+// documents with an invalid id, recording the ids of each request and the most requests
+// handled at the same time
+type indexerServer struct {
+	mutex    sync.Mutex
+	invalid  map[string]bool
+	status   int
+	delay    time.Duration
+	requests [][]string
+	inFlight int
+	peak     int
+}
+
+func (s *indexerServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/" {
+		w.Write([]byte(`{"version":{"number":"7.10.2"}}`))
+		return
+	}
+
+	s.mutex.Lock()
+	s.inFlight++
+	if s.inFlight > s.peak {
+		s.peak = s.inFlight
+	}
+	s.mutex.Unlock()
+
+	time.Sleep(s.delay)
+
+	var ids []string
+	scanner := bufio.NewScanner(r.Body)
+	for scanner.Scan() {
+		var line map[string]map[string]string
+		json.Unmarshal(scanner.Bytes(), &line)
+		if action, ok := line["index"]; ok {
+			ids = append(ids, action["_id"])
+		}
+	}
+
+	s.mutex.Lock()
+	s.inFlight--
+	s.requests = append(s.requests, ids)
+	s.mutex.Unlock()
+
+	if s.status != 0 {
+		w.WriteHeader(s.status)
+		return
+	}
+
+	var items []string
+	for _, id := range ids {
+		item := bulkIndexed
+		if s.invalid[id] {
+			item = bulkInvalid
+		}
+		items = append(items, strings.Replace(item, "%s", id, 1))
+	}
+
+	w.Write([]byte(`{"errors":true,"items":[` + strings.Join(items, ",") + `]}`))
+}
+
+func (s *indexerServer) sent() (int, int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	documents := 0
+	for _, ids := range s.requests {
+		documents += len(ids)
+	}
+
+	return len(s.requests), documents
+}
+
+// addDocuments adds documents with the ids to the indexer, positions start at 1
+func addDocuments(indexer *BulkIndexer, ids ...string) {
+	for i, id := range ids {
+		So(indexer.Add(context.Background(), i+1, bulkDocument{ID: id}), ShouldBeNil)
+	}
+}
+
+func TestBulkIndexer(t *testing.T) {
+	ctx := context.Background()
+	ids := []string{"a", "b", "c", "d", "e", "f", "g"}
+
+	Convey("Given a bulk indexer that sends up to 3 documents in each request", t, func() {
+		es := &indexerServer{}
+		server := httptest.NewServer(es)
+		defer server.Close()
+
+		var progress []BulkStats
+		indexer, err := NewBulkIndexer(ctx, newTestAPI(server.URL), BulkIndexerConfig{
+			Index:      "postcodes",
+			Workers:    2,
+			Size:       3,
+			OnProgress: func(stats BulkStats) { progress = append(progress, stats) },
+		})
+		So(err, ShouldBeNil)
+
+		Convey("When documents are added and the indexer is closed", func() {
+			addDocuments(indexer, ids...)
+			So(indexer.Close(ctx), ShouldBeNil)
+
+			Convey("Then the documents are sent in full batches and the last batch is flushed", func() {
+				requests, documents := es.sent()
+				So(requests, ShouldEqual, 3)
+				So(documents, ShouldEqual, 7)
+				So(indexer.Stats(), ShouldResemble, BulkStats{Added: 7, Indexed: 7, Requests: 3})
+			})
+
+			Convey("Then progress is reported after each request", func() {
+				So(progress, ShouldHaveLength, 3)
+				So(progress[2], ShouldResemble, BulkStats{Added: 7, Indexed: 7, Requests: 3})
+			})
+
+			Convey("Then documents cannot be added once the indexer is closed", func() {
+				So(indexer.Add(ctx, 8, bulkDocument{ID: "h"}), ShouldEqual, ErrIndexerClosed)
+			})
+		})
+	})
+
+	Convey("Given a bulk indexer that limits the size of each request", t, func() {
+		es := &indexerServer{}
+		server := httptest.NewServer(es)
+		defer server.Close()
+
+		line, err := bulkLine("postcodes", bulkDocument{ID: "a"}, Version{Major: 7})
+		So(err, ShouldBeNil)
+
+		indexer, err := NewBulkIndexer(ctx, newTestAPI(server.URL), BulkIndexerConfig{
+			Index: "postcodes",
+			Bytes: 2*len(line) + 1,
+		})
+		So(err, ShouldBeNil)
+
+		Convey("When documents are added then each request holds as many documents as fit", func() {
+			addDocuments(indexer, ids...)
+			So(indexer.Close(ctx), ShouldBeNil)
+
+			requests, documents := es.sent()
+			So(requests, ShouldEqual, 4)
+			So(documents, ShouldEqual, 7)
+		})
+	})
+
+	Convey("Given a cluster that takes a while to respond", t, func() {
+		es := &indexerServer{delay: 50 * time.Millisecond}
+		server := httptest.NewServer(es)
+		defer server.Close()
+
+		indexer, err := NewBulkIndexer(ctx, newTestAPI(server.URL), BulkIndexerConfig{
+			Index:   "postcodes",
+			Workers: 2,
+			Size:    1,
+		})
+		So(err, ShouldBeNil)
+
+		Convey("When documents are added then requests are sent by each worker at the same time", func() {
+			addDocuments(indexer, ids...)
+			So(indexer.Close(ctx), ShouldBeNil)
+
+			es.mutex.Lock()
+			defer es.mutex.Unlock()
+			So(es.peak, ShouldEqual, 2)
+			So(es.requests, ShouldHaveLength, 7)
+		})
+	})
+
+	Convey("Given a cluster that fails to index some documents", t, func() {
+		dir, err := ioutil.TempDir("", "indexer")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		es := &indexerServer{invalid: map[string]bool{"b": true, "f": true}}
+		server := httptest.NewServer(es)
+		defer server.Close()
+
+		rejects := NewRejectFile(filepath.Join(dir, "rejects.jsonl"))
+		indexer, err := NewBulkIndexer(ctx, newTestAPI(server.URL), BulkIndexerConfig{
+			Index:   "area-profiles",
+			Size:    3,
+			Rejects: rejects,
+		})
+		So(err, ShouldBeNil)
+
+		Convey("When documents are added", func() {
+			addDocuments(indexer, ids...)
+			So(indexer.Close(ctx), ShouldBeNil)
+			So(rejects.Close(), ShouldBeNil)
+
+			Convey("Then the failed documents are written to the rejects file with their position in the source", func() {
+				So(indexer.Stats(), ShouldResemble, BulkStats{Added: 7, Indexed: 5, Failed: 2, Requests: 3})
+				So(rejects.Rejected(), ShouldEqual, 2)
+
+				b, err := ioutil.ReadFile(rejects.Filename())
+				So(err, ShouldBeNil)
+				So(string(b), ShouldContainSubstring, `{"position":2,"id":"b","status":400`)
+				So(string(b), ShouldContainSubstring, `{"position":6,"id":"f","status":400`)
+			})
+		})
+	})
+
+	Convey("Given a cluster that fails bulk requests", t, func() {
+		es := &indexerServer{status: http.StatusBadRequest}
+		server := httptest.NewServer(es)
+		defer server.Close()
+
+		indexer, err := NewBulkIndexer(ctx, newTestAPI(server.URL), BulkIndexerConfig{
+			Index:   "postcodes",
+			Workers: 1,
+			Size:    1,
+		})
+		So(err, ShouldBeNil)
+
+		Convey("When documents are added then the error is returned and no more requests are sent", func() {
+			So(indexer.Add(ctx, 1, bulkDocument{ID: "a"}), ShouldBeNil)
+
+			So(indexer.Close(ctx), ShouldNotBeNil)
+			So(indexer.Add(ctx, 2, bulkDocument{ID: "b"}), ShouldNotBeNil)
+
+			requests, _ := es.sent()
+			So(requests, ShouldEqual, 1)
+		})
+	})
+}
@@ -0,0 +1,108 @@
+package memory
+
+import (
+	"github.com/ONSdigital/dp-census-alpha-search-api/models"
+)
+
+// locationField is the geo_shape field queried by geo_shape filters
+const locationField = "location"
+
+// bounds represents the bounding box of a shape
+type bounds struct {
+	minLon, minLat, maxLon, maxLat float64
+}
+
+// matchesShape checks whether the bounding box of the document location has the relation to the
+// bounding box of the shape, documents without a location do not match
+func matchesShape(doc *document, query models.GeoLocationObj) bool {
+	location, ok := doc.source[locationField].(map[string]interface{})
+	if !ok {
+		return false
+	}
+
+	docBounds, ok := shapeBounds(location["coordinates"])
+	if !ok {
+		return false
+	}
+
+	queryBounds, ok := shapeBounds(query.Shape.Coordinates)
+	if !ok {
+		return false
+	}
+
+	switch query.Relation {
+	case "within":
+		return queryBounds.contains(docBounds)
+	case "contains":
+		return docBounds.contains(queryBounds)
+	case "disjoint":
+		return !docBounds.intersects(queryBounds)
+	default:
+		return docBounds.intersects(queryBounds)
+	}
+}
+
+func (b bounds) contains(other bounds) bool {
+	return b.minLon <= other.minLon && b.maxLon >= other.maxLon && b.minLat <= other.minLat && b.maxLat >= other.maxLat
+}
+
+func (b bounds) intersects(other bounds) bool {
+	return b.minLon <= other.maxLon && b.maxLon >= other.minLon && b.minLat <= other.maxLat && b.maxLat >= other.minLat
+}
+
+// shapeBounds calculates the bounding box of geojson coordinates, which are [lon, lat] points
+// nested in any number of arrays
+func shapeBounds(coordinates interface{}) (bounds, bool) {
+	var (
+		b     bounds
+		found bool
+	)
+
+	var walk func(value interface{})
+	walk = func(value interface{}) {
+		list, ok := value.([]interface{})
+		if !ok {
+			return
+		}
+
+		if len(list) == 2 {
+			lon, lonOK := list[0].(float64)
+			lat, latOK := list[1].(float64)
+			if lonOK && latOK {
+				if !found {
+					b = bounds{minLon: lon, minLat: lat, maxLon: lon, maxLat: lat}
+					found = true
+					return
+				}
+
+				b.minLon = minFloat(b.minLon, lon)
+				b.maxLon = maxFloat(b.maxLon, lon)
+				b.minLat = minFloat(b.minLat, lat)
+				b.maxLat = maxFloat(b.maxLat, lat)
+				return
+			}
+		}
+
+		for _, item := range list {
+			walk(item)
+		}
+	}
+
+	walk(coordinates)
+
+	return b, found
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,463 @@
+// Package memory provides an in memory implementation of the elasticsearch calls made by the
+// search api. Queries are evaluated against documents held in memory so that handlers can be
+// tested without an elasticsearch cluster.
+//
+// Only the subset of the query dsl generated by the api is supported: bool queries with must,
+// must_not, should and filter clauses, match, match_phrase, term, terms, nested and geo_shape
+// queries, terms aggregations, sorting on score, sort_title, index and id, from, size and
+// search_after. Text is analysed by lower casing it and splitting it on anything that is not
+// a letter or a digit, and geo shapes are compared by their bounding boxes.
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	errs "github.com/ONSdigital/dp-census-alpha-search-api/apierrors"
+	"github.com/ONSdigital/dp-census-alpha-search-api/internal/elasticsearch"
+	"github.com/ONSdigital/dp-census-alpha-search-api/models"
+)
+
+// defaultSize is the number of hits returned when a query does not set a size
+const defaultSize = 10
+
+// clusterStatusGreen is the cluster health status when all shards are allocated
+const clusterStatusGreen = "green"
+
+// Elasticsearch holds search indexes in memory
+type Elasticsearch struct {
+	mutex         sync.RWMutex
+	clusterStatus string
+	circuitState  string
+	indexes       map[string][]*document
+	failures      map[string]failure
+	delays        map[string]time.Duration
+}
+
+// document represents a single document stored in an index
+type document struct {
+	id     string
+	index  string
+	raw    []byte
+	source map[string]interface{}
+}
+
+// failure represents an error returned for every request to an index
+type failure struct {
+	status int
+	err    error
+}
+
+// New creates an in memory elasticsearch without any indexes
+func New() *Elasticsearch {
+	return &Elasticsearch{
+		clusterStatus: clusterStatusGreen,
+		circuitState:  elasticsearch.CircuitClosed,
+		indexes:       make(map[string][]*document),
+		failures:      make(map[string]failure),
+		delays:        make(map[string]time.Duration),
+	}
+}
+
+// CreateIndex creates an empty index, an existing index is left unchanged
+func (es *Elasticsearch) CreateIndex(indexName string) {
+	es.mutex.Lock()
+	defer es.mutex.Unlock()
+
+	if _, ok := es.indexes[indexName]; !ok {
+		es.indexes[indexName] = []*document{}
+	}
+}
+
+// AddDocuments adds documents to an index, creating the index if it does not exist. The id of
+// each document is taken from its id field, or its alias for datasets, falling back to its
+// position in the index.
+func (es *Elasticsearch) AddDocuments(indexName string, docs ...interface{}) error {
+	es.mutex.Lock()
+	defer es.mutex.Unlock()
+
+	for _, doc := range docs {
+		raw, err := json.Marshal(doc)
+		if err != nil {
+			return err
+		}
+
+		source := make(map[string]interface{})
+		if err = json.Unmarshal(raw, &source); err != nil {
+			return err
+		}
+
+		id, ok := source["id"].(string)
+		if !ok || id == "" {
+			id, ok = source["alias"].(string)
+		}
+		if !ok || id == "" {
+			id = strconv.Itoa(len(es.indexes[indexName]))
+		}
+
+		es.indexes[indexName] = append(es.indexes[indexName], &document{
+			id:     id,
+			index:  indexName,
+			raw:    raw,
+			source: source,
+		})
+	}
+
+	return nil
+}
+
+// Fail causes every request to an index to return the status and error, as if elasticsearch
+// had failed to query the index
+func (es *Elasticsearch) Fail(indexName string, status int, err error) {
+	es.mutex.Lock()
+	defer es.mutex.Unlock()
+
+	es.failures[indexName] = failure{status: status, err: err}
+}
+
+// Recover stops requests to an index from failing
+func (es *Elasticsearch) Recover(indexName string) {
+	es.mutex.Lock()
+	defer es.mutex.Unlock()
+
+	delete(es.failures, indexName)
+}
+
+// Delay causes every request to an index to wait before responding, a request cancelled while
+// waiting returns the timeout error as if elasticsearch had not responded in time
+func (es *Elasticsearch) Delay(indexName string, delay time.Duration) {
+	es.mutex.Lock()
+	defer es.mutex.Unlock()
+
+	es.delays[indexName] = delay
+}
+
+// SetClusterStatus sets the status returned by the cluster health, which is green by default
+func (es *Elasticsearch) SetClusterStatus(status string) {
+	es.mutex.Lock()
+	defer es.mutex.Unlock()
+
+	es.clusterStatus = status
+}
+
+// SetCircuitBreakerState sets the state of the circuit breaker, which is closed by default
+func (es *Elasticsearch) SetCircuitBreakerState(state string) {
+	es.mutex.Lock()
+	defer es.mutex.Unlock()
+
+	es.circuitState = state
+}
+
+// CircuitBreakerState returns the state of the circuit breaker
+func (es *Elasticsearch) CircuitBreakerState() string {
+	es.mutex.RLock()
+	defer es.mutex.RUnlock()
+
+	return es.circuitState
+}
+
+// GetClusterHealth returns the cluster status, the circuit breaker error is returned while the
+// circuit breaker is open
+func (es *Elasticsearch) GetClusterHealth(ctx context.Context) (*models.ClusterHealth, int, error) {
+	es.mutex.RLock()
+	defer es.mutex.RUnlock()
+
+	if es.circuitState == elasticsearch.CircuitOpen {
+		return nil, 0, elasticsearch.ErrCircuitOpen
+	}
+
+	return &models.ClusterHealth{
+		ClusterName:   "memory",
+		Status:        es.clusterStatus,
+		NumberOfNodes: 1,
+	}, http.StatusOK, nil
+}
+
+// CountDocuments returns the number of documents in an index
+func (es *Elasticsearch) CountDocuments(ctx context.Context, indexName string) (int, int, error) {
+	es.mutex.RLock()
+	defer es.mutex.RUnlock()
+
+	if f, ok := es.failures[indexName]; ok {
+		return 0, f.status, f.err
+	}
+
+	index, ok := es.indexes[indexName]
+	if !ok {
+		return 0, http.StatusNotFound, elasticsearch.ErrorUnexpectedStatusCode
+	}
+
+	return len(index), http.StatusOK, nil
+}
+
+// IndexDocument stores the document with the id, replacing any document with the same id. The
+// index is created if it does not exist, as elasticsearch does.
+func (es *Elasticsearch) IndexDocument(ctx context.Context, indexName, id string, doc interface{}) (int, error) {
+	if err := es.wait(ctx, indexName); err != nil {
+		return 0, err
+	}
+
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return 0, err
+	}
+
+	source := make(map[string]interface{})
+	if err = json.Unmarshal(raw, &source); err != nil {
+		return 0, err
+	}
+
+	es.mutex.Lock()
+	defer es.mutex.Unlock()
+
+	if f, ok := es.failures[indexName]; ok {
+		return f.status, f.err
+	}
+
+	stored := &document{
+		id:     id,
+		index:  indexName,
+		raw:    raw,
+		source: source,
+	}
+
+	index := es.indexes[indexName]
+	for i, existing := range index {
+		if existing.id == id {
+			index[i] = stored
+			return http.StatusOK, nil
+		}
+	}
+
+	es.indexes[indexName] = append(index, stored)
+
+	return http.StatusCreated, nil
+}
+
+// DeleteDocument removes the document with the id, returning the document not found error if
+// the index does not hold the document
+func (es *Elasticsearch) DeleteDocument(ctx context.Context, indexName, id string) (int, error) {
+	if err := es.wait(ctx, indexName); err != nil {
+		return 0, err
+	}
+
+	es.mutex.Lock()
+	defer es.mutex.Unlock()
+
+	if f, ok := es.failures[indexName]; ok {
+		return f.status, f.err
+	}
+
+	index := es.indexes[indexName]
+	for i, existing := range index {
+		if existing.id == id {
+			es.indexes[indexName] = append(index[:i:i], index[i+1:]...)
+			return http.StatusOK, nil
+		}
+	}
+
+	return http.StatusNotFound, elasticsearch.ErrDocumentNotFound
+}
+
+// QuerySearchIndex searches one or more comma separated indexes
+func (es *Elasticsearch) QuerySearchIndex(ctx context.Context, indexName string, query interface{}) (*models.SearchResponse, int, error) {
+	hits, aggregations, total, status, err := es.search(ctx, indexName, query)
+	if err != nil {
+		return nil, status, err
+	}
+
+	response := &models.SearchResponse{
+		Hits: models.Hits{
+			Total:   total,
+			HitList: []models.HitList{},
+		},
+		Aggregations: aggregations,
+	}
+
+	for _, h := range hits {
+		hit := models.HitList{
+			Score: h.score,
+			Sort:  h.sort,
+		}
+
+		if err = json.Unmarshal(h.doc.raw, &hit.Source); err != nil {
+			return nil, status, errs.ErrUnmarshallingJSON
+		}
+
+		response.Hits.HitList = append(response.Hits.HitList, hit)
+	}
+
+	return response, status, nil
+}
+
+// GetAreaProfile returns the first area profile found by the query
+func (es *Elasticsearch) GetAreaProfile(ctx context.Context, indexName string, query interface{}) (*models.AreaProfile, int, error) {
+	hits, _, _, status, err := es.search(ctx, indexName, query)
+	if err != nil {
+		return nil, status, err
+	}
+
+	if len(hits) < 1 {
+		return nil, status, errs.ErrAreaProfileNotFound
+	}
+
+	areaProfile := &models.AreaProfile{}
+	if err = json.Unmarshal(hits[0].doc.raw, areaProfile); err != nil {
+		return nil, status, errs.ErrUnmarshallingJSON
+	}
+
+	return areaProfile, status, nil
+}
+
+// GetPostcodes searches an index for documents with the postcode
+func (es *Elasticsearch) GetPostcodes(ctx context.Context, indexName, postcode string) (*models.PostcodeResponse, int, error) {
+	query := models.PostcodeRequest{
+		Query: models.PostcodeQuery{
+			Distance: models.PostcodeTerm{
+				Postcode: postcode,
+			},
+		},
+	}
+
+	hits, _, _, status, err := es.search(ctx, indexName, query)
+	if err != nil {
+		return nil, status, err
+	}
+
+	response := &models.PostcodeResponse{}
+
+	for _, h := range hits {
+		hit := models.HitObj{}
+		if err = json.Unmarshal(h.doc.raw, &hit.Source); err != nil {
+			return nil, status, errs.ErrUnmarshallingJSON
+		}
+
+		response.Hits.Hits = append(response.Hits.Hits, hit)
+	}
+
+	return response, status, nil
+}
+
+// request represents the parts of a search request body that can be evaluated, size is
+// a pointer so that requests without a size return the default number of hits
+type request struct {
+	models.Body
+	Size    *int            `json:"size"`
+	Suggest json.RawMessage `json:"suggest,omitempty"`
+}
+
+// hit represents a document that matched a query
+type hit struct {
+	doc   *document
+	score float64
+	sort  []interface{}
+}
+
+// search evaluates the query against the documents in each of the comma separated indexes,
+// returning the page of hits along with aggregations and the total number of hits
+func (es *Elasticsearch) search(ctx context.Context, indexName string, query interface{}) ([]hit, models.Aggregations, int, int, error) {
+	if err := es.wait(ctx, indexName); err != nil {
+		return nil, models.Aggregations{}, 0, 0, err
+	}
+
+	b, err := json.Marshal(query)
+	if err != nil {
+		return nil, models.Aggregations{}, 0, 0, errs.ErrMarshallingQuery
+	}
+
+	var req request
+	if err = json.Unmarshal(b, &req); err != nil {
+		return nil, models.Aggregations{}, 0, http.StatusBadRequest, errs.ErrBadSearchQuery
+	}
+
+	es.mutex.RLock()
+	defer es.mutex.RUnlock()
+
+	var docs []*document
+	for _, name := range strings.Split(indexName, ",") {
+		if f, ok := es.failures[name]; ok {
+			return nil, models.Aggregations{}, 0, f.status, f.err
+		}
+
+		index, ok := es.indexes[name]
+		if !ok {
+			return nil, models.Aggregations{}, 0, http.StatusNotFound, errs.ErrIndexNotFound
+		}
+
+		docs = append(docs, index...)
+	}
+
+	// Suggestions are not supported, the request is treated as finding no suggestions
+	if len(req.Suggest) > 0 {
+		return nil, models.Aggregations{}, 0, http.StatusOK, nil
+	}
+
+	var hits []hit
+	for _, doc := range docs {
+		if ok, score := matchesQuery(doc, req.Query); ok {
+			hits = append(hits, hit{doc: doc, score: score})
+		}
+	}
+
+	total := len(hits)
+	aggregations := aggregate(hits, req.Aggregations)
+
+	sortHits(hits, req.Sort)
+
+	if len(req.SearchAfter) > 0 {
+		hits = searchAfter(hits, req.Sort, req.SearchAfter)
+	}
+
+	size := defaultSize
+	if req.Size != nil {
+		size = *req.Size
+	}
+
+	return page(hits, req.From, size), aggregations, total, http.StatusOK, nil
+}
+
+// wait waits for the longest delay of the comma separated indexes, returning the timeout error
+// if the context is done first
+func (es *Elasticsearch) wait(ctx context.Context, indexName string) error {
+	es.mutex.RLock()
+	var delay time.Duration
+	for _, name := range strings.Split(indexName, ",") {
+		if es.delays[name] > delay {
+			delay = es.delays[name]
+		}
+	}
+	es.mutex.RUnlock()
+
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return errs.ErrElasticsearchTimeout
+	case <-timer.C:
+		return nil
+	}
+}
+
+// page returns the hits between from and from+size
+func page(hits []hit, from, size int) []hit {
+	if from >= len(hits) {
+		return nil
+	}
+
+	end := from + size
+	if end > len(hits) {
+		end = len(hits)
+	}
+
+	return hits[from:end]
+}
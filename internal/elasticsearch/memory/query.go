@@ -0,0 +1,358 @@
+package memory
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/ONSdigital/dp-census-alpha-search-api/models"
+)
+
+// subfields are multi-fields in the mappings that are searched using the value of their parent field
+var subfields = map[string]bool{
+	"autocomplete": true,
+	"raw":          true,
+}
+
+// matchesQuery checks whether a document matches a query, a query without any clauses matches all documents
+func matchesQuery(doc *document, query models.Query) (bool, float64) {
+	if query.Bool == nil && len(query.Term) == 0 {
+		return true, 1
+	}
+
+	score := 0.0
+
+	if len(query.Term) > 0 {
+		if !matchesTerm(doc, query.Term) {
+			return false, 0
+		}
+		score++
+	}
+
+	if query.Bool != nil {
+		ok, s := matchesBool(doc, query.Bool)
+		if !ok {
+			return false, 0
+		}
+		score += s
+	}
+
+	return true, score
+}
+
+// matchesBool checks whether a document matches all must and filter clauses, none of the
+// must_not clauses and the minimum number of should clauses. Should clauses are optional if
+// there are any must or filter clauses, unless a minimum number has been set.
+func matchesBool(doc *document, b *models.Bool) (bool, float64) {
+	score := 0.0
+
+	for _, filter := range b.Filter {
+		if !matchesFilter(doc, filter) {
+			return false, 0
+		}
+	}
+
+	for _, must := range b.Must {
+		ok, s := matchesMatch(doc, must)
+		if !ok {
+			return false, 0
+		}
+		score += s
+	}
+
+	for _, mustNot := range b.MustNot {
+		if ok, _ := matchesMatch(doc, mustNot); ok {
+			return false, 0
+		}
+	}
+
+	minimumShouldMatch := b.MinimumShouldMatch
+	if minimumShouldMatch == 0 && len(b.Should) > 0 && len(b.Must) == 0 && len(b.Filter) == 0 {
+		minimumShouldMatch = 1
+	}
+
+	shouldMatched := 0
+	for _, should := range b.Should {
+		if ok, s := matchesMatch(doc, should); ok {
+			shouldMatched++
+			score += s
+		}
+	}
+
+	if shouldMatched < minimumShouldMatch {
+		return false, 0
+	}
+
+	return true, score
+}
+
+// matchesFilter checks whether a document matches every clause set on the filter
+func matchesFilter(doc *document, filter models.Filter) bool {
+	if len(filter.Term) > 0 && !matchesTerm(doc, filter.Term) {
+		return false
+	}
+
+	if len(filter.Terms) > 0 && !matchesTerms(doc, filter.Terms) {
+		return false
+	}
+
+	if filter.Nested != nil && !matchesNested(doc, filter.Nested) {
+		return false
+	}
+
+	if filter.Shape != nil && !matchesShape(doc, filter.Shape.Location) {
+		return false
+	}
+
+	return true
+}
+
+// matchesMatch checks whether a document matches every clause set on the match, the score is
+// the number of clauses that matched
+func matchesMatch(doc *document, match models.Match) (bool, float64) {
+	score := 0.0
+
+	if match.Bool != nil {
+		ok, s := matchesBool(doc, match.Bool)
+		if !ok {
+			return false, 0
+		}
+		score += s
+	}
+
+	for field, text := range match.Match {
+		if !matchesText(values(doc, field), text) {
+			return false, 0
+		}
+		score++
+	}
+
+	for field, text := range match.MatchPhrase {
+		if !matchesPhrase(values(doc, field), text) {
+			return false, 0
+		}
+		score++
+	}
+
+	if len(match.Term) > 0 {
+		if !matchesTerm(doc, match.Term) {
+			return false, 0
+		}
+		score++
+	}
+
+	if match.Nested != nil {
+		if !matchesNested(doc, match.Nested) {
+			return false, 0
+		}
+		score++
+	}
+
+	return true, score
+}
+
+// matchesNested checks whether a document matches the nested query
+func matchesNested(doc *document, nested *models.Nested) bool {
+	if nested.Query == nil {
+		return false
+	}
+
+	query := nested.Query
+
+	if query.Bool != nil {
+		if ok, _ := matchesBool(doc, query.Bool); !ok {
+			return false
+		}
+	}
+
+	for _, must := range query.Must {
+		if ok, _ := matchesMatch(doc, must); !ok {
+			return false
+		}
+	}
+
+	if len(query.Term) > 0 && !matchesTerm(doc, query.Term) {
+		return false
+	}
+
+	if len(query.Terms) > 0 && !matchesTerms(doc, query.Terms) {
+		return false
+	}
+
+	return query.Bool != nil || len(query.Must) > 0 || len(query.Term) > 0 || len(query.Terms) > 0
+}
+
+// matchesTerm checks whether a document has the exact value for every field
+func matchesTerm(doc *document, term map[string]string) bool {
+	for field, value := range term {
+		if !containsTerm(values(doc, field), value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchesTerms checks whether a document has one of the exact values for every field
+func matchesTerms(doc *document, terms map[string]interface{}) bool {
+	for field, list := range terms {
+		fieldValues := values(doc, field)
+
+		found := false
+		for _, value := range toStrings(list) {
+			if containsTerm(fieldValues, value) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// containsTerm checks for a value that is exactly the term, as elasticsearch does not analyse
+// the term so only keyword fields hold the exact value
+func containsTerm(fieldValues []string, term string) bool {
+	for _, value := range fieldValues {
+		if value == term {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchesText checks whether any token in the text is a token in one of the values
+func matchesText(fieldValues []string, text string) bool {
+	for _, value := range fieldValues {
+		tokens := make(map[string]bool)
+		for _, token := range tokenise(value) {
+			tokens[token] = true
+		}
+
+		for _, token := range tokenise(text) {
+			if tokens[token] {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// matchesPhrase checks whether all tokens in the text appear in order in one of the values
+func matchesPhrase(fieldValues []string, text string) bool {
+	phrase := tokenise(text)
+	if len(phrase) == 0 {
+		return false
+	}
+
+	for _, value := range fieldValues {
+		tokens := tokenise(value)
+
+		for i := 0; i+len(phrase) <= len(tokens); i++ {
+			if equal(tokens[i:i+len(phrase)], phrase) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// tokenise lower cases text and splits it on anything that is not a letter or a digit
+func tokenise(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// values returns every value of a field in a document, the field is a dot separated path
+// through objects and arrays of objects
+func values(doc *document, field string) []string {
+	switch field {
+	case "_index":
+		return []string{doc.index}
+	case "_id":
+		return []string{doc.id}
+	}
+
+	return toStrings(lookup(doc.source, strings.Split(field, ".")))
+}
+
+// lookup finds the value at the path within an object, the values of every object in an
+// array are returned as a single array
+func lookup(value interface{}, path []string) interface{} {
+	if len(path) == 0 {
+		return value
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		child, ok := v[path[0]]
+		if !ok {
+			return nil
+		}
+
+		// a subfield of the last field in the path is searched using the field value
+		if len(path) == 2 && subfields[path[1]] {
+			if _, isObject := child.(map[string]interface{}); !isObject {
+				return child
+			}
+		}
+
+		return lookup(child, path[1:])
+	case []interface{}:
+		var list []interface{}
+		for _, item := range v {
+			if found := lookup(item, path); found != nil {
+				list = append(list, found)
+			}
+		}
+
+		return list
+	default:
+		return nil
+	}
+}
+
+// toStrings flattens a value into a list of strings
+func toStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case float64:
+		return []string{strconv.FormatFloat(v, 'f', -1, 64)}
+	case bool:
+		return []string{strconv.FormatBool(v)}
+	case []string:
+		return v
+	case []interface{}:
+		var list []string
+		for _, item := range v {
+			list = append(list, toStrings(item)...)
+		}
+
+		return list
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,207 @@
+package memory
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/ONSdigital/dp-census-alpha-search-api/models"
+)
+
+// maximumBuckets is the number of buckets returned for each terms aggregation
+const maximumBuckets = 10
+
+// sortFields are the fields copied into sort_title, in order of preference
+var sortFields = []string{"title", "name"}
+
+// sortHits orders hits by each of the sorts in turn, setting the sort values of each hit. Script
+// and geo distance sorts are not supported and leave hits in the order they were added.
+func sortHits(hits []hit, sorts []models.Scores) {
+	for i := range hits {
+		hits[i].sort = sortValues(hits[i], sorts)
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		return compareSortValues(hits[i].sort, hits[j].sort, sorts) < 0
+	})
+}
+
+// searchAfter returns the sorted hits that come after the sort values
+func searchAfter(hits []hit, sorts []models.Scores, after []interface{}) []hit {
+	for i, h := range hits {
+		if compareSortValues(h.sort, after, sorts) > 0 {
+			return hits[i:]
+		}
+	}
+
+	return nil
+}
+
+func sortValues(h hit, sorts []models.Scores) []interface{} {
+	var list []interface{}
+
+	for _, s := range sorts {
+		switch {
+		case s.Score != nil:
+			list = append(list, h.score)
+		case s.SortTitle != nil:
+			list = append(list, sortTitle(h.doc))
+		case s.Index != nil:
+			list = append(list, h.doc.index)
+		case s.Alias != nil:
+			list = append(list, firstValue(h.doc, "alias"))
+		case s.ID != nil:
+			list = append(list, firstValue(h.doc, "id"))
+		default:
+			list = append(list, nil)
+		}
+	}
+
+	return list
+}
+
+// sortTitle returns the normalised value copied into sort_title by the mappings, or nil if
+// the document does not have a value to sort by
+func sortTitle(doc *document) interface{} {
+	for _, field := range sortFields {
+		if v := values(doc, field); len(v) > 0 {
+			return strings.ToLower(v[0])
+		}
+	}
+
+	return nil
+}
+
+// firstValue returns the first value of the field, or nil if the document does not have the field
+func firstValue(doc *document, field string) interface{} {
+	if v := values(doc, field); len(v) > 0 {
+		return v[0]
+	}
+
+	return nil
+}
+
+// compareSortValues compares two lists of sort values, missing values are always sorted last
+func compareSortValues(a, b []interface{}, sorts []models.Scores) int {
+	for i, s := range sorts {
+		if i >= len(a) || i >= len(b) {
+			break
+		}
+
+		switch {
+		case a[i] == nil && b[i] == nil:
+			continue
+		case a[i] == nil:
+			return 1
+		case b[i] == nil:
+			return -1
+		}
+
+		c := compareValues(a[i], b[i])
+		if c == 0 {
+			continue
+		}
+
+		if order(s) == "desc" {
+			return -c
+		}
+
+		return c
+	}
+
+	return 0
+}
+
+func compareValues(a, b interface{}) int {
+	switch av := a.(type) {
+	case float64:
+		bv, ok := b.(float64)
+		if !ok {
+			return 0
+		}
+
+		switch {
+		case av < bv:
+			return -1
+		case av > bv:
+			return 1
+		}
+	case string:
+		bv, ok := b.(string)
+		if !ok {
+			return 0
+		}
+
+		return strings.Compare(av, bv)
+	}
+
+	return 0
+}
+
+func order(s models.Scores) string {
+	switch {
+	case s.Score != nil:
+		return s.Score.Order
+	case s.SortTitle != nil:
+		return s.SortTitle.Order
+	case s.Index != nil:
+		return s.Index.Order
+	case s.Alias != nil:
+		return s.Alias.Order
+	case s.ID != nil:
+		return s.ID.Order
+	default:
+		return ""
+	}
+}
+
+// aggregate counts the hits for each value of the aggregated fields, aggregations are
+// only returned for the fields that were requested
+func aggregate(hits []hit, aggs models.Aggs) models.Aggregations {
+	return models.Aggregations{
+		Dimensions:  termsAggregation(hits, aggs.Dimensions),
+		Hierarchies: termsAggregation(hits, aggs.Hierarchies),
+		Topic1:      termsAggregation(hits, aggs.Topic1),
+		Topic2:      termsAggregation(hits, aggs.Topic2),
+		Topic3:      termsAggregation(hits, aggs.Topic3),
+	}
+}
+
+// termsAggregation returns the most common values of a field, ordered by the number of hits
+// with the value and then by the value
+func termsAggregation(hits []hit, agg models.Agg) *models.AggItems {
+	if agg.Terms.Field == "" {
+		return nil
+	}
+
+	counts := make(map[string]int)
+	for _, h := range hits {
+		seen := make(map[string]bool)
+		for _, value := range values(h.doc, agg.Terms.Field) {
+			if !seen[value] {
+				counts[value]++
+				seen[value] = true
+			}
+		}
+	}
+
+	items := &models.AggItems{
+		Items: []models.Bucket{},
+	}
+
+	for key, count := range counts {
+		items.Items = append(items.Items, models.Bucket{Key: key, DocCount: count})
+	}
+
+	sort.Slice(items.Items, func(i, j int) bool {
+		if items.Items[i].DocCount != items.Items[j].DocCount {
+			return items.Items[i].DocCount > items.Items[j].DocCount
+		}
+		return items.Items[i].Key < items.Items[j].Key
+	})
+
+	if len(items.Items) > maximumBuckets {
+		items.Items = items.Items[:maximumBuckets]
+	}
+
+	return items
+}
@@ -0,0 +1,101 @@
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"time"
+
+	errs "github.com/ONSdigital/dp-census-alpha-search-api/apierrors"
+)
+
+// Retries configures how calls to elasticsearch that fail with a transient error are retried,
+// the wait before each retry is a random duration up to the backoff, which doubles after each
+// retry up to the maximum backoff. Only idempotent read calls are retried.
+type Retries struct {
+	MaxRetries     int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// retryableStatuses are the status codes returned while elasticsearch is overloaded or a
+// proxy in front of it cannot reach the cluster
+var retryableStatuses = map[int]bool{
+	http.StatusTooManyRequests:    true,
+	http.StatusBadGateway:         true,
+	http.StatusServiceUnavailable: true,
+}
+
+// unavailableStatuses are the status codes that count as failures towards opening the circuit
+// breaker, as the cluster rather than the request is at fault
+var unavailableStatuses = map[int]bool{
+	http.StatusBadGateway:         true,
+	http.StatusServiceUnavailable: true,
+	http.StatusGatewayTimeout:     true,
+}
+
+// idempotent checks whether a call to elasticsearch can be safely repeated, searches are sent
+// as GET requests with a body so are idempotent
+func idempotent(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead
+}
+
+// retryable checks whether a failed call should be retried, calls are not retried once the
+// context is done
+func retryable(ctx context.Context, status int, err error) bool {
+	if err == nil || ctx.Err() != nil || err == ErrCircuitOpen {
+		return false
+	}
+
+	// a status of 0 is a connection error
+	return status == 0 || retryableStatuses[status]
+}
+
+// cancelled checks whether a call failed because the client cancelled it, which says nothing
+// about whether elasticsearch is available. Calls that ran past the deadline of the operation
+// are not cancelled by the client.
+func cancelled(ctx context.Context, err error) bool {
+	return err == errs.ErrElasticsearchTimeout && errors.Is(ctx.Err(), context.Canceled)
+}
+
+// unavailable checks whether a call failed because elasticsearch could not serve it, a call
+// that was not cancelled by the client but timed out was too slow to be served
+func unavailable(status int, err error) bool {
+	if err == nil {
+		return false
+	}
+
+	return err == errs.ErrElasticsearchTimeout || status == 0 || unavailableStatuses[status]
+}
+
+// backoff returns the random wait before the retry, retry is 0 for the first retry
+func (r Retries) backoff(retry int) time.Duration {
+	max := r.InitialBackoff
+	for i := 0; i < retry && max < r.MaxBackoff; i++ {
+		max *= 2
+	}
+
+	if max > r.MaxBackoff {
+		max = r.MaxBackoff
+	}
+
+	if max <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(max) + 1))
+}
+
+// wait waits before the retry, the timeout error is returned if the context is done first
+func (r Retries) wait(ctx context.Context, retry int) error {
+	timer := time.NewTimer(r.backoff(retry))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return errs.ErrElasticsearchTimeout
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,185 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	errs "github.com/ONSdigital/dp-census-alpha-search-api/apierrors"
+	"github.com/ONSdigital/log.go/log"
+)
+
+// ErrUnsupportedVersion is returned when the cluster runs a version of elasticsearch older
+// than 6.x, which does not accept the mappings or requests built by the api
+var ErrUnsupportedVersion = errors.New("unsupported elasticsearch version, 6.x or later is required")
+
+// DistributionOpenSearch is the distribution reported by OpenSearch clusters, elasticsearch
+// clusters do not report a distribution
+const DistributionOpenSearch = "opensearch"
+
+// docType is the single mapping type used for indices on 6.x clusters, types were removed
+// in elasticsearch 7
+const docType = "_doc"
+
+// Version represents the version of the elasticsearch or OpenSearch cluster
+type Version struct {
+	Number       string
+	Distribution string
+	Major        int
+}
+
+// Typeless checks whether the cluster no longer supports mapping types, which is every
+// OpenSearch cluster and elasticsearch from 7.x
+func (v Version) Typeless() bool {
+	return v.Distribution == DistributionOpenSearch || v.Major >= 7
+}
+
+func (v Version) String() string {
+	if v.Distribution == "" {
+		return "elasticsearch " + v.Number
+	}
+
+	return v.Distribution + " " + v.Number
+}
+
+// clusterInfo represents the response from the root of the cluster
+type clusterInfo struct {
+	Version struct {
+		Number       string `json:"number"`
+		Distribution string `json:"distribution"`
+	} `json:"version"`
+}
+
+// DetectVersion retrieves the version of the cluster, which decides the shape of requests
+// to create indices and add documents
+func (api *API) DetectVersion(ctx context.Context) (Version, int, error) {
+	responseBody, status, err := api.CallElastic(ctx, api.url, "GET", nil)
+	if err != nil {
+		return Version{}, status, err
+	}
+
+	info := &clusterInfo{}
+
+	if err = json.Unmarshal(responseBody, info); err != nil {
+		log.Event(ctx, "unable to unmarshal json body", log.ERROR, log.Error(err))
+		return Version{}, status, errs.ErrUnmarshallingJSON
+	}
+
+	version, err := parseVersion(info.Version.Number, info.Version.Distribution)
+	if err != nil {
+		log.Event(ctx, "unsupported elasticsearch version", log.ERROR, log.Error(err), log.Data{"version": info.Version.Number, "distribution": info.Version.Distribution})
+		return Version{}, status, err
+	}
+
+	api.mutex.Lock()
+	api.version = &version
+	api.mutex.Unlock()
+
+	return version, status, nil
+}
+
+// clusterVersion returns the version of the cluster, detecting it on first use so that
+// scripts do not need to detect the version before creating indices
+func (api *API) clusterVersion(ctx context.Context) (Version, error) {
+	api.mutex.Lock()
+	version := api.version
+	api.mutex.Unlock()
+
+	if version != nil {
+		return *version, nil
+	}
+
+	detected, _, err := api.DetectVersion(ctx)
+	return detected, err
+}
+
+// parseVersion parses the version number reported by the cluster, e.g. 7.10.2
+func parseVersion(number, distribution string) (Version, error) {
+	major, err := strconv.Atoi(strings.SplitN(number, ".", 2)[0])
+	if err != nil {
+		return Version{}, ErrUnsupportedVersion
+	}
+
+	version := Version{
+		Number:       number,
+		Distribution: strings.ToLower(distribution),
+		Major:        major,
+	}
+
+	if version.Distribution != DistributionOpenSearch && major < 6 {
+		return Version{}, ErrUnsupportedVersion
+	}
+
+	return version, nil
+}
+
+// indexMappings returns the settings and mappings of an index in the shape expected by the
+// cluster, mapping files are typeless so the mappings are nested under the single mapping
+// type for 6.x clusters
+func indexMappings(mappings []byte, version Version) ([]byte, error) {
+	if version.Typeless() {
+		return mappings, nil
+	}
+
+	index := map[string]json.RawMessage{}
+	if err := json.Unmarshal(mappings, &index); err != nil {
+		return nil, err
+	}
+
+	if typeless, ok := index["mappings"]; ok {
+		typed, err := json.Marshal(map[string]json.RawMessage{docType: typeless})
+		if err != nil {
+			return nil, err
+		}
+		index["mappings"] = typed
+	}
+
+	return json.Marshal(index)
+}
+
+// bulkAction returns the action line that indexes the next document of a bulk request, the
+// mapping type is only set for 6.x clusters and the id is generated by elasticsearch if empty
+func bulkAction(indexName, id string, version Version) ([]byte, error) {
+	action := map[string]string{"_index": indexName}
+	if id != "" {
+		action["_id"] = id
+	}
+	if !version.Typeless() {
+		action["_type"] = docType
+	}
+
+	b, err := json.Marshal(map[string]map[string]string{"index": action})
+	if err != nil {
+		return nil, err
+	}
+
+	return append(b, '\n'), nil
+}
+
+// bulkLine returns the action and source lines that index the document in a bulk request
+func bulkLine(indexName string, document interface{}, version Version) ([]byte, error) {
+	action, err := bulkAction(indexName, documentID(document), version)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+
+	line := append(action, b...)
+
+	return append(line, '\n'), nil
+}
+
+// documentID returns the id of a document that is stored with its own id
+func documentID(document interface{}) string {
+	if doc, ok := document.(Document); ok {
+		return doc.DocumentID()
+	}
+
+	return ""
+}
@@ -0,0 +1,173 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseVersion(t *testing.T) {
+	Convey("Given the versions reported by clusters", t, func() {
+		tests := []struct {
+			number       string
+			distribution string
+			major        int
+			typeless     bool
+		}{
+			{number: "6.8.23", major: 6, typeless: false},
+			{number: "7.10.2", major: 7, typeless: true},
+			{number: "8.11.0", major: 8, typeless: true},
+			{number: "1.3.7", distribution: "opensearch", major: 1, typeless: true},
+			{number: "2.11.0", distribution: "OpenSearch", major: 2, typeless: true},
+		}
+
+		Convey("When each version is parsed then the major version and whether types are supported is known", func() {
+			for _, test := range tests {
+				version, err := parseVersion(test.number, test.distribution)
+				So(err, ShouldBeNil)
+				So(version.Major, ShouldEqual, test.major)
+				So(version.Typeless(), ShouldEqual, test.typeless)
+			}
+		})
+	})
+
+	Convey("Given a cluster running elasticsearch 5.x", t, func() {
+		Convey("When the version is parsed then it is not supported", func() {
+			_, err := parseVersion("5.6.16", "")
+			So(err, ShouldEqual, ErrUnsupportedVersion)
+		})
+	})
+
+	Convey("Given a version number that cannot be parsed", t, func() {
+		Convey("When the version is parsed then it is not supported", func() {
+			_, err := parseVersion("", "")
+			So(err, ShouldEqual, ErrUnsupportedVersion)
+		})
+	})
+}
+
+func TestIndexMappings(t *testing.T) {
+	mappings := []byte(`{"settings":{"index":{"number_of_shards":1}},"mappings":{"properties":{"name":{"type":"text"}}}}`)
+
+	Convey("Given a typeless mappings file", t, func() {
+		Convey("When the mappings are adapted for a 7.x cluster then they are unchanged", func() {
+			body, err := indexMappings(mappings, Version{Number: "7.10.2", Major: 7})
+			So(err, ShouldBeNil)
+			So(string(body), ShouldEqual, string(mappings))
+		})
+
+		Convey("When the mappings are adapted for a 6.x cluster then the properties are nested under the mapping type", func() {
+			body, err := indexMappings(mappings, Version{Number: "6.8.23", Major: 6})
+			So(err, ShouldBeNil)
+			So(string(body), ShouldEqual, `{"mappings":{"_doc":{"properties":{"name":{"type":"text"}}}},"settings":{"index":{"number_of_shards":1}}}`)
+		})
+	})
+
+	Convey("Given each mappings file in the repository", t, func() {
+		for _, name := range AssetNames() {
+			b, err := Asset(name)
+			So(err, ShouldBeNil)
+
+			Convey("When "+name+" is read then it has no mapping type", func() {
+				index := struct {
+					Mappings map[string]json.RawMessage `json:"mappings"`
+				}{}
+				So(json.Unmarshal(b, &index), ShouldBeNil)
+				So(index.Mappings, ShouldContainKey, "properties")
+				So(index.Mappings, ShouldNotContainKey, "doc")
+			})
+		}
+	})
+}
+
+// newVersionServer creates a cluster that reports the version and records the body of each
+// request to create an index or add a single document in bulk
+func newVersionServer(root string) (*httptest.Server, func() []string) {
+	var (
+		mutex  sync.Mutex
+		bodies []string
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Write([]byte(root))
+			return
+		}
+
+		b, _ := ioutil.ReadAll(r.Body)
+		mutex.Lock()
+		bodies = append(bodies, string(b))
+		mutex.Unlock()
+
+		if r.URL.Path == "/_bulk" {
+			w.Write([]byte(`{"errors":false,"items":[{"index":{"status":201}}]}`))
+			return
+		}
+
+		w.Write([]byte(`{}`))
+	}))
+
+	return server, func() []string {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return append([]string(nil), bodies...)
+	}
+}
+
+func TestBulkRequestVersions(t *testing.T) {
+	ctx := context.Background()
+	documents := []interface{}{map[string]string{"id": "1"}}
+
+	Convey("Given a cluster running elasticsearch 6.x", t, func() {
+		server, bodies := newVersionServer(`{"version":{"number":"6.8.23"}}`)
+		defer server.Close()
+
+		api := newTestAPI(server.URL)
+
+		Convey("When documents are added in bulk without detecting the version first", func() {
+			_, _, err := api.BulkRequest(ctx, "datasets", documents)
+
+			Convey("Then the version is detected and each document has the mapping type", func() {
+				So(err, ShouldBeNil)
+				So(bodies(), ShouldResemble, []string{"{\"index\":{\"_index\":\"datasets\",\"_type\":\"_doc\"}}\n{\"id\":\"1\"}\n"})
+			})
+		})
+	})
+
+	Convey("Given a cluster running OpenSearch", t, func() {
+		server, bodies := newVersionServer(`{"version":{"distribution":"opensearch","number":"2.11.0"}}`)
+		defer server.Close()
+
+		api := newTestAPI(server.URL)
+
+		version, status, err := api.DetectVersion(ctx)
+		So(err, ShouldBeNil)
+		So(status, ShouldEqual, http.StatusOK)
+		So(version.String(), ShouldEqual, "opensearch 2.11.0")
+
+		Convey("When documents are added in bulk", func() {
+			_, _, err := api.BulkRequest(ctx, "datasets", documents)
+
+			Convey("Then the documents have no mapping type", func() {
+				So(err, ShouldBeNil)
+				So(bodies(), ShouldResemble, []string{"{\"index\":{\"_index\":\"datasets\"}}\n{\"id\":\"1\"}\n"})
+			})
+		})
+
+		Convey("When an index is created", func() {
+			_, err := api.CreateSearchIndex(ctx, "postcodes", "postcode-mappings.json")
+
+			Convey("Then the typeless mappings file is sent unchanged", func() {
+				mappings, _ := Asset("postcode-mappings.json")
+				So(err, ShouldBeNil)
+				So(bodies(), ShouldResemble, []string{string(mappings)})
+			})
+		})
+	})
+}
@@ -0,0 +1,88 @@
+// Package searchapi calls the admin endpoints of a running search api, so that the loaders can
+// tell the api once a new generation of an index has been published.
+package searchapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+
+	dphttp "github.com/ONSdigital/dp-net/http"
+	"github.com/ONSdigital/log.go/log"
+)
+
+// DefaultURL is the url of a search api running locally
+const DefaultURL = "http://localhost:10300"
+
+// AdminAuthTokenEnv is the environment variable the admin auth token is read from, the same
+// variable the api is configured with
+const AdminAuthTokenEnv = "ADMIN_AUTH_TOKEN"
+
+// ErrUnexpectedStatus is returned when the api responds with an unexpected status code
+var ErrUnexpectedStatus = errors.New("unexpected status code from search api")
+
+// Client calls the admin endpoints of the search api with the admin auth token
+type Client struct {
+	clienter dphttp.Clienter
+	url      string
+	token    string
+}
+
+// NewClient creates a client for the search api at the url
+func NewClient(clienter dphttp.Clienter, url, token string) *Client {
+	return &Client{
+		clienter: clienter,
+		url:      url,
+		token:    token,
+	}
+}
+
+// PurgeCache empties the cache of search responses held by the api, so that a newly published
+// index is searched straight away rather than once the cached responses expire. The cache is
+// not purged without an admin auth token, as the admin endpoints are disabled without one.
+func (c *Client) PurgeCache(ctx context.Context) error {
+	path := c.url + "/admin/cache"
+	logData := log.Data{"url": path}
+
+	if c.token == "" {
+		log.Event(ctx, "not purging search api cache as "+AdminAuthTokenEnv+" is not set, cached search responses are served until they expire", log.WARN, logData)
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, path, nil)
+	if err != nil {
+		log.Event(ctx, "failed to create request to purge search api cache", log.ERROR, log.Error(err), logData)
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+
+	resp, err := c.clienter.Do(ctx, req)
+	if err != nil {
+		log.Event(ctx, "failed to call search api to purge cache", log.ERROR, log.Error(err), logData)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		logData["status"] = resp.StatusCode
+		log.Event(ctx, "failed to purge search api cache", log.ERROR, log.Error(ErrUnexpectedStatus), logData)
+		return ErrUnexpectedStatus
+	}
+
+	log.Event(ctx, "purged search api cache", log.INFO, logData)
+
+	return nil
+}
+
+// PurgeAfterPublish empties the cache of the search api at the url once a loader has published
+// a new generation of the index behind the alias, as the cached search responses were built from
+// the previous index. The admin auth token is read from the environment.
+func PurgeAfterPublish(ctx context.Context, clienter dphttp.Clienter, url, alias string) error {
+	if err := NewClient(clienter, url, os.Getenv(AdminAuthTokenEnv)).PurgeCache(ctx); err != nil {
+		log.Event(ctx, "published index but failed to purge search api cache, cached search responses are served until they expire", log.ERROR, log.Error(err), log.Data{"alias": alias})
+		return err
+	}
+
+	return nil
+}
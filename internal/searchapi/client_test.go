@@ -0,0 +1,73 @@
+package searchapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	dphttp "github.com/ONSdigital/dp-net/http"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPurgeCache(t *testing.T) {
+	ctx := context.Background()
+
+	Convey("Given a search api with admin endpoints", t, func() {
+		var requests []*http.Request
+		status := http.StatusNoContent
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r)
+			w.WriteHeader(status)
+		}))
+		defer server.Close()
+
+		cli := dphttp.NewClient()
+		cli.SetMaxRetries(0)
+
+		Convey("When the cache is purged with an admin auth token", func() {
+			err := NewClient(cli, server.URL, "secret").PurgeCache(ctx)
+
+			Convey("Then the admin endpoint is called with the token", func() {
+				So(err, ShouldBeNil)
+				So(requests, ShouldHaveLength, 1)
+				So(requests[0].Method, ShouldEqual, http.MethodDelete)
+				So(requests[0].URL.Path, ShouldEqual, "/admin/cache")
+				So(requests[0].Header.Get("Authorization"), ShouldEqual, "Bearer secret")
+			})
+		})
+
+		Convey("When the api rejects the request to purge the cache", func() {
+			status = http.StatusUnauthorized
+			err := NewClient(cli, server.URL, "wrong").PurgeCache(ctx)
+
+			Convey("Then an error is returned", func() {
+				So(err, ShouldEqual, ErrUnexpectedStatus)
+			})
+		})
+
+		Convey("When an index is published with the admin auth token set in the environment", func() {
+			So(os.Setenv(AdminAuthTokenEnv, "secret"), ShouldBeNil)
+			defer os.Unsetenv(AdminAuthTokenEnv)
+
+			err := PurgeAfterPublish(ctx, cli, server.URL, "datasets")
+
+			Convey("Then the cache is purged with the token", func() {
+				So(err, ShouldBeNil)
+				So(requests, ShouldHaveLength, 1)
+				So(requests[0].Header.Get("Authorization"), ShouldEqual, "Bearer secret")
+			})
+		})
+
+		Convey("When the cache is purged without an admin auth token", func() {
+			err := NewClient(cli, server.URL, "").PurgeCache(ctx)
+
+			Convey("Then the api is not called", func() {
+				So(err, ShouldBeNil)
+				So(requests, ShouldBeEmpty)
+			})
+		})
+	})
+}
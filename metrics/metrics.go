@@ -0,0 +1,255 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "search_api"
+
+// A list of results of resolving a postcode in a search term to a location
+const (
+	PostcodeHit   = "hit"
+	PostcodeMiss  = "miss"
+	PostcodeError = "error"
+)
+
+// A list of results of looking up a response in the cache
+const (
+	CacheHit    = "hit"
+	CacheMiss   = "miss"
+	CacheBypass = "bypass"
+)
+
+// A list of reasons responses are removed from the cache
+const (
+	CacheExpired = "expired"
+	CacheFull    = "full"
+	CacheReload  = "reload"
+	CacheAdmin   = "admin"
+	CacheWrite   = "write"
+)
+
+// unknownRoute is the route label of requests that did not match a route
+const unknownRoute = "unknown"
+
+var (
+	requests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of http requests by route, method and status code.",
+		},
+		[]string{"route", "method", "status"},
+	)
+
+	requestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Duration of http requests by route, method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"route", "method", "status"},
+	)
+
+	elasticsearchDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "elasticsearch_request_duration_seconds",
+			Help:      "Duration of requests to elasticsearch by index, operation and status code, the status is 0 if elasticsearch could not be reached.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"index", "operation", "status"},
+	)
+
+	zeroResultSearches = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "zero_result_searches_total",
+			Help:      "Number of searches that did not find any results by route.",
+		},
+		[]string{"route"},
+	)
+
+	postcodeLookups = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "postcode_lookups_total",
+			Help:      "Number of postcodes in search terms looked up by result, either hit, miss or error.",
+		},
+		[]string{"result"},
+	)
+
+	elasticsearchRetries = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "elasticsearch_retries_total",
+			Help:      "Number of requests to elasticsearch retried after a transient failure by index and operation.",
+		},
+		[]string{"index", "operation"},
+	)
+
+	circuitRejections = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "elasticsearch_circuit_rejections_total",
+			Help:      "Number of requests to elasticsearch not made because the circuit breaker was open by index and operation.",
+		},
+		[]string{"index", "operation"},
+	)
+
+	circuitState = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "elasticsearch_circuit_state",
+			Help:      "State of the elasticsearch circuit breaker, 1 for the current state of closed, open or half-open.",
+		},
+		[]string{"state"},
+	)
+
+	cacheLookups = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_lookups_total",
+			Help:      "Number of responses looked up in the response cache by route and result, either hit, miss or bypass when the client sent Cache-Control: no-cache.",
+		},
+		[]string{"route", "result"},
+	)
+
+	cacheEvictions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_evictions_total",
+			Help:      "Number of responses removed from the response cache by reason, either expired or full.",
+		},
+		[]string{"reason"},
+	)
+
+	cachePurges = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_purges_total",
+			Help:      "Number of times every response was removed from the response cache by reason, either reload of the reference files, admin request or admin write of a document.",
+		},
+		[]string{"reason"},
+	)
+
+	cacheEntries = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "cache_entries",
+			Help:      "Number of responses in the response cache.",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(requests, requestDuration, elasticsearchDuration, zeroResultSearches, postcodeLookups,
+		elasticsearchRetries, circuitRejections, circuitState, cacheLookups, cacheEvictions, cachePurges, cacheEntries)
+}
+
+// Handler returns the handler that exposes the metrics in the prometheus text format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Middleware records the number and duration of requests by the route template they matched,
+// so that requests for different ids are recorded against the same route
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(recorder, r)
+
+		route := unknownRoute
+		if current := mux.CurrentRoute(r); current != nil {
+			if template, err := current.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+
+		status := strconv.Itoa(recorder.status)
+
+		requests.WithLabelValues(route, r.Method, status).Inc()
+		requestDuration.WithLabelValues(route, r.Method, status).Observe(time.Since(start).Seconds())
+	})
+}
+
+// statusRecorder captures the status code written to a response, only the first status
+// written is sent to the client
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if !s.wroteHeader {
+		s.status = status
+		s.wroteHeader = true
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	s.wroteHeader = true
+	return s.ResponseWriter.Write(b)
+}
+
+// ObserveElasticsearch records the duration of a request to elasticsearch
+func ObserveElasticsearch(index, operation string, status int, duration time.Duration) {
+	elasticsearchDuration.WithLabelValues(index, operation, strconv.Itoa(status)).Observe(duration.Seconds())
+}
+
+// ElasticsearchRetry records a request to elasticsearch that is retried
+func ElasticsearchRetry(index, operation string) {
+	elasticsearchRetries.WithLabelValues(index, operation).Inc()
+}
+
+// CircuitRejected records a request to elasticsearch that was not made as the circuit was open
+func CircuitRejected(index, operation string) {
+	circuitRejections.WithLabelValues(index, operation).Inc()
+}
+
+// SetCircuitState records the current state of the elasticsearch circuit breaker
+func SetCircuitState(state string) {
+	circuitState.Reset()
+	circuitState.WithLabelValues(state).Set(1)
+}
+
+// ZeroResultSearch records a search on a route that did not find any results
+func ZeroResultSearch(route string) {
+	zeroResultSearches.WithLabelValues(route).Inc()
+}
+
+// PostcodeLookup records the result of looking up a postcode found in a search term
+func PostcodeLookup(result string) {
+	postcodeLookups.WithLabelValues(result).Inc()
+}
+
+// CacheLookup records the result of looking up a response for a route in the cache
+func CacheLookup(route, result string) {
+	cacheLookups.WithLabelValues(route, result).Inc()
+}
+
+// CacheEvicted records a response removed from the cache
+func CacheEvicted(reason string) {
+	cacheEvictions.WithLabelValues(reason).Inc()
+}
+
+// CachePurged records every response being removed from the cache
+func CachePurged(reason string) {
+	cachePurges.WithLabelValues(reason).Inc()
+}
+
+// SetCacheEntries records the number of responses in the cache
+func SetCacheEntries(entries int) {
+	cacheEntries.Set(float64(entries))
+}
@@ -0,0 +1,25 @@
+package models
+
+// AliasRequest represents a list of alias changes that elasticsearch applies atomically
+type AliasRequest struct {
+	Actions []AliasAction `json:"actions"`
+}
+
+// AliasAction represents a single alias change, only one of the actions is set
+type AliasAction struct {
+	Add         *AliasIndex `json:"add,omitempty"`
+	Remove      *AliasIndex `json:"remove,omitempty"`
+	RemoveIndex *AliasIndex `json:"remove_index,omitempty"`
+}
+
+// AliasIndex represents the index and alias of an alias change, the alias is not set when
+// removing an index
+type AliasIndex struct {
+	Index string `json:"index"`
+	Alias string `json:"alias,omitempty"`
+}
+
+// IndexSummary represents an index in the list of indices returned by the cat api
+type IndexSummary struct {
+	Index string `json:"index"`
+}
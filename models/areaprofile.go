@@ -1,5 +1,7 @@
 package models
 
+import "encoding/json"
+
 // AreaProfile represents the data structure for an area profile page
 type AreaProfile struct {
 	ID             string         `json:"id"`
@@ -64,6 +66,27 @@ type AHits struct {
 	HitList []AHitList `json:"hits"`
 }
 
+// UnmarshalJSON decodes the hits of an area profile search, see Hits for the shapes of total
+func (h *AHits) UnmarshalJSON(b []byte) error {
+	type hits AHits
+	aux := struct {
+		*hits
+		Total json.RawMessage `json:"total"`
+	}{hits: (*hits)(h)}
+
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+
+	total, err := totalHits(aux.Total)
+	if err != nil {
+		return err
+	}
+
+	h.Total = total
+	return nil
+}
+
 type AHitList struct {
 	Score   float64     `json:"_score"`
 	Source  AreaProfile `json:"_source"`
@@ -0,0 +1,15 @@
+package models
+
+// AutocompleteResults represents a short mixed list of suggestions for a partially typed search term
+type AutocompleteResults struct {
+	Count int                  `json:"count"`
+	Items []AutocompleteResult `json:"items"`
+}
+
+// AutocompleteResult represents a single suggestion, either a dataset title, area name or postcode
+type AutocompleteResult struct {
+	DocType string `json:"doc_type"`
+	Text    string `json:"text"`
+	ID      string `json:"id,omitempty"`
+	Links   *Links `json:"links,omitempty"`
+}
@@ -0,0 +1,25 @@
+package models
+
+// BulkResponse represents the response to a bulk request, errors is true if any document
+// failed even though the request succeeded
+type BulkResponse struct {
+	Errors bool                  `json:"errors"`
+	Items  []map[string]BulkItem `json:"items"`
+}
+
+// BulkItem represents the outcome of a single action of a bulk request, keyed by the action
+// in the response, e.g. index
+type BulkItem struct {
+	Index  string     `json:"_index"`
+	ID     string     `json:"_id"`
+	Status int        `json:"status"`
+	Error  *BulkError `json:"error,omitempty"`
+}
+
+// BulkError represents the reason elasticsearch failed to index a document, caused by holds
+// the underlying error, e.g. the invalid polygon behind a mapper parsing exception
+type BulkError struct {
+	Type     string     `json:"type"`
+	Reason   string     `json:"reason"`
+	CausedBy *BulkError `json:"caused_by,omitempty"`
+}
@@ -0,0 +1,123 @@
+package models
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+
+	errs "github.com/ONSdigital/dp-census-alpha-search-api/apierrors"
+)
+
+// StartCursor is the cursor value used to request the first page of results
+const StartCursor = "*"
+
+// Cursor represents the position reached in each list of search results when paging
+// with a cursor, the encoded cursor is opaque to api users. The sort is the order the
+// results are paged through in, as the position is made up of the sort values.
+type Cursor struct {
+	Sort        string                   `json:"sort,omitempty"`
+	SearchAfter map[string][]interface{} `json:"search_after,omitempty"`
+	Done        map[string]bool          `json:"done,omitempty"`
+}
+
+// NewCursor creates a cursor positioned at the start of every list of search results
+func NewCursor() *Cursor {
+	return &Cursor{
+		SearchAfter: make(map[string][]interface{}),
+		Done:        make(map[string]bool),
+	}
+}
+
+// ParseCursor decodes a cursor from a cursor query parameter
+func ParseCursor(cursor string) (*Cursor, error) {
+	if cursor == StartCursor {
+		return NewCursor(), nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errs.ErrInvalidCursor
+	}
+
+	c := NewCursor()
+
+	// Use numbers to avoid losing precision on sort values such as dates
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+
+	if err = decoder.Decode(c); err != nil || c.Sort == "" {
+		return nil, errs.ErrInvalidCursor
+	}
+
+	if c.SearchAfter == nil {
+		c.SearchAfter = make(map[string][]interface{})
+	}
+
+	if c.Done == nil {
+		c.Done = make(map[string]bool)
+	}
+
+	return c, nil
+}
+
+// ForSort checks the cursor can page through results in the sort order, a cursor can only be
+// used with the sort it was issued for while the start cursor can be used with any sort
+func (c *Cursor) ForSort(sort string) error {
+	if c.Sort != "" && c.Sort != sort {
+		return errs.ErrInvalidCursor
+	}
+
+	c.Sort = sort
+
+	return nil
+}
+
+// Encode creates an opaque cursor to return to api users
+func (c *Cursor) Encode() (string, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// IsDone checks whether all results in a list of search results have been paged through
+func (c *Cursor) IsDone(list string) bool {
+	return c.Done[list]
+}
+
+// Advance records the position reached in a list of search results for the next page,
+// a list is complete once a page returns fewer items than the limit
+func (c *Cursor) Advance(list string, previous *Cursor, results SearchResults, limit int) {
+	if previous.IsDone(list) || results.Count < limit || len(results.SearchAfter) == 0 {
+		c.Done[list] = true
+		return
+	}
+
+	c.SearchAfter[list] = results.SearchAfter
+}
+
+// Retain keeps the position reached in a list of search results from the previous cursor,
+// so that a page which failed to be retrieved is requested again with the next cursor
+func (c *Cursor) Retain(list string, previous *Cursor) {
+	if previous.IsDone(list) {
+		c.Done[list] = true
+		return
+	}
+
+	if searchAfter, ok := previous.SearchAfter[list]; ok {
+		c.SearchAfter[list] = searchAfter
+	}
+}
+
+// IsComplete checks whether all lists of search results have been paged through
+func (c *Cursor) IsComplete(lists ...string) bool {
+	for _, list := range lists {
+		if !c.Done[list] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -1,8 +1,15 @@
 package models
 
+import (
+	"encoding/json"
+
+	errs "github.com/ONSdigital/dp-census-alpha-search-api/apierrors"
+)
+
 type SearchResponse struct {
-	Hits         Hits         `json:"hits"`
-	Aggregations Aggregations `json:"aggregations,omitempty"`
+	Hits         Hits                      `json:"hits"`
+	Aggregations Aggregations              `json:"aggregations,omitempty"`
+	Suggest      map[string][]SuggestEntry `json:"suggest,omitempty"`
 }
 
 type Hits struct {
@@ -10,10 +17,54 @@ type Hits struct {
 	HitList []HitList `json:"hits"`
 }
 
+// UnmarshalJSON decodes the hits of a search response, the total is a number on 6.x clusters
+// and an object with the value on elasticsearch 7+ and OpenSearch
+func (h *Hits) UnmarshalJSON(b []byte) error {
+	type hits Hits
+	aux := struct {
+		*hits
+		Total json.RawMessage `json:"total"`
+	}{hits: (*hits)(h)}
+
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+
+	total, err := totalHits(aux.Total)
+	if err != nil {
+		return err
+	}
+
+	h.Total = total
+	return nil
+}
+
+// totalHits decodes the total number of hits from either a number or an object such as
+// {"value": 10000, "relation": "gte"}
+func totalHits(b json.RawMessage) (int, error) {
+	if len(b) == 0 || string(b) == "null" {
+		return 0, nil
+	}
+
+	if b[0] != '{' {
+		var total int
+		err := json.Unmarshal(b, &total)
+		return total, err
+	}
+
+	var total struct {
+		Value int `json:"value"`
+	}
+	err := json.Unmarshal(b, &total)
+
+	return total.Value, err
+}
+
 type HitList struct {
-	Score   float64      `json:"_score"`
-	Source  SearchResult `json:"_source"`
-	Matches Matches      `json:"highlight,omitempty"`
+	Score   float64       `json:"_score"`
+	Source  SearchResult  `json:"_source"`
+	Matches Matches       `json:"highlight,omitempty"`
+	Sort    []interface{} `json:"sort,omitempty"`
 }
 
 type DimensionHits struct {
@@ -29,10 +80,21 @@ type AllSearchResults struct {
 	Counts       Counts        `json:"counts"`
 	Limit        int           `json:"limit"`
 	Offset       int           `json:"offset"`
+	NextCursor   string        `json:"next_cursor,omitempty"`
 	All          SearchResults `json:"all"`
 	Datasets     SearchResults `json:"datasets"`
 	AreaProfiles SearchResults `json:"area_profiles"`
 	Publications SearchResults `json:"publications"`
+	Suggestions  *Suggestions  `json:"suggestions,omitempty"`
+	Partial      bool          `json:"partial,omitempty"`
+	Errors       []ListError   `json:"errors,omitempty"`
+}
+
+// ListError represents a list of search results that could not be retrieved, the
+// list is returned empty alongside the lists that were retrieved successfully
+type ListError struct {
+	List string `json:"list"`
+	*errs.Error
 }
 
 // Counts represent a list of counts for each data type
@@ -49,6 +111,8 @@ type SearchResults struct {
 	Count        int            `json:"count"`
 	Items        []SearchResult `json:"items"`
 	TotalCount   int            `json:"total_count"`
+	// SearchAfter holds the sort values of the last item to page on from when using a cursor
+	SearchAfter []interface{} `json:"-"`
 }
 
 // DatasetSearchResults represents a structure for a list of returned dataset resources
@@ -56,6 +120,7 @@ type DatasetSearchResults struct {
 	Count      int            `json:"count"`
 	Limit      int            `json:"limit"`
 	Offset     int            `json:"offset"`
+	NextCursor string         `json:"next_cursor,omitempty"`
 	TotalCount int            `json:"total_count"`
 	Items      []SearchResult `json:"items"`
 }
@@ -76,6 +141,14 @@ type SearchResult struct {
 	Code      string `json:"code,omitempty"`
 	Hierarchy string `json:"hierarchy,omitempty"`
 	Name      string `json:"name,omitempty"`
+	// postcode data
+	Postcode    string `json:"postcode,omitempty"`
+	PostcodeRaw string `json:"postcode_raw,omitempty"`
+	// publication data
+	Keywords        []string `json:"keywords,omitempty"`
+	PublicationType string   `json:"publication_type,omitempty"`
+	ReleaseDate     string   `json:"release_date,omitempty"`
+	Summary         string   `json:"summary,omitempty"`
 	// generic data
 	Links   Links      `json:"links,omitempty"`
 	Matches NewMatches `json:"matches,omitempty"`
@@ -102,6 +175,9 @@ type Matches struct {
 	Code      []string `json:"code,omitempty"`
 	Hierarchy []string `json:"hierarchy,omitempty"`
 	Name      []string `json:"name,omitempty"`
+	// Publication Matches
+	Keywords []string `json:"keywords.raw,omitempty"`
+	Summary  []string `json:"summary.raw,omitempty"`
 }
 
 // NewMatches represents a list of members and their arrays of character offsets that matched the search term
@@ -119,6 +195,9 @@ type NewMatches struct {
 	Code      []string `json:"code,omitempty"`
 	Hierarchy []string `json:"hierarchy,omitempty"`
 	Name      []string `json:"name,omitempty"`
+	// Publication Matches
+	Keywords []string `json:"keywords,omitempty"`
+	Summary  []string `json:"summary,omitempty"`
 }
 
 // Aggregations is a list of aggregated fields with the number of
@@ -0,0 +1,45 @@
+package models_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ONSdigital/dp-census-alpha-search-api/models"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHitsTotal(t *testing.T) {
+	Convey("Given search responses from clusters of each version", t, func() {
+		tests := []struct {
+			name     string
+			response string
+			total    int
+		}{
+			{name: "6.x", response: `{"hits":{"total":42,"hits":[{"_score":1}]}}`, total: 42},
+			{name: "7.x", response: `{"hits":{"total":{"value":42,"relation":"eq"},"hits":[{"_score":1}]}}`, total: 42},
+			{name: "7.x without tracking total hits", response: `{"hits":{"total":{"value":10000,"relation":"gte"},"hits":[{"_score":1}]}}`, total: 10000},
+			{name: "a response without a total", response: `{"hits":{"hits":[{"_score":1}]}}`, total: 0},
+		}
+
+		for _, test := range tests {
+			Convey("When the search response from "+test.name+" is decoded then the total and hits are set", func() {
+				response := &models.SearchResponse{}
+				So(json.Unmarshal([]byte(test.response), response), ShouldBeNil)
+				So(response.Hits.Total, ShouldEqual, test.total)
+				So(response.Hits.HitList, ShouldHaveLength, 1)
+
+				areaProfiles := &models.AreaProfileResponse{}
+				So(json.Unmarshal([]byte(test.response), areaProfiles), ShouldBeNil)
+				So(areaProfiles.Hits.Total, ShouldEqual, test.total)
+				So(areaProfiles.Hits.HitList, ShouldHaveLength, 1)
+			})
+		}
+	})
+
+	Convey("Given a search response with an invalid total", t, func() {
+		Convey("When the response is decoded then an error is returned", func() {
+			response := &models.SearchResponse{}
+			So(json.Unmarshal([]byte(`{"hits":{"total":"many"}}`), response), ShouldNotBeNil)
+		})
+	})
+}
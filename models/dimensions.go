@@ -11,3 +11,13 @@ type DimensionObject struct {
 	Label string `json:"label,omitempty"`
 	Name  string `json:"name,omitempty"`
 }
+
+// Names returns the name of every dimension
+func (d DimensionsDoc) Names() []string {
+	names := make([]string, 0, len(d.Dimensions))
+	for _, dimension := range d.Dimensions {
+		names = append(names, dimension.Name)
+	}
+
+	return names
+}
@@ -2,10 +2,11 @@ package models
 
 import (
 	"context"
-	"errors"
+	"net/http"
 	"strconv"
 	"strings"
 
+	errs "github.com/ONSdigital/dp-census-alpha-search-api/apierrors"
 	"github.com/ONSdigital/log.go/log"
 )
 
@@ -33,14 +34,12 @@ var miles = map[string]bool{
 
 // ErrorInvalidDistance - return error
 func ErrorInvalidDistance(m string) error {
-	err := errors.New("invalid distance value: " + m + ". Should contain a number and unit of distance separated by a comma e.g. 40,km")
-	return err
+	return errs.New(errs.CodeInvalidDistance, http.StatusBadRequest, "invalid distance value: "+m+". Should contain a number and unit of distance separated by a comma e.g. 40,km", "distance")
 }
 
 // ErrorInvalidRelation - return error
 func ErrorInvalidRelation(m string) error {
-	err := errors.New("invalid relation value: " + m + ". Should contain one of the following: intersects or within")
-	return err
+	return errs.New(errs.CodeInvalidRelation, http.StatusBadRequest, "invalid relation value: "+m+". Should contain one of the following: intersects or within", "relation")
 }
 
 // ValidateDistance ...
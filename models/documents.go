@@ -0,0 +1,228 @@
+package models
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	errs "github.com/ONSdigital/dp-census-alpha-search-api/apierrors"
+)
+
+// A list of document types stored in the doc_type field of each document
+const (
+	DatasetDocType     = "dataset"
+	AreaProfileDocType = "area_profile"
+)
+
+// shapeTypes are the geojson geometries accepted as the location of a document, elasticsearch
+// does not mind the case of the type
+var shapeTypes = map[string]bool{
+	"polygon":      true,
+	"multipolygon": true,
+}
+
+// DatasetDocument represents a dataset stored in the dataset index, it is identified by its alias
+type DatasetDocument struct {
+	Alias       string       `json:"alias"`
+	Description string       `json:"description,omitempty"`
+	Dimensions  []Dimension  `json:"dimensions,omitempty"`
+	DocType     string       `json:"doc_type"`
+	Location    *GeoLocation `json:"location,omitempty"`
+	Links       Links        `json:"links"`
+	Title       string       `json:"title"`
+	Topic1      string       `json:"topic1,omitempty"`
+	Topic2      string       `json:"topic2,omitempty"`
+	Topic3      string       `json:"topic3,omitempty"`
+}
+
+// AreaProfileDocument represents an area profile stored in the area profile index, it is
+// identified by its id
+type AreaProfileDocument struct {
+	ID             string           `json:"id"`
+	Name           string           `json:"name"`
+	Centroid       *CoordinatePoint `json:"centroid,omitempty"`
+	Code           string           `json:"code"`
+	Datasets       Datasets         `json:"datasets"`
+	DocType        string           `json:"doc_type"`
+	Hierarchy      string           `json:"hierarchy"`
+	Links          Links            `json:"links"`
+	Location       GeoLocation      `json:"location"`
+	Statistics     []Statistic      `json:"statistics"`
+	Visualisations Visualisations   `json:"visualisation"`
+}
+
+// ErrorMissingField - return error
+func ErrorMissingField(field string) error {
+	return errs.New(errs.CodeMissingField, http.StatusBadRequest, "missing required field: "+field, field)
+}
+
+// ErrorInvalidField - return error
+func ErrorInvalidField(field, reason string) error {
+	return errs.New(errs.CodeInvalidField, http.StatusBadRequest, "invalid "+field+", "+reason, field)
+}
+
+// Validate checks the dataset can be stored under the alias, setting the alias and document
+// type if they are missing. Topics must exist at their level of the taxonomy.
+func (d *DatasetDocument) Validate(alias string, levels TopicLevels) error {
+	if d.Alias == "" {
+		d.Alias = alias
+	}
+
+	if d.Alias != alias {
+		return ErrorInvalidField("alias", "should match the alias in the url")
+	}
+
+	if d.DocType == "" {
+		d.DocType = DatasetDocType
+	}
+
+	if d.DocType != DatasetDocType {
+		return ErrorInvalidField("doc_type", "should be "+DatasetDocType)
+	}
+
+	if d.Title == "" {
+		return ErrorMissingField("title")
+	}
+
+	for i, dimension := range d.Dimensions {
+		if dimension.Name == "" || dimension.Label == "" {
+			return ErrorInvalidField("dimensions", "dimension "+strconv.Itoa(i)+" should have a name and label")
+		}
+	}
+
+	for level, topic := range []string{d.Topic1, d.Topic2, d.Topic3} {
+		field := "topic" + strconv.Itoa(level+1)
+		if topic != "" && levels[topic] != level+1 {
+			return errs.WithParameter(ErrorInvalidTopics([]string{topic}), field)
+		}
+	}
+
+	if d.Location != nil {
+		if err := validateShape("location", *d.Location); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Validate checks the area profile can be stored under the id, setting the id and document
+// type if they are missing. The hierarchy must exist in the list of geography hierarchies, and
+// the centroid is calculated from the location if it is missing.
+func (a *AreaProfileDocument) Validate(id string, hierarchies GeoHierarchiesDoc) error {
+	if a.ID == "" {
+		a.ID = id
+	}
+
+	if a.ID != id {
+		return ErrorInvalidField("id", "should match the id in the url")
+	}
+
+	if a.DocType == "" {
+		a.DocType = AreaProfileDocType
+	}
+
+	if a.DocType != AreaProfileDocType {
+		return ErrorInvalidField("doc_type", "should be "+AreaProfileDocType)
+	}
+
+	if a.Name == "" {
+		return ErrorMissingField("name")
+	}
+
+	if a.Code == "" {
+		return ErrorMissingField("code")
+	}
+
+	if a.Hierarchy == "" {
+		return ErrorMissingField("hierarchy")
+	}
+
+	var names []string
+	valid := false
+	for _, item := range hierarchies.Items {
+		names = append(names, item.Hierarchy)
+		if item.Hierarchy == a.Hierarchy {
+			valid = true
+		}
+	}
+
+	if !valid {
+		return errs.WithParameter(ErrorInvalidHierarchy(a.Hierarchy, CloseMatches(a.Hierarchy, names)), "hierarchy")
+	}
+
+	if err := validateShape("location", a.Location); err != nil {
+		return err
+	}
+
+	if a.Centroid == nil {
+		a.Centroid = centroid(a.Location.Coordinates)
+	}
+
+	if a.Centroid == nil {
+		return ErrorInvalidField("location", "should contain coordinates")
+	}
+
+	if a.Centroid.Latitude < -90 || a.Centroid.Latitude > 90 || a.Centroid.Longitude < -180 || a.Centroid.Longitude > 180 {
+		return ErrorInvalidField("centroid", "lat should be between -90 and 90 and lon between -180 and 180")
+	}
+
+	return nil
+}
+
+func validateShape(field string, location GeoLocation) error {
+	if location.Type == "" {
+		return ErrorMissingField(field + ".type")
+	}
+
+	if !shapeTypes[strings.ToLower(location.Type)] {
+		return ErrorInvalidField(field+".type", "should be Polygon or MultiPolygon")
+	}
+
+	if location.Coordinates == nil {
+		return ErrorMissingField(field + ".coordinates")
+	}
+
+	return nil
+}
+
+// centroid calculates the centre of the bounding box around the decoded geojson coordinates of
+// a polygon or multipolygon, the same centre calculated by the geojson scripts
+func centroid(coordinates interface{}) *CoordinatePoint {
+	minLon, minLat := math.Inf(1), math.Inf(1)
+	maxLon, maxLat := math.Inf(-1), math.Inf(-1)
+
+	var walk func(value interface{})
+	walk = func(value interface{}) {
+		values, ok := value.([]interface{})
+		if !ok {
+			return
+		}
+
+		// a position is [longitude, latitude]
+		if len(values) >= 2 {
+			lon, lonOK := values[0].(float64)
+			lat, latOK := values[1].(float64)
+			if lonOK && latOK {
+				minLon, maxLon = math.Min(minLon, lon), math.Max(maxLon, lon)
+				minLat, maxLat = math.Min(minLat, lat), math.Max(maxLat, lat)
+				return
+			}
+		}
+
+		for _, v := range values {
+			walk(v)
+		}
+	}
+	walk(coordinates)
+
+	if math.IsInf(minLon, 1) {
+		return nil
+	}
+
+	return &CoordinatePoint{
+		Latitude:  (minLat + maxLat) / 2,
+		Longitude: (minLon + maxLon) / 2,
+	}
+}
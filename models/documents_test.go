@@ -0,0 +1,124 @@
+package models_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	errs "github.com/ONSdigital/dp-census-alpha-search-api/apierrors"
+	"github.com/ONSdigital/dp-census-alpha-search-api/models"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var testLevels = models.TopicLevels{
+	"economy":                      1,
+	"peoplepopulationandcommunity": 1,
+	"birthsdeathsandmarriages":     2,
+}
+
+var testHierarchies = models.GeoHierarchiesDoc{
+	Items: []models.GeographyObject{
+		{Hierarchy: "Countries"},
+		{Hierarchy: "Major Towns and Cities"},
+	},
+}
+
+func TestValidateDataset(t *testing.T) {
+	Convey("Given a dataset without an alias or document type", t, func() {
+		dataset := &models.DatasetDocument{
+			Title:  "Deaths registered by sex",
+			Topic1: "peoplepopulationandcommunity",
+			Topic2: "birthsdeathsandmarriages",
+		}
+
+		Convey("When the dataset is validated then the alias and document type are set", func() {
+			So(dataset.Validate("deaths-by-sex", testLevels), ShouldBeNil)
+			So(dataset.Alias, ShouldEqual, "deaths-by-sex")
+			So(dataset.DocType, ShouldEqual, models.DatasetDocType)
+		})
+	})
+
+	Convey("Given invalid datasets", t, func() {
+		tests := []struct {
+			name      string
+			dataset   models.DatasetDocument
+			parameter string
+		}{
+			{name: "a different alias to the url", dataset: models.DatasetDocument{Alias: "house-prices", Title: "Deaths"}, parameter: "alias"},
+			{name: "another document type", dataset: models.DatasetDocument{DocType: "area_profile", Title: "Deaths"}, parameter: "doc_type"},
+			{name: "no title", dataset: models.DatasetDocument{}, parameter: "title"},
+			{name: "a dimension without a label", dataset: models.DatasetDocument{Title: "Deaths", Dimensions: []models.Dimension{{Name: "sex"}}}, parameter: "dimensions"},
+			{name: "a topic at the wrong level", dataset: models.DatasetDocument{Title: "Deaths", Topic1: "birthsdeathsandmarriages"}, parameter: "topic1"},
+			{name: "a topic not in the taxonomy", dataset: models.DatasetDocument{Title: "Deaths", Topic2: "births"}, parameter: "topic2"},
+			{name: "a location that is not a polygon", dataset: models.DatasetDocument{Title: "Deaths", Location: &models.GeoLocation{Type: "Point", Coordinates: []interface{}{0.0, 0.0}}}, parameter: "location.type"},
+		}
+
+		for _, test := range tests {
+			Convey("When a dataset with "+test.name+" is validated then a bad request error is returned for the field", func() {
+				err := test.dataset.Validate("deaths-by-sex", testLevels)
+				So(err, ShouldNotBeNil)
+
+				apiErr, ok := err.(*errs.Error)
+				So(ok, ShouldBeTrue)
+				So(apiErr.Status, ShouldEqual, 400)
+				So(apiErr.Parameter, ShouldEqual, test.parameter)
+			})
+		}
+	})
+}
+
+func TestValidateAreaProfile(t *testing.T) {
+	Convey("Given an area profile without a centroid", t, func() {
+		areaProfile := &models.AreaProfileDocument{}
+		So(json.Unmarshal([]byte(`{
+			"name": "Cardiff",
+			"code": "W06000015",
+			"hierarchy": "Major Towns and Cities",
+			"location": {"type": "MultiPolygon", "coordinates": [[[[-3.3, 51.4], [-3.1, 51.4], [-3.1, 51.6], [-3.3, 51.4]]]]}
+		}`), areaProfile), ShouldBeNil)
+
+		Convey("When the area profile is validated", func() {
+			So(areaProfile.Validate("W06000015", testHierarchies), ShouldBeNil)
+
+			Convey("Then the id and document type are set and the centroid is the centre of the location", func() {
+				So(areaProfile.ID, ShouldEqual, "W06000015")
+				So(areaProfile.DocType, ShouldEqual, models.AreaProfileDocType)
+				So(areaProfile.Centroid.Latitude, ShouldAlmostEqual, 51.5)
+				So(areaProfile.Centroid.Longitude, ShouldAlmostEqual, -3.2)
+			})
+		})
+	})
+
+	Convey("Given an area profile in a hierarchy that does not exist", t, func() {
+		areaProfile := &models.AreaProfileDocument{
+			Name:      "Cardiff",
+			Code:      "W06000015",
+			Hierarchy: "Major Towns",
+			Location:  models.GeoLocation{Type: "Polygon", Coordinates: []interface{}{}},
+		}
+
+		Convey("When the area profile is validated then the hierarchy is rejected", func() {
+			err := areaProfile.Validate("W06000015", testHierarchies)
+			So(err, ShouldNotBeNil)
+
+			apiErr, ok := err.(*errs.Error)
+			So(ok, ShouldBeTrue)
+			So(apiErr.Code, ShouldEqual, errs.CodeInvalidHierarchies)
+			So(apiErr.Parameter, ShouldEqual, "hierarchy")
+		})
+	})
+
+	Convey("Given an area profile with a location without coordinates", t, func() {
+		areaProfile := &models.AreaProfileDocument{
+			Name:      "Cardiff",
+			Code:      "W06000015",
+			Hierarchy: "Major Towns and Cities",
+			Location:  models.GeoLocation{Type: "Polygon", Coordinates: []interface{}{}},
+		}
+
+		Convey("When the area profile is validated then the location is rejected", func() {
+			err := areaProfile.Validate("W06000015", testHierarchies)
+			So(err, ShouldNotBeNil)
+			So(err.(*errs.Error).Parameter, ShouldEqual, "location")
+		})
+	})
+}
@@ -1,7 +1,8 @@
 package models
 
 import (
-	"errors"
+	"net/http"
+	"strconv"
 	"strings"
 
 	errs "github.com/ONSdigital/dp-census-alpha-search-api/apierrors"
@@ -12,27 +13,40 @@ const (
 	maximumHierarchyFilters                      = 5
 	dimensionName                                = "dimensions.name"
 	hierarchyName                                = "hierarchy"
-	topic1                                       = "topic1"
-	topic2                                       = "topic2"
-	topic3                                       = "topic3"
+	topicField                                   = "topic"
 )
 
 // ErrorInvalidTopics - return error
 func ErrorInvalidTopics(topicList []string) error {
 	topics := strings.Join(topicList, ",")
-	err := errors.New("invalid list of topics to filter by: " + topics)
-	return err
+	return errs.New(errs.CodeInvalidTopics, http.StatusBadRequest, "invalid list of topics to filter by: "+topics, "topics")
 }
 
 // ErrorInvalidHierarchy - return error
-func ErrorInvalidHierarchy(hierarchy string) error {
-	err := errors.New("invalid hierarchy to filter by: " + hierarchy)
+func ErrorInvalidHierarchy(hierarchy string, closeMatches []string) error {
+	err := errs.New(errs.CodeInvalidHierarchies, http.StatusBadRequest, "invalid hierarchy to filter by: "+hierarchy+didYouMean(closeMatches), "hierarchies")
+	err.Suggestions = closeMatches
+	return err
+}
+
+// ErrorInvalidDimension - return error
+func ErrorInvalidDimension(dimension string, closeMatches []string) error {
+	err := errs.New(errs.CodeInvalidDimensions, http.StatusBadRequest, "invalid dimension to filter by: "+dimension+didYouMean(closeMatches), "dimensions")
+	err.Suggestions = closeMatches
 	return err
 }
 
-// ValidateDimensions checks the values in dimensions are valid for
-// querying elasticsearch API
-func ValidateDimensions(dimensions string) ([]Filter, error) {
+func didYouMean(closeMatches []string) string {
+	if len(closeMatches) == 0 {
+		return ""
+	}
+
+	return ". Did you mean one of the following: " + strings.Join(closeMatches, ",")
+}
+
+// ValidateDimensions checks the values in dimensions exist in the list of
+// dimensions for querying elasticsearch API
+func ValidateDimensions(dimensions string, doc DimensionsDoc) ([]Filter, error) {
 	if dimensions == "" {
 		return nil, nil
 	}
@@ -43,17 +57,24 @@ func ValidateDimensions(dimensions string) ([]Filter, error) {
 		return nil, errs.ErrTooManyDimensionFilters
 	}
 
+	names := doc.Names()
+	validDimensions := make(map[string]bool)
+	for _, name := range names {
+		validDimensions[name] = true
+	}
+
 	var filters []Filter
 	for _, dimension := range dimensionList {
+		if !validDimensions[dimension] {
+			return nil, ErrorInvalidDimension(dimension, CloseMatches(dimension, names))
+		}
 
 		filters = append(filters, Filter{
 			Nested: &Nested{
 				Path: "dimensions",
-				Query: []NestedQuery{
-					{
-						Term: map[string]string{
-							dimensionName: dimension},
-					},
+				Query: &NestedQuery{
+					Term: map[string]string{
+						dimensionName: dimension},
 				},
 			},
 		})
@@ -62,9 +83,9 @@ func ValidateDimensions(dimensions string) ([]Filter, error) {
 	return filters, nil
 }
 
-// ValidateHierarchies checks the values in hierarchies are valid for
-// querying elasticsearch API
-func ValidateHierarchies(hierarchies string) ([]Filter, error) {
+// ValidateHierarchies checks the values in hierarchies exist in the list of
+// geography hierarchies for querying elasticsearch API
+func ValidateHierarchies(hierarchies string, doc GeoHierarchiesDoc) ([]Filter, error) {
 
 	// Lower case and remove all white space for hierarchies
 	h := strings.ToLower(strings.ReplaceAll(hierarchies, " ", ""))
@@ -79,12 +100,19 @@ func ValidateHierarchies(hierarchies string) ([]Filter, error) {
 		return nil, errs.ErrTooManyHierarchyFilters
 	}
 
+	var filterableHierarchies []string
+	validHierarchies := make(map[string]string)
+	for _, item := range doc.Items {
+		validHierarchies[item.FilterableHierarchy] = item.Hierarchy
+		filterableHierarchies = append(filterableHierarchies, item.FilterableHierarchy)
+	}
+
 	var newHierarchyList []string
 	for _, hierarchy := range hierarchyList {
 		// Check hierarchy is valid
 		val, ok := validHierarchies[hierarchy]
 		if !ok {
-			return nil, ErrorInvalidHierarchy(hierarchy)
+			return nil, ErrorInvalidHierarchy(hierarchy, CloseMatches(hierarchy, filterableHierarchies))
 		}
 
 		newHierarchyList = append(newHierarchyList, val)
@@ -100,8 +128,9 @@ func ValidateHierarchies(hierarchies string) ([]Filter, error) {
 	return filters, nil
 }
 
-// ValidateTopics checks the values in topics are valid
-func ValidateTopics(topics string) ([]Filter, error) {
+// ValidateTopics checks the values in topics exist in the taxonomy, filtering each topic
+// on the topic field for its level in the taxonomy (topic1, topic2, topic3 etc.)
+func ValidateTopics(topics string, levels TopicLevels) ([]Filter, error) {
 	if topics == "" {
 		return nil, nil
 	}
@@ -112,16 +141,19 @@ func ValidateTopics(topics string) ([]Filter, error) {
 		return nil, errs.ErrTooManyTopicFilters
 	}
 
-	var invalidTopics, topic1List, topic2List, topic3List []string
+	var invalidTopics []string
+	topicsByLevel := make(map[int][]string)
+	maxLevel := 0
 	for _, topic := range topicList {
-		if validTopics[topic] < 1 {
+		level, ok := levels[topic]
+		if !ok {
 			invalidTopics = append(invalidTopics, topic)
-		} else if validTopics[topic] == 1 {
-			topic1List = append(topic1List, topic)
-		} else if validTopics[topic] == 2 {
-			topic2List = append(topic2List, topic)
-		} else if validTopics[topic] == 3 {
-			topic3List = append(topic3List, topic)
+			continue
+		}
+
+		topicsByLevel[level] = append(topicsByLevel[level], topic)
+		if level > maxLevel {
+			maxLevel = level
 		}
 	}
 
@@ -130,121 +162,13 @@ func ValidateTopics(topics string) ([]Filter, error) {
 	}
 
 	var filters []Filter
-	if len(topic1List) > 0 {
-		filters = append(filters, Filter{
-			Terms: map[string]interface{}{topic1: topic1List},
-		})
-	}
-
-	if len(topic2List) > 0 {
-		filters = append(filters, Filter{
-			Terms: map[string]interface{}{topic2: topic2List},
-		})
-	}
-
-	if len(topic3List) > 0 {
-		filters = append(filters, Filter{
-			Terms: map[string]interface{}{topic3: topic3List},
-		})
+	for level := 1; level <= maxLevel; level++ {
+		if len(topicsByLevel[level]) > 0 {
+			filters = append(filters, Filter{
+				Terms: map[string]interface{}{topicField + strconv.Itoa(level): topicsByLevel[level]},
+			})
+		}
 	}
 
 	return filters, nil
 }
-
-var validHierarchies = map[string]string{
-	"countries":                   "Countries",
-	"lowerlayersuperoutputareas":  "Lower Layer Super Output Areas",
-	"majortownsandcities":         "Major Towns and Cities",
-	"middlelayersuperoutputareas": "Middle Layer Super Output Areas",
-	"outputareas":                 "Output Areas",
-}
-
-var validTopics = map[string]int{
-	"adoption":                                 3,
-	"ageing":                                   3,
-	"balanceofpayments":                        3,
-	"birthsdeathsandmarriages":                 2,
-	"causesofdeath":                            3,
-	"childhealth":                              3,
-	"conceptionandfertilityrates":              3,
-	"conditionsanddiseases":                    3,
-	"crimeandjustice":                          2,
-	"culturalidentity":                         2,
-	"deaths":                                   3,
-	"debt":                                     3,
-	"disability":                               3,
-	"divorce":                                  3,
-	"drugusealcoholandsmoking":                 3,
-	"earningsandworkinghours":                  3,
-	"economicinactivity":                       3,
-	"economicoutputandproductivity":            2,
-	"economy":                                  1,
-	"educationandchildcare":                    2,
-	"elections":                                2,
-	"electoralregistration":                    3,
-	"employmentandemployeetypes":               3,
-	"employmentandlabourmarket":                1,
-	"environmentalaccounts":                    2,
-	"ethnicity":                                3,
-	"expenditure":                              3,
-	"families":                                 3,
-	"generalelections":                         3,
-	"governmentpublicsectorandtaxes":           2,
-	"grossdisposablehouseholdincome":           3,
-	"grossdomesticproductgdp":                  2,
-	"grossvalueaddedgva":                       2,
-	"healthandlifeexpectancies":                3,
-	"healthandsocialcare":                      2,
-	"healthandwellbeing":                       3,
-	"healthcaresystem":                         3,
-	"healthinequalities":                       3,
-	"homeinternetandsocialmediausage":          3,
-	"householdcharacteristics":                 2,
-	"housing":                                  2,
-	"incomeandwealth":                          3,
-	"inflationandpriceindices":                 2,
-	"internationalmigration":                   3,
-	"investmentspensionsandtrusts":             2,
-	"labourproductivity":                       3,
-	"language":                                 3,
-	"leisureandtourism":                        2,
-	"lifeexpectancies":                         3,
-	"livebirths":                               3,
-	"localgovernmentelections":                 3,
-	"localgovernmentfinance":                   3,
-	"marriagecohabitationandcivilpartnerships": 3,
-	"maternities":                              3,
-	"mentalhealth":                             3,
-	"migrationwithintheuk":                     3,
-	"nationalaccounts":                         2,
-	"outofworkbenefits":                        3,
-	"output":                                   3,
-	"pensionssavingsandinvestments":            3,
-	"peopleinwork":                             2,
-	"peoplenotinwork":                          2,
-	"peoplepopulationandcommunity":             1,
-	"personalandhouseholdfinances":             2,
-	"populationandmigration":                   2,
-	"populationestimates":                      3,
-	"populationprojections":                    3,
-	"productivitymeasures":                     3,
-	"publicsectorfinance":                      3,
-	"publicsectorpersonnel":                    3,
-	"publicservicesproductivity":               3,
-	"publicspending":                           3,
-	"redundancies":                             3,
-	"regionalaccounts":                         2,
-	"religion":                                 3,
-	"researchanddevelopmentexpenditure":        3,
-	"satelliteaccounts":                        3,
-	"sexuality":                                3,
-	"socialcare":                               3,
-	"stillbirths":                              3,
-	"supplyandusetables":                       3,
-	"taxesandrevenue":                          3,
-	"uksectoraccounts":                         3,
-	"unemployment":                             3,
-	"wellbeing":                                2,
-	"workplacedisputesandworkingconditions":    3,
-	"workplacepensions":                        3,
-}
@@ -0,0 +1,152 @@
+package models_test
+
+import (
+	"testing"
+
+	errs "github.com/ONSdigital/dp-census-alpha-search-api/apierrors"
+	"github.com/ONSdigital/dp-census-alpha-search-api/models"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var taxonomy = models.Taxonomy{
+	Topics: []models.Topic{
+		{
+			Title:          "Economy",
+			FormattedTitle: "economy",
+			ChildTopics: []models.Topic{
+				{
+					Title:          "Inflation and price indices",
+					FormattedTitle: "inflationandpriceindices",
+				},
+			},
+		},
+		{
+			Title:          "People, population and community",
+			FormattedTitle: "peoplepopulationandcommunity",
+			ChildTopics: []models.Topic{
+				{
+					Title:          "Births, deaths and marriages",
+					FormattedTitle: "birthsdeathsandmarriages",
+					ChildTopics: []models.Topic{
+						{
+							Title:          "Deaths",
+							FormattedTitle: "deaths",
+							ChildTopics: []models.Topic{
+								{
+									Title:          "Causes of death",
+									FormattedTitle: "causesofdeath",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestValidateTopics(t *testing.T) {
+	levels := taxonomy.Levels()
+
+	Convey("Given a list of topics from different levels of the taxonomy", t, func() {
+		topics := "deaths,economy,inflationandpriceindices,causesofdeath"
+
+		Convey("When the topics are validated", func() {
+			filters, err := models.ValidateTopics(topics, levels)
+
+			Convey("Then each topic is filtered on the topic field for its level", func() {
+				So(err, ShouldBeNil)
+				So(filters, ShouldResemble, []models.Filter{
+					{Terms: map[string]interface{}{"topic1": []string{"economy"}}},
+					{Terms: map[string]interface{}{"topic2": []string{"inflationandpriceindices"}}},
+					{Terms: map[string]interface{}{"topic3": []string{"deaths"}}},
+					{Terms: map[string]interface{}{"topic4": []string{"causesofdeath"}}},
+				})
+			})
+		})
+	})
+
+	Convey("Given a list of topics containing topics that are not in the taxonomy", t, func() {
+		topics := "economy,notatopic,alsonotatopic"
+
+		Convey("When the topics are validated", func() {
+			filters, err := models.ValidateTopics(topics, levels)
+
+			Convey("Then an invalid topics error listing the unknown topics is returned", func() {
+				So(filters, ShouldBeNil)
+				So(err, ShouldNotBeNil)
+				So(err.(*errs.Error).Code, ShouldEqual, errs.CodeInvalidTopics)
+				So(err.Error(), ShouldEqual, "invalid list of topics to filter by: notatopic,alsonotatopic")
+			})
+		})
+	})
+}
+
+func TestValidateHierarchies(t *testing.T) {
+	hierarchies := models.GeoHierarchiesDoc{
+		Items: []models.GeographyObject{
+			{Hierarchy: "Countries", FilterableHierarchy: "countries"},
+			{Hierarchy: "Output Areas", FilterableHierarchy: "outputareas"},
+			{Hierarchy: "Wards", FilterableHierarchy: "wards"},
+		},
+	}
+
+	Convey("Given a list of hierarchies that exist in the hierarchies file", t, func() {
+		Convey("When the hierarchies are validated", func() {
+			filters, err := models.ValidateHierarchies("Output Areas,wards", hierarchies)
+
+			Convey("Then the hierarchies are filtered by their name", func() {
+				So(err, ShouldBeNil)
+				So(filters, ShouldResemble, []models.Filter{
+					{Terms: map[string]interface{}{"hierarchy": []string{"Output Areas", "Wards"}}},
+				})
+			})
+		})
+	})
+
+	Convey("Given a misspelt hierarchy", t, func() {
+		Convey("When the hierarchies are validated", func() {
+			filters, err := models.ValidateHierarchies("country", hierarchies)
+
+			Convey("Then an invalid hierarchies error with close matches is returned", func() {
+				So(filters, ShouldBeNil)
+				So(err.(*errs.Error).Code, ShouldEqual, errs.CodeInvalidHierarchies)
+				So(err.(*errs.Error).Suggestions, ShouldResemble, []string{"countries"})
+			})
+		})
+	})
+}
+
+func TestValidateDimensions(t *testing.T) {
+	dimensions := models.DimensionsDoc{
+		Dimensions: []models.DimensionObject{
+			{Label: "Sex", Name: "sex"},
+			{Label: "House price age", Name: "housepriceage"},
+		},
+	}
+
+	Convey("Given a dimension that exists in the dimensions file", t, func() {
+		Convey("When the dimensions are validated", func() {
+			filters, err := models.ValidateDimensions("sex", dimensions)
+
+			Convey("Then a nested dimension filter is returned", func() {
+				So(err, ShouldBeNil)
+				So(len(filters), ShouldEqual, 1)
+				So(filters[0].Nested.Query.Term, ShouldResemble, map[string]string{"dimensions.name": "sex"})
+			})
+		})
+	})
+
+	Convey("Given a dimension that does not exist in the dimensions file", t, func() {
+		Convey("When the dimensions are validated", func() {
+			filters, err := models.ValidateDimensions("sex,housepricage", dimensions)
+
+			Convey("Then an invalid dimensions error with close matches is returned", func() {
+				So(filters, ShouldBeNil)
+				So(err.(*errs.Error).Code, ShouldEqual, errs.CodeInvalidDimensions)
+				So(err.(*errs.Error).Parameter, ShouldEqual, "dimensions")
+				So(err.(*errs.Error).Suggestions, ShouldResemble, []string{"housepriceage"})
+			})
+		})
+	})
+}
@@ -0,0 +1,14 @@
+package models
+
+// ClusterHealth represents the health of an elasticsearch cluster, the status is green,
+// yellow or red
+type ClusterHealth struct {
+	ClusterName   string `json:"cluster_name"`
+	Status        string `json:"status"`
+	NumberOfNodes int    `json:"number_of_nodes"`
+}
+
+// CountResponse represents the number of documents in an index
+type CountResponse struct {
+	Count int `json:"count"`
+}
@@ -1,13 +1,61 @@
 package models
 
+import "sort"
+
 // GeoHierarchiesDoc represents a list of geography hierarchies
 type GeoHierarchiesDoc struct {
 	Items      []GeographyObject `json:"items"`
 	TotalCount int               `json:"total_count"`
 }
 
-// GeographyObject represents the structure of a dimension
+// GeographyObject represents the structure of a dimension, the level is the position of the
+// hierarchy from the largest areas (level 1) down to the smallest
 type GeographyObject struct {
 	Hierarchy           string `json:"hierarchy"`
 	FilterableHierarchy string `json:"filterable_hierarchy"`
+	Level               int    `json:"level"`
+}
+
+// HierarchyLevels maps the name of each geography hierarchy to its level, from the
+// largest area (countries) down to the smallest (output areas)
+type HierarchyLevels map[string]int
+
+// Levels returns the level of every hierarchy in the list
+func (d GeoHierarchiesDoc) Levels() HierarchyLevels {
+	levels := make(HierarchyLevels)
+	for _, hierarchy := range d.Items {
+		levels[hierarchy.Hierarchy] = hierarchy.Level
+	}
+
+	return levels
+}
+
+// Level returns the level of the hierarchy, any unrecognised hierarchy is placed
+// after the smallest level
+func (l HierarchyLevels) Level(hierarchy string) int {
+	if level, ok := l[hierarchy]; ok {
+		return level
+	}
+
+	return l.unknown()
+}
+
+// unknown returns the level given to unrecognised hierarchies
+func (l HierarchyLevels) unknown() int {
+	max := 0
+	for _, level := range l {
+		if level > max {
+			max = level
+		}
+	}
+
+	return max + 1
+}
+
+// SortByHierarchyLevel orders area profiles by hierarchy level from country down
+// to output area, any unrecognised hierarchies are placed at the end
+func SortByHierarchyLevel(items []SearchResult, levels HierarchyLevels) {
+	sort.SliceStable(items, func(i, j int) bool {
+		return levels.Level(items[i].Hierarchy) < levels.Level(items[j].Hierarchy)
+	})
 }
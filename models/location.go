@@ -0,0 +1,46 @@
+package models
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	errs "github.com/ONSdigital/dp-census-alpha-search-api/apierrors"
+)
+
+// LocationResult represents a single point and the area profiles it falls within
+type LocationResult struct {
+	Pin          PinLocation   `json:"pin"`
+	AreaProfiles SearchResults `json:"area_profiles"`
+}
+
+// ValidateLatLon checks the latitude and longitude values are finite numbers
+// that represent a valid point on the Earth
+func ValidateLatLon(lat, lon string) (*PinLocation, error) {
+	lat = strings.TrimSpace(lat)
+	lon = strings.TrimSpace(lon)
+
+	if lat == "" || lon == "" {
+		return nil, errs.ErrMissingLatLon
+	}
+
+	latitude, err := strconv.ParseFloat(lat, 64)
+	if err != nil || !finite(latitude) || latitude > 90 || latitude < -90 {
+		return nil, errs.ErrInvalidLatitude
+	}
+
+	longitude, err := strconv.ParseFloat(lon, 64)
+	if err != nil || !finite(longitude) || longitude > 180 || longitude < -180 {
+		return nil, errs.ErrInvalidLongitude
+	}
+
+	return &PinLocation{
+		Lat: latitude,
+		Lon: longitude,
+	}, nil
+}
+
+// finite checks the value is neither NaN nor infinite, which strconv accepts
+func finite(value float64) bool {
+	return !math.IsNaN(value) && !math.IsInf(value, 0)
+}
@@ -0,0 +1,44 @@
+package models_test
+
+import (
+	"testing"
+
+	errs "github.com/ONSdigital/dp-census-alpha-search-api/apierrors"
+	"github.com/ONSdigital/dp-census-alpha-search-api/models"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestValidateLatLon(t *testing.T) {
+	Convey("Given a latitude and longitude of a point on the Earth", t, func() {
+		Convey("When the values are validated then the point is returned", func() {
+			pin, err := models.ValidateLatLon(" 51.5 ", "-0.12")
+			So(err, ShouldBeNil)
+			So(pin, ShouldResemble, &models.PinLocation{Lat: 51.5, Lon: -0.12})
+		})
+	})
+
+	Convey("Given latitude and longitude values that are not a point on the Earth", t, func() {
+		tests := []struct {
+			lat, lon string
+			err      error
+		}{
+			{lat: "", lon: "-0.12", err: errs.ErrMissingLatLon},
+			{lat: "51.5", lon: " ", err: errs.ErrMissingLatLon},
+			{lat: "north", lon: "-0.12", err: errs.ErrInvalidLatitude},
+			{lat: "90.1", lon: "-0.12", err: errs.ErrInvalidLatitude},
+			{lat: "NaN", lon: "-0.12", err: errs.ErrInvalidLatitude},
+			{lat: "-Inf", lon: "-0.12", err: errs.ErrInvalidLatitude},
+			{lat: "51.5", lon: "-180.1", err: errs.ErrInvalidLongitude},
+			{lat: "51.5", lon: "nan", err: errs.ErrInvalidLongitude},
+			{lat: "51.5", lon: "+Inf", err: errs.ErrInvalidLongitude},
+		}
+
+		Convey("When the values are validated then each is rejected with an error", func() {
+			for _, test := range tests {
+				pin, err := models.ValidateLatLon(test.lat, test.lon)
+				So(err, ShouldEqual, test.err)
+				So(pin, ShouldBeNil)
+			}
+		})
+	})
+}
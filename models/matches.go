@@ -0,0 +1,104 @@
+package models
+
+import (
+	"sort"
+	"strings"
+)
+
+const (
+	maximumCloseMatches = 3
+	minimumCommonPrefix = 4
+)
+
+// CloseMatches returns up to 3 options that are similar to an invalid value, ordered by how
+// similar they are. An option is similar if it contains the value, starts with the same
+// few characters or can be reached from the value in a small number of single character edits.
+func CloseMatches(value string, options []string) []string {
+	value = strings.ToLower(value)
+	if value == "" {
+		return nil
+	}
+
+	type match struct {
+		option   string
+		distance int
+	}
+
+	// allow roughly one edit for every three characters, with a minimum of two
+	maxDistance := len(value) / 3
+	if maxDistance < 2 {
+		maxDistance = 2
+	}
+
+	var matches []match
+	for _, option := range options {
+		lcOption := strings.ToLower(option)
+		distance := editDistance(value, lcOption)
+
+		if distance <= maxDistance || strings.Contains(lcOption, value) || commonPrefixLength(value, lcOption) >= minimumCommonPrefix {
+			matches = append(matches, match{option: option, distance: distance})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].distance < matches[j].distance
+	})
+
+	var closeMatches []string
+	for i := 0; i < len(matches) && i < maximumCloseMatches; i++ {
+		closeMatches = append(closeMatches, matches[i].option)
+	}
+
+	return closeMatches
+}
+
+func commonPrefixLength(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	n := 0
+	for n < len(ra) && n < len(rb) && ra[n] == rb[n] {
+		n++
+	}
+
+	return n
+}
+
+// editDistance calculates the levenshtein distance between two strings
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			current[j] = minimum(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+
+		previous, current = current, previous
+	}
+
+	return previous[len(rb)]
+}
+
+func minimum(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+
+	return m
+}
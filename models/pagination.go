@@ -1,7 +1,7 @@
 package models
 
 import (
-	"errors"
+	"net/http"
 	"strconv"
 
 	errs "github.com/ONSdigital/dp-census-alpha-search-api/apierrors"
@@ -9,6 +9,7 @@ import (
 
 // PageVariables are the necessary fields to determine paging
 type PageVariables struct {
+	Cursor            *Cursor
 	DefaultMaxResults int
 	Limit             int
 	Offset            int
@@ -16,14 +17,12 @@ type PageVariables struct {
 
 // ErrorMaximumOffsetReached - return error
 func ErrorMaximumOffsetReached(m int) error {
-	err := errors.New("the maximum offset has been reached, the offset cannot be more than " + strconv.Itoa(m))
-	return err
+	return errs.New(errs.CodeMaximumOffset, http.StatusBadRequest, "the maximum offset has been reached, the offset cannot be more than "+strconv.Itoa(m), "offset")
 }
 
 // ErrorMaximumLimitReached - return error
 func ErrorMaximumLimitReached(m int) error {
-	err := errors.New("the maximum limit has been reached, the limit cannot be more than " + strconv.Itoa(m))
-	return err
+	return errs.New(errs.CodeMaximumLimit, http.StatusBadRequest, "the maximum limit has been reached, the limit cannot be more than "+strconv.Itoa(m), "limit")
 }
 
 // Validate represents a model for validating pagination variables
@@ -36,6 +35,19 @@ func (page *PageVariables) Validate() error {
 		return errs.ErrNegativeLimit
 	}
 
+	// Paging with a cursor is not restricted by the maximum offset
+	if page.Cursor != nil {
+		if page.Offset > 0 {
+			return errs.ErrCursorWithOffset
+		}
+
+		if page.Limit >= page.DefaultMaxResults {
+			return ErrorMaximumLimitReached(page.DefaultMaxResults)
+		}
+
+		return nil
+	}
+
 	if page.Offset >= page.DefaultMaxResults {
 		return ErrorMaximumOffsetReached(page.DefaultMaxResults)
 	}
@@ -64,3 +64,13 @@ type GeoLocation struct {
 	Type        string      `json:"type"`
 	Coordinates interface{} `json:"coordinates"`
 }
+
+// ------------------------------------------------------------------------
+
+// PostcodeResult represents a single postcode, its location and the area profiles it falls within
+type PostcodeResult struct {
+	Postcode     string        `json:"postcode"`
+	PostcodeRaw  string        `json:"postcode_raw"`
+	Pin          PinLocation   `json:"pin"`
+	AreaProfiles SearchResults `json:"area_profiles"`
+}
@@ -2,13 +2,21 @@ package models
 
 // Body represents the request body to elasticsearch
 type Body struct {
-	Aggregations Aggs       `json:"aggs,omitempty"`
-	From         int        `json:"from"`
-	Size         int        `json:"size"`
-	Highlight    *Highlight `json:"highlight,omitempty"`
-	Query        Query      `json:"query"`
-	Sort         []Scores   `json:"sort"`
-	TotalHits    bool       `json:"track_total_hits"`
+	Aggregations Aggs          `json:"aggs,omitempty"`
+	From         int           `json:"from"`
+	Size         int           `json:"size"`
+	Highlight    *Highlight    `json:"highlight,omitempty"`
+	Query        Query         `json:"query"`
+	SearchAfter  []interface{} `json:"search_after,omitempty"`
+	Sort         []Scores      `json:"sort"`
+	Source       *SourceFilter `json:"_source,omitempty"`
+	TotalHits    bool          `json:"track_total_hits"`
+}
+
+// SourceFilter represents the fields to include or exclude from the documents returned
+type SourceFilter struct {
+	Excludes []string `json:"excludes,omitempty"`
+	Includes []string `json:"includes,omitempty"`
 }
 
 // Aggs represents the name in which an specific aggregation is returned as
@@ -63,6 +71,7 @@ type Query struct {
 type Bool struct {
 	Filter             []Filter `json:"filter,omitempty"`
 	Must               []Match  `json:"must,omitempty"`
+	MustNot            []Match  `json:"must_not,omitempty"`
 	Should             []Match  `json:"should,omitempty"`
 	MinimumShouldMatch int      `json:"minimum_should_match,omitempty"`
 }
@@ -77,15 +86,40 @@ type Filter struct {
 
 // Match represents the fields that the term should or must match within query
 type Match struct {
-	Bool   *Bool             `json:"bool,omitempty"`
-	Match  map[string]string `json:"match,omitempty"`
-	Nested *Nested           `json:"nested,omitempty"`
+	Bool        *Bool             `json:"bool,omitempty"`
+	Match       map[string]string `json:"match,omitempty"`
+	MatchPhrase map[string]string `json:"match_phrase,omitempty"`
+	Nested      *Nested           `json:"nested,omitempty"`
+	Term        map[string]string `json:"term,omitempty"`
 }
 
-// Nested represents a nested query object
+// Nested represents a nested query object, elasticsearch accepts a single query for each path
 type Nested struct {
-	Path  string        `json:"path,omitempty"`
-	Query []NestedQuery `json:"query,omitempty"`
+	Path  string       `json:"path,omitempty"`
+	Query *NestedQuery `json:"query,omitempty"`
+}
+
+// DimensionMatch matches the text against the label or name of any dimension of a dataset,
+// labels are analysed text and names are keywords matched exactly
+func DimensionMatch(text string) Match {
+	return Match{
+		Nested: &Nested{
+			Path: "dimensions",
+			Query: &NestedQuery{
+				Bool: &Bool{
+					Should: []Match{
+						{
+							Match: map[string]string{"dimensions.label": text},
+						},
+						{
+							Term: map[string]string{"dimensions.name": text},
+						},
+					},
+					MinimumShouldMatch: 1,
+				},
+			},
+		},
+	}
 }
 
 // GeoShape represents the query object for a elasticsearch geography shape
@@ -101,6 +135,7 @@ type GeoLocationObj struct {
 
 // NestedQuery represents ...
 type NestedQuery struct {
+	Bool  *Bool                  `json:"bool,omitempty"`
 	Must  []Match                `json:"must,omitempty"`
 	Term  map[string]string      `json:"term,omitempty"`
 	Terms map[string]interface{} `json:"terms,omitempty"`
@@ -109,10 +144,39 @@ type NestedQuery struct {
 // Scores represents a list of scoring, e.g. scoring on relevance, but can add in secondary
 // score such as alphabetical order if relevance is the same for two search results
 type Scores struct {
-	Score Score `json:"_score"`
+	Score       *Score           `json:"_score,omitempty"`
+	SortTitle   *Score           `json:"sort_title,omitempty"` // normalised copy of dataset title or area profile name
+	Script      *ScriptSort      `json:"_script,omitempty"`
+	GeoDistance *GeoDistanceSort `json:"_geo_distance,omitempty"`
+	Index       *Score           `json:"_index,omitempty"`
+	Alias       *Score           `json:"alias,omitempty"` // id of a dataset
+	ID          *Score           `json:"id,omitempty"`    // id of an area profile or publication
 }
 
 // Score contains the ordering of the score (ascending or descending)
 type Score struct {
-	Order string `json:"order"`
+	Order        string `json:"order"`
+	Missing      string `json:"missing,omitempty"`
+	UnmappedType string `json:"unmapped_type,omitempty"`
+}
+
+// ScriptSort represents a score calculated by a script for each search result
+type ScriptSort struct {
+	Type   string `json:"type"`
+	Order  string `json:"order"`
+	Script Script `json:"script"`
+}
+
+// Script represents an elasticsearch painless script and its parameters
+type Script struct {
+	Source string                 `json:"source"`
+	Params map[string]interface{} `json:"params,omitempty"`
+}
+
+// GeoDistanceSort represents a score based on the distance of each area profile centroid from a location
+type GeoDistanceSort struct {
+	Centroid       PinLocation `json:"centroid"`
+	Order          string      `json:"order"`
+	Unit           string      `json:"unit"`
+	IgnoreUnmapped bool        `json:"ignore_unmapped"`
 }
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// ReloadResponse represents the reference documents loaded by a reload of the reference files
+type ReloadResponse struct {
+	Dimensions  int       `json:"dimensions"`
+	Hierarchies int       `json:"hierarchies"`
+	Topics      int       `json:"topics"`
+	LoadedAt    time.Time `json:"loaded_at"`
+}
@@ -0,0 +1,45 @@
+package models
+
+// ReindexRequest represents the request body to copy documents from one index to another
+type ReindexRequest struct {
+	Source ReindexIndex `json:"source"`
+	Dest   ReindexIndex `json:"dest"`
+}
+
+// ReindexIndex represents an index taking part in a reindex
+type ReindexIndex struct {
+	Index string `json:"index"`
+}
+
+// ReindexResponse represents the response from starting a reindex task
+type ReindexResponse struct {
+	Task string `json:"task"`
+}
+
+// TaskResponse represents the state of a long running elasticsearch task
+type TaskResponse struct {
+	Completed bool        `json:"completed"`
+	Task      TaskInfo    `json:"task"`
+	Error     interface{} `json:"error,omitempty"`
+	Response  TaskResult  `json:"response"`
+}
+
+// TaskInfo represents the progress of a task
+type TaskInfo struct {
+	Status TaskStatus `json:"status"`
+}
+
+// TaskStatus represents the number of documents processed by a task
+type TaskStatus struct {
+	Total   int `json:"total"`
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+}
+
+// TaskResult represents the outcome of a completed task
+type TaskResult struct {
+	Total    int           `json:"total"`
+	Created  int           `json:"created"`
+	Updated  int           `json:"updated"`
+	Failures []interface{} `json:"failures"`
+}
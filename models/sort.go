@@ -0,0 +1,108 @@
+package models
+
+import (
+	"net/http"
+	"strings"
+
+	errs "github.com/ONSdigital/dp-census-alpha-search-api/apierrors"
+)
+
+// A list of sort orders for search results
+const (
+	SortRelevance    = "relevance"
+	SortAlphabetical = "alphabetical"
+	SortHierarchy    = "hierarchy"
+	SortDistance     = "distance"
+)
+
+// hierarchyLevelScript scores area profiles by hierarchy level, documents without a hierarchy
+// (e.g. datasets) are scored after all known levels
+const hierarchyLevelScript = "if (doc.containsKey('hierarchy') && doc['hierarchy'].size() > 0) { return params.levels.getOrDefault(doc['hierarchy'].value, params.unknown); } return params.unknown;"
+
+// SearchSortOptions represents the sort orders available when searching across all data types
+var SearchSortOptions = []string{SortRelevance, SortAlphabetical, SortHierarchy, SortDistance}
+
+// DatasetSortOptions represents the sort orders available when only searching datasets
+var DatasetSortOptions = []string{SortRelevance, SortAlphabetical}
+
+// ErrorInvalidSort - return error
+func ErrorInvalidSort(sort string, options []string) error {
+	return errs.New(errs.CodeInvalidSort, http.StatusBadRequest, "invalid sort value: "+sort+". Should contain one of the following: "+strings.Join(options, ","), "sort")
+}
+
+// ValidateSort checks the requested sort is one of the options available, defaulting to relevance
+func ValidateSort(sort string, options []string) (string, error) {
+	if sort == "" {
+		return SortRelevance, nil
+	}
+
+	lcSort := strings.ToLower(strings.TrimSpace(sort))
+	for _, option := range options {
+		if lcSort == option {
+			return option, nil
+		}
+	}
+
+	return "", ErrorInvalidSort(sort, options)
+}
+
+// BuildSort creates the list of scores to order search results by, relevance is used as a
+// secondary score to order results that have the same value for the primary score. The pin is
+// the location distances are measured from and is only required when sorting by distance, the
+// hierarchy levels are only required when sorting by hierarchy
+func BuildSort(sort string, pin *PinLocation, levels HierarchyLevels) []Scores {
+	relevance := Scores{
+		Score: &Score{
+			Order: "desc",
+		},
+	}
+
+	switch sort {
+	case SortAlphabetical:
+		return []Scores{
+			{
+				SortTitle: &Score{
+					Order:        "asc",
+					Missing:      "_last",
+					UnmappedType: "keyword",
+				},
+			},
+			relevance,
+		}
+	case SortHierarchy:
+		return []Scores{
+			{
+				Script: &ScriptSort{
+					Type:  "number",
+					Order: "asc",
+					Script: Script{
+						Source: hierarchyLevelScript,
+						Params: map[string]interface{}{
+							"levels":  levels,
+							"unknown": levels.unknown(),
+						},
+					},
+				},
+			},
+			relevance,
+		}
+	case SortDistance:
+		if pin == nil {
+			return []Scores{relevance}
+		}
+
+		return []Scores{
+			{
+				GeoDistance: &GeoDistanceSort{
+					Centroid:       *pin,
+					Order:          "asc",
+					Unit:           "m",
+					IgnoreUnmapped: true,
+				},
+			},
+			relevance,
+		}
+	default:
+		return []Scores{relevance}
+	}
+}
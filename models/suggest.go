@@ -0,0 +1,60 @@
+package models
+
+// SuggestBody represents the request body to elasticsearch to retrieve spelling suggestions
+type SuggestBody struct {
+	Size    int     `json:"size"`
+	Suggest Suggest `json:"suggest"`
+}
+
+// Suggest represents the text to find suggestions for and the name in which each suggester is returned as
+type Suggest struct {
+	Text   string     `json:"text"`
+	Title  *Suggester `json:"title,omitempty"`
+	Name   *Suggester `json:"name,omitempty"`
+	Topic1 *Suggester `json:"topic1,omitempty"`
+	Topic2 *Suggester `json:"topic2,omitempty"`
+	Topic3 *Suggester `json:"topic3,omitempty"`
+}
+
+// Suggester represents a single suggester
+type Suggester struct {
+	Term *TermSuggester `json:"term,omitempty"`
+}
+
+// TermSuggester represents a suggester that suggests terms based on edit distance
+type TermSuggester struct {
+	Field       string `json:"field"`
+	Size        int    `json:"size,omitempty"`
+	SuggestMode string `json:"suggest_mode,omitempty"`
+}
+
+// SuggestEntry represents a single term of the suggest text and the options found for it
+type SuggestEntry struct {
+	Text    string          `json:"text"`
+	Offset  int             `json:"offset"`
+	Length  int             `json:"length"`
+	Options []SuggestOption `json:"options"`
+}
+
+// SuggestOption represents a suggested correction for a term
+type SuggestOption struct {
+	Text  string  `json:"text"`
+	Score float64 `json:"score"`
+	Freq  int     `json:"freq"`
+}
+
+// Suggestions represents a list of spelling suggestions for a search term that returned few or no results
+type Suggestions struct {
+	Text      string             `json:"text,omitempty"`
+	Options   []SuggestionOption `json:"options"`
+	Requeried bool               `json:"requeried"`
+}
+
+// SuggestionOption represents the best suggested correction for a single term of the search term
+type SuggestionOption struct {
+	Original string  `json:"original"`
+	Text     string  `json:"text"`
+	Field    string  `json:"field"`
+	Score    float64 `json:"score"`
+	Freq     int     `json:"freq"`
+}
@@ -11,3 +11,22 @@ type Topic struct {
 	FormattedTitle string  `json:"filterable_title"`
 	ChildTopics    []Topic `json:"child_topics,omitempty"`
 }
+
+// TopicLevels maps the filterable title of each topic to its level in the taxonomy,
+// top level topics are level 1
+type TopicLevels map[string]int
+
+// Levels returns the level of every topic in the taxonomy at any depth
+func (t Taxonomy) Levels() TopicLevels {
+	levels := make(TopicLevels)
+	addTopicLevels(levels, t.Topics, 1)
+
+	return levels
+}
+
+func addTopicLevels(levels TopicLevels, topics []Topic, level int) {
+	for _, topic := range topics {
+		levels[topic.FormattedTitle] = level
+		addTopicLevels(levels, topic.ChildTopics, level+1)
+	}
+}
@@ -0,0 +1,129 @@
+package parser
+
+import (
+	"github.com/ONSdigital/dp-census-alpha-search-api/models"
+)
+
+// fields represents the fields searched for each field prefix, fields that do not exist in
+// an index will not match any documents in that index
+var fields = map[string][]string{
+	FieldCode:      {"code"},
+	FieldHierarchy: {"hierarchy"},
+	FieldTitle:     {"title.raw", "name"},
+	FieldTopic:     {"topic1", "topic2", "topic3"},
+}
+
+// Compile converts the query into an elasticsearch bool query. Terms without a field
+// prefix are matched against the list of matches created by defaults
+func (q *Query) Compile(defaults func(text string) []models.Match) *models.Bool {
+	c := compiler{defaults: defaults}
+
+	switch n := q.root.(type) {
+	case *and:
+		return c.compileAnd(n)
+	case *or:
+		return c.compileOr(n)
+	default:
+		return c.compileTerm(n.(*term))
+	}
+}
+
+type compiler struct {
+	defaults func(text string) []models.Match
+}
+
+func (c compiler) compile(n node) models.Match {
+	switch n := n.(type) {
+	case *and:
+		return models.Match{Bool: c.compileAnd(n)}
+	case *or:
+		return models.Match{Bool: c.compileOr(n)}
+	case *not:
+		return models.Match{
+			Bool: &models.Bool{
+				MustNot: []models.Match{c.compile(n.node)},
+			},
+		}
+	default:
+		return models.Match{Bool: c.compileTerm(n.(*term))}
+	}
+}
+
+func (c compiler) compileAnd(n *and) *models.Bool {
+	b := &models.Bool{}
+
+	for _, child := range n.nodes {
+		if excluded, ok := child.(*not); ok {
+			b.MustNot = append(b.MustNot, c.compile(excluded.node))
+			continue
+		}
+
+		b.Must = append(b.Must, c.compile(child))
+	}
+
+	return b
+}
+
+func (c compiler) compileOr(n *or) *models.Bool {
+	b := &models.Bool{
+		MinimumShouldMatch: 1,
+	}
+
+	for _, child := range n.nodes {
+		b.Should = append(b.Should, c.compile(child))
+	}
+
+	return b
+}
+
+// compileTerm matches the text against any of the fields for the term
+func (c compiler) compileTerm(t *term) *models.Bool {
+	var matches []models.Match
+
+	switch t.field {
+	case "":
+		matches = c.defaults(t.text)
+	case FieldDimension:
+		matches = []models.Match{models.DimensionMatch(t.text)}
+	default:
+		for _, field := range fields[t.field] {
+			matches = append(matches, models.Match{
+				Match: map[string]string{field: t.text},
+			})
+		}
+	}
+
+	if t.phrase {
+		phrase(matches)
+	}
+
+	return &models.Bool{
+		Should:             matches,
+		MinimumShouldMatch: 1,
+	}
+}
+
+// phrase converts the analysed matches to phrase matches, including those inside
+// nested and bool queries such as the dimension label, exact terms are left as they are
+func phrase(matches []models.Match) {
+	for i := range matches {
+		if matches[i].Match != nil {
+			matches[i].MatchPhrase = matches[i].Match
+			matches[i].Match = nil
+		}
+
+		if matches[i].Bool != nil {
+			phraseBool(matches[i].Bool)
+		}
+
+		if matches[i].Nested != nil && matches[i].Nested.Query != nil && matches[i].Nested.Query.Bool != nil {
+			phraseBool(matches[i].Nested.Query.Bool)
+		}
+	}
+}
+
+func phraseBool(b *models.Bool) {
+	phrase(b.Must)
+	phrase(b.MustNot)
+	phrase(b.Should)
+}
@@ -0,0 +1,366 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// List of field prefixes that can be used to restrict a term to specific fields
+const (
+	FieldCode      = "code"
+	FieldDimension = "dimension"
+	FieldHierarchy = "hierarchy"
+	FieldTitle     = "title"
+	FieldTopic     = "topic"
+)
+
+var fieldPrefixes = map[string]bool{
+	FieldCode:      true,
+	FieldDimension: true,
+	FieldHierarchy: true,
+	FieldTitle:     true,
+	FieldTopic:     true,
+}
+
+// SyntaxErrorMessage is the start of all syntax error messages
+const SyntaxErrorMessage = "invalid query syntax"
+
+// SyntaxError represents a query that cannot be parsed, position is the
+// 1-based character position in the query where the error was found
+type SyntaxError struct {
+	Position int
+	Message  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at position %d: %s", SyntaxErrorMessage, e.Position, e.Message)
+}
+
+func syntaxError(position int, format string, args ...interface{}) *SyntaxError {
+	return &SyntaxError{
+		Position: position + 1,
+		Message:  fmt.Sprintf(format, args...),
+	}
+}
+
+// Query represents a parsed search query
+type Query struct {
+	root node
+}
+
+// node represents a part of a parsed query, one of *term, *and, *or or *not
+type node interface{}
+
+// term represents text to match, restricted to a field if the field is set
+type term struct {
+	field  string
+	text   string
+	phrase bool
+}
+
+// and represents nodes that must all match
+type and struct {
+	nodes []node
+}
+
+// or represents nodes where at least one must match
+type or struct {
+	nodes []node
+}
+
+// not represents a node that must not match
+type not struct {
+	node node
+}
+
+type tokenType int
+
+const (
+	tokenWord tokenType = iota
+	tokenPhrase
+	tokenField
+	tokenAnd
+	tokenOr
+	tokenExclude
+	tokenOpen
+	tokenClose
+	tokenEnd
+)
+
+type token struct {
+	typ      tokenType
+	text     string
+	position int
+}
+
+// Parse converts the search term into a query. The syntax supports "exact phrases",
+// AND and OR operators (in uppercase), -excluded terms, (grouping) and field prefixes,
+// e.g. title:wellbeing. Words next to each other are matched together as a single term
+func Parse(q string) (*Query, error) {
+	tokens, err := tokenise(q)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.typ != tokenEnd {
+		return nil, syntaxError(t.position, "unexpected %q", t.text)
+	}
+
+	// a query of only excluded terms would match every other document in the index
+	if !includesTerm(root) {
+		return nil, syntaxError(0, "expected a search term that is not excluded")
+	}
+
+	return &Query{root: root}, nil
+}
+
+// includesTerm checks whether the node can only match documents that match one of its terms,
+// an OR needs a term on every side
+func includesTerm(n node) bool {
+	switch n := n.(type) {
+	case *and:
+		for _, child := range n.nodes {
+			if includesTerm(child) {
+				return true
+			}
+		}
+
+		return false
+	case *or:
+		for _, child := range n.nodes {
+			if !includesTerm(child) {
+				return false
+			}
+		}
+
+		return true
+	case *not:
+		return false
+	default:
+		return true
+	}
+}
+
+func tokenise(q string) ([]token, error) {
+	var tokens []token
+	runes := []rune(q)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{typ: tokenOpen, text: "(", position: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{typ: tokenClose, text: ")", position: i})
+			i++
+		case r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+
+			if end == len(runes) {
+				return nil, syntaxError(i, "missing closing quote")
+			}
+
+			text := strings.TrimSpace(string(runes[i+1 : end]))
+			if text == "" {
+				return nil, syntaxError(i, "empty phrase")
+			}
+
+			tokens = append(tokens, token{typ: tokenPhrase, text: text, position: i})
+			i = end + 1
+		case r == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) && runes[i+1] != ')':
+			tokens = append(tokens, token{typ: tokenExclude, text: "-", position: i})
+			i++
+		default:
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && !strings.ContainsRune(`()"`, runes[end]) {
+				end++
+			}
+
+			text := string(runes[i:end])
+
+			if colon := strings.IndexRune(text, ':'); colon > 0 {
+				// a word before a colon that is not a field prefix is part of the search term,
+				// e.g. covid:deaths
+				prefix := strings.ToLower(text[:colon])
+				if !fieldPrefixes[prefix] {
+					tokens = append(tokens, token{typ: tokenWord, text: text, position: i})
+					i = end
+					break
+				}
+
+				tokens = append(tokens, token{typ: tokenField, text: prefix, position: i})
+
+				// a field prefix is followed by the value it applies to
+				i += len([]rune(text[:colon+1]))
+				continue
+			}
+
+			switch text {
+			case "AND":
+				tokens = append(tokens, token{typ: tokenAnd, text: text, position: i})
+			case "OR":
+				tokens = append(tokens, token{typ: tokenOr, text: text, position: i})
+			default:
+				tokens = append(tokens, token{typ: tokenWord, text: text, position: i})
+			}
+
+			i = end
+		}
+	}
+
+	return append(tokens, token{typ: tokenEnd, position: len(runes)}), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.typ != tokenEnd {
+		p.pos++
+	}
+
+	return t
+}
+
+// parseOr handles: and ( OR and )*
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []node{left}
+	for p.peek().typ == tokenOr {
+		p.next()
+
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		nodes = append(nodes, right)
+	}
+
+	if len(nodes) == 1 {
+		return left, nil
+	}
+
+	return &or{nodes: nodes}, nil
+}
+
+// parseAnd handles: unary ( [AND] unary )*
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []node{left}
+	for {
+		t := p.peek()
+		if t.typ == tokenAnd {
+			p.next()
+		} else if !startsUnary(t.typ) {
+			break
+		}
+
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		nodes = append(nodes, right)
+	}
+
+	if len(nodes) == 1 {
+		return left, nil
+	}
+
+	return &and{nodes: nodes}, nil
+}
+
+func startsUnary(typ tokenType) bool {
+	switch typ {
+	case tokenWord, tokenPhrase, tokenField, tokenExclude, tokenOpen:
+		return true
+	}
+
+	return false
+}
+
+// parseUnary handles: - unary | primary
+func (p *parser) parseUnary() (node, error) {
+	if p.peek().typ == tokenExclude {
+		p.next()
+
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		return &not{node: n}, nil
+	}
+
+	return p.parsePrimary()
+}
+
+// parsePrimary handles: ( or ) | field value | phrase | word+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+
+	switch t.typ {
+	case tokenOpen:
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if closing := p.next(); closing.typ != tokenClose {
+			return nil, syntaxError(t.position, "missing closing bracket")
+		}
+
+		return n, nil
+	case tokenField:
+		value := p.next()
+		if value.typ != tokenWord && value.typ != tokenPhrase {
+			return nil, syntaxError(value.position, "expected a word or quoted phrase after %s:", t.text)
+		}
+
+		return &term{field: t.text, text: value.text, phrase: value.typ == tokenPhrase}, nil
+	case tokenPhrase:
+		return &term{text: t.text, phrase: true}, nil
+	case tokenWord:
+		words := []string{t.text}
+		for p.peek().typ == tokenWord {
+			words = append(words, p.next().text)
+		}
+
+		return &term{text: strings.Join(words, " ")}, nil
+	case tokenEnd:
+		return nil, syntaxError(t.position, "expected a search term")
+	default:
+		return nil, syntaxError(t.position, "unexpected %q", t.text)
+	}
+}
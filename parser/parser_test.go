@@ -0,0 +1,234 @@
+package parser_test
+
+import (
+	"testing"
+
+	"github.com/ONSdigital/dp-census-alpha-search-api/models"
+	"github.com/ONSdigital/dp-census-alpha-search-api/parser"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func defaultMatches(text string) []models.Match {
+	return []models.Match{
+		{
+			Match: map[string]string{"title.raw": text},
+		},
+		{
+			Match: map[string]string{"description.raw": text},
+		},
+	}
+}
+
+func TestParseAndCompile(t *testing.T) {
+	Convey("Given a search term without any query syntax", t, func() {
+		q, err := parser.Parse("population estimates")
+		So(err, ShouldBeNil)
+
+		Convey("When the query is compiled", func() {
+			query := q.Compile(defaultMatches)
+
+			Convey("Then the whole term is matched against the default fields", func() {
+				So(query, ShouldResemble, &models.Bool{
+					Should:             defaultMatches("population estimates"),
+					MinimumShouldMatch: 1,
+				})
+			})
+		})
+	})
+
+	Convey("Given a search term with an excluded word", t, func() {
+		q, err := parser.Parse("wellbeing -annual")
+		So(err, ShouldBeNil)
+
+		Convey("When the query is compiled", func() {
+			query := q.Compile(defaultMatches)
+
+			Convey("Then the excluded word must not match", func() {
+				So(query, ShouldResemble, &models.Bool{
+					Must: []models.Match{
+						{
+							Bool: &models.Bool{
+								Should:             defaultMatches("wellbeing"),
+								MinimumShouldMatch: 1,
+							},
+						},
+					},
+					MustNot: []models.Match{
+						{
+							Bool: &models.Bool{
+								Should:             defaultMatches("annual"),
+								MinimumShouldMatch: 1,
+							},
+						},
+					},
+				})
+			})
+		})
+	})
+
+	Convey("Given a search term with a quoted phrase and a field prefix combined with OR", t, func() {
+		q, err := parser.Parse(`"personal well-being" OR code:E92000001`)
+		So(err, ShouldBeNil)
+
+		Convey("When the query is compiled", func() {
+			query := q.Compile(defaultMatches)
+
+			Convey("Then either the phrase or the field must match", func() {
+				So(query, ShouldResemble, &models.Bool{
+					Should: []models.Match{
+						{
+							Bool: &models.Bool{
+								Should: []models.Match{
+									{
+										MatchPhrase: map[string]string{"title.raw": "personal well-being"},
+									},
+									{
+										MatchPhrase: map[string]string{"description.raw": "personal well-being"},
+									},
+								},
+								MinimumShouldMatch: 1,
+							},
+						},
+						{
+							Bool: &models.Bool{
+								Should: []models.Match{
+									{
+										Match: map[string]string{"code": "E92000001"},
+									},
+								},
+								MinimumShouldMatch: 1,
+							},
+						},
+					},
+					MinimumShouldMatch: 1,
+				})
+			})
+		})
+	})
+
+	Convey("Given a search term with grouped terms and the AND operator", t, func() {
+		q, err := parser.Parse("(births OR deaths) AND topic:populationandmigration")
+		So(err, ShouldBeNil)
+
+		Convey("When the query is compiled", func() {
+			query := q.Compile(defaultMatches)
+
+			Convey("Then both the group and the topic must match", func() {
+				So(len(query.Must), ShouldEqual, 2)
+				So(len(query.Must[0].Bool.Should), ShouldEqual, 2)
+				So(query.Must[1].Bool.Should, ShouldResemble, []models.Match{
+					{
+						Match: map[string]string{"topic1": "populationandmigration"},
+					},
+					{
+						Match: map[string]string{"topic2": "populationandmigration"},
+					},
+					{
+						Match: map[string]string{"topic3": "populationandmigration"},
+					},
+				})
+			})
+		})
+	})
+
+	Convey("Given a search term with a dimension prefix", t, func() {
+		q, err := parser.Parse("dimension:Age")
+		So(err, ShouldBeNil)
+
+		Convey("When the query is compiled", func() {
+			query := q.Compile(defaultMatches)
+
+			Convey("Then a single nested query matches the label or the name of a dimension", func() {
+				So(query.Should, ShouldHaveLength, 1)
+
+				nested := query.Should[0].Nested
+				So(nested.Path, ShouldEqual, "dimensions")
+				So(nested.Query.Bool.Should, ShouldResemble, []models.Match{
+					{
+						Match: map[string]string{"dimensions.label": "Age"},
+					},
+					{
+						Term: map[string]string{"dimensions.name": "Age"},
+					},
+				})
+				So(nested.Query.Bool.MinimumShouldMatch, ShouldEqual, 1)
+			})
+		})
+	})
+
+	Convey("Given a search term with a quoted phrase after a dimension prefix", t, func() {
+		q, err := parser.Parse(`dimension:"country of birth"`)
+		So(err, ShouldBeNil)
+
+		Convey("When the query is compiled", func() {
+			query := q.Compile(defaultMatches)
+
+			Convey("Then the dimension label is matched as a phrase and the name as an exact term", func() {
+				So(query.Should, ShouldHaveLength, 1)
+
+				nested := query.Should[0].Nested
+				So(nested.Path, ShouldEqual, "dimensions")
+				So(nested.Query.Bool.Should, ShouldResemble, []models.Match{
+					{
+						MatchPhrase: map[string]string{"dimensions.label": "country of birth"},
+					},
+					{
+						Term: map[string]string{"dimensions.name": "country of birth"},
+					},
+				})
+			})
+		})
+	})
+
+	Convey("Given a search term with a hyphenated word or a colon that is not a field prefix", t, func() {
+		q, err := parser.Parse("well-being 1:2")
+		So(err, ShouldBeNil)
+
+		Convey("Then the words are matched as written", func() {
+			So(q.Compile(defaultMatches).Should, ShouldResemble, defaultMatches("well-being 1:2"))
+		})
+	})
+
+	Convey("Given a search term with a word before a colon that is not a field prefix", t, func() {
+		for _, term := range []string{"Wales: population", "covid:deaths"} {
+			Convey("When parsing "+term+" then the words are matched as written", func() {
+				q, err := parser.Parse(term)
+				So(err, ShouldBeNil)
+				So(q.Compile(defaultMatches).Should, ShouldResemble, defaultMatches(term))
+			})
+		}
+	})
+}
+
+func TestParseSyntaxErrors(t *testing.T) {
+	Convey("Given search terms with invalid syntax", t, func() {
+		tests := []struct {
+			q        string
+			position int
+		}{
+			{q: `"wellbeing`, position: 1},
+			{q: `wellbeing ""`, position: 11},
+			{q: "wellbeing AND", position: 14},
+			{q: "OR wellbeing", position: 1},
+			{q: "(births OR deaths", position: 1},
+			{q: "births)", position: 7},
+			{q: "title:", position: 7},
+			{q: "-census", position: 1},
+			{q: "-(births OR deaths)", position: 1},
+			{q: "wellbeing OR -annual", position: 1},
+		}
+
+		for _, test := range tests {
+			Convey("When parsing "+test.q+" then a syntax error with a position is returned", func() {
+				q, err := parser.Parse(test.q)
+				So(q, ShouldBeNil)
+				So(err, ShouldNotBeNil)
+
+				syntaxErr, ok := err.(*parser.SyntaxError)
+				So(ok, ShouldBeTrue)
+				So(syntaxErr.Position, ShouldEqual, test.position)
+				So(err.Error(), ShouldStartWith, parser.SyntaxErrorMessage)
+			})
+		}
+	})
+}
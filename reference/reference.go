@@ -0,0 +1,309 @@
+package reference
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ONSdigital/dp-census-alpha-search-api/models"
+	"github.com/ONSdigital/log.go/log"
+)
+
+// A list of errors returned when validating reference documents
+var (
+	ErrNoDimensions           = errors.New("dimensions file contains no dimensions")
+	ErrNoHierarchies          = errors.New("hierarchies file contains no hierarchies")
+	ErrNoTopics               = errors.New("taxonomy file contains no topics")
+	ErrMissingDimensionName   = errors.New("dimensions file contains a dimension without a name")
+	ErrMissingHierarchyName   = errors.New("hierarchies file contains a hierarchy without a hierarchy or filterable_hierarchy")
+	ErrMissingHierarchyLevel  = errors.New("hierarchies file contains a hierarchy without a positive level")
+	ErrMissingTopicTitle      = errors.New("taxonomy file contains a topic without a title or filterable_title")
+	ErrDuplicateTopicFilter   = errors.New("taxonomy file contains more than one topic with the same filterable_title")
+	ErrDuplicateHierarchyName = errors.New("hierarchies file contains more than one hierarchy with the same filterable_hierarchy")
+)
+
+// Files represents the locations of the reference documents
+type Files struct {
+	Dimensions  string
+	Hierarchies string
+	Taxonomy    string
+}
+
+// Docs represents the reference documents served by the api, docs are replaced rather
+// than modified on reload so must be treated as read only
+type Docs struct {
+	Dimensions      models.DimensionsDoc
+	Hierarchies     models.GeoHierarchiesDoc
+	Taxonomy        models.Taxonomy
+	TopicLevels     models.TopicLevels
+	HierarchyLevels models.HierarchyLevels
+	LoadedAt        time.Time
+}
+
+// Store holds the last valid version of the reference documents and is safe for concurrent use
+type Store struct {
+	files    Files
+	mutex    sync.RWMutex
+	reload   sync.Mutex
+	docs     *Docs
+	modTimes map[string]time.Time
+	// failedAt and failure are set when the last reload failed
+	failedAt  time.Time
+	failure   error
+	listeners []func(*Docs)
+}
+
+// New creates a store with the reference documents loaded from files
+func New(files Files) (*Store, error) {
+	s := &Store{
+		files: files,
+	}
+
+	docs, modTimes, err := load(files)
+	if err != nil {
+		return nil, err
+	}
+
+	s.docs = docs
+	s.modTimes = modTimes
+
+	return s, nil
+}
+
+// Get returns the current version of the reference documents
+func (s *Store) Get() *Docs {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.docs
+}
+
+// OnReload registers a function that is called with the new version of the reference
+// documents after every successful reload
+func (s *Store) OnReload(listener func(*Docs)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.listeners = append(s.listeners, listener)
+}
+
+// LastFailure returns the error from the last reload and when it failed, a nil error is
+// returned if the last reload succeeded or the documents have not been reloaded
+func (s *Store) LastFailure() (time.Time, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.failedAt, s.failure
+}
+
+// Reload reads and validates the reference documents from files, the current version
+// of the documents is kept if any of the files fail to load
+func (s *Store) Reload(ctx context.Context) (*Docs, error) {
+	s.reload.Lock()
+	defer s.reload.Unlock()
+
+	logData := log.Data{"files": s.files}
+
+	// the files are checked before they are read so a failed reload is only retried by
+	// Watch once the files change again, rather than on every interval
+	attempted := statModTimes(s.filenames())
+
+	docs, modTimes, err := load(s.files)
+	if err != nil {
+		log.Event(ctx, "failed to reload reference files, keeping previous version", log.ERROR, log.Error(err), logData)
+
+		s.mutex.Lock()
+		s.modTimes = attempted
+		s.failedAt = time.Now().UTC()
+		s.failure = err
+		s.mutex.Unlock()
+
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	s.docs = docs
+	s.modTimes = modTimes
+	s.failedAt = time.Time{}
+	s.failure = nil
+	listeners := s.listeners
+	s.mutex.Unlock()
+
+	for _, listener := range listeners {
+		listener(docs)
+	}
+
+	logData["dimensions"] = len(docs.Dimensions.Dimensions)
+	logData["hierarchies"] = len(docs.Hierarchies.Items)
+	logData["topics"] = len(docs.Taxonomy.Topics)
+	log.Event(ctx, "successfully reloaded reference files", log.INFO, logData)
+
+	return docs, nil
+}
+
+// Watch reloads the reference documents whenever one of the files is modified, checking
+// the files at every interval until the context is done
+func (s *Store) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if s.modified() {
+				s.Reload(ctx)
+			}
+		}
+	}
+}
+
+// modified checks whether any of the files have changed since the last reload, whether
+// or not it succeeded
+func (s *Store) modified() bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for _, filename := range s.filenames() {
+		info, err := os.Stat(filename)
+		if err != nil {
+			continue
+		}
+
+		if !info.ModTime().Equal(s.modTimes[filename]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *Store) filenames() []string {
+	return []string{s.files.Dimensions, s.files.Hierarchies, s.files.Taxonomy}
+}
+
+// statModTimes returns the modified time of each file that exists
+func statModTimes(filenames []string) map[string]time.Time {
+	modTimes := make(map[string]time.Time)
+
+	for _, filename := range filenames {
+		if info, err := os.Stat(filename); err == nil {
+			modTimes[filename] = info.ModTime()
+		}
+	}
+
+	return modTimes
+}
+
+// load reads all reference documents, returning the modified time of each file read
+func load(files Files) (*Docs, map[string]time.Time, error) {
+	docs := &Docs{
+		LoadedAt: time.Now().UTC(),
+	}
+	modTimes := make(map[string]time.Time)
+
+	if err := readJSON(files.Dimensions, &docs.Dimensions, modTimes); err != nil {
+		return nil, nil, err
+	}
+
+	if err := readJSON(files.Hierarchies, &docs.Hierarchies, modTimes); err != nil {
+		return nil, nil, err
+	}
+
+	if err := readJSON(files.Taxonomy, &docs.Taxonomy, modTimes); err != nil {
+		return nil, nil, err
+	}
+
+	if err := docs.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	docs.TopicLevels = docs.Taxonomy.Levels()
+	docs.HierarchyLevels = docs.Hierarchies.Levels()
+
+	return docs, modTimes, nil
+}
+
+func readJSON(filename string, v interface{}, modTimes map[string]time.Time) error {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	if err = json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("failed to unmarshal %s: %w", filename, err)
+	}
+
+	modTimes[filename] = info.ModTime()
+
+	return nil
+}
+
+// Validate checks the reference documents contain the values needed to serve and validate filters
+func (d *Docs) Validate() error {
+	if len(d.Dimensions.Dimensions) == 0 {
+		return ErrNoDimensions
+	}
+
+	for _, dimension := range d.Dimensions.Dimensions {
+		if dimension.Name == "" {
+			return ErrMissingDimensionName
+		}
+	}
+
+	if len(d.Hierarchies.Items) == 0 {
+		return ErrNoHierarchies
+	}
+
+	hierarchies := make(map[string]bool)
+	for _, hierarchy := range d.Hierarchies.Items {
+		if hierarchy.Hierarchy == "" || hierarchy.FilterableHierarchy == "" {
+			return ErrMissingHierarchyName
+		}
+
+		if hierarchy.Level <= 0 {
+			return ErrMissingHierarchyLevel
+		}
+
+		if hierarchies[hierarchy.FilterableHierarchy] {
+			return ErrDuplicateHierarchyName
+		}
+		hierarchies[hierarchy.FilterableHierarchy] = true
+	}
+
+	if len(d.Taxonomy.Topics) == 0 {
+		return ErrNoTopics
+	}
+
+	return validateTopics(d.Taxonomy.Topics, make(map[string]bool))
+}
+
+func validateTopics(topics []models.Topic, seen map[string]bool) error {
+	for _, topic := range topics {
+		if topic.Title == "" || topic.FormattedTitle == "" {
+			return ErrMissingTopicTitle
+		}
+
+		if seen[topic.FormattedTitle] {
+			return ErrDuplicateTopicFilter
+		}
+		seen[topic.FormattedTitle] = true
+
+		if err := validateTopics(topic.ChildTopics, seen); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,186 @@
+package reference_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-census-alpha-search-api/models"
+	"github.com/ONSdigital/dp-census-alpha-search-api/reference"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const (
+	dimensionsJSON  = `{"items":[{"label":"Sex","name":"sex"}],"total_count":1}`
+	hierarchiesJSON = `{"items":[{"hierarchy":"Countries","filterable_hierarchy":"countries","level":1},{"hierarchy":"Output Areas","filterable_hierarchy":"outputareas","level":2}],"total_count":2}`
+	taxonomyJSON    = `{"topics":[{"title":"Economy","filterable_title":"economy","child_topics":[{"title":"Inflation","filterable_title":"inflation"}]}]}`
+)
+
+func writeFiles(dir, dimensions, hierarchies, taxonomy string) reference.Files {
+	files := reference.Files{
+		Dimensions:  filepath.Join(dir, "dimensions.json"),
+		Hierarchies: filepath.Join(dir, "hierarchy.json"),
+		Taxonomy:    filepath.Join(dir, "taxonomy.json"),
+	}
+
+	So(ioutil.WriteFile(files.Dimensions, []byte(dimensions), 0644), ShouldBeNil)
+	So(ioutil.WriteFile(files.Hierarchies, []byte(hierarchies), 0644), ShouldBeNil)
+	So(ioutil.WriteFile(files.Taxonomy, []byte(taxonomy), 0644), ShouldBeNil)
+
+	return files
+}
+
+// waitForFailure waits up to a second for the last reload of the store to fail or succeed,
+// returning when it failed
+func waitForFailure(store *reference.Store, failed bool) time.Time {
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if failedAt, err := store.LastFailure(); (err != nil) == failed {
+			return failedAt
+		}
+	}
+
+	return time.Time{}
+}
+
+func TestReload(t *testing.T) {
+	Convey("Given a store loaded from valid reference files", t, func() {
+		dir, err := ioutil.TempDir("", "reference")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		files := writeFiles(dir, dimensionsJSON, hierarchiesJSON, taxonomyJSON)
+
+		store, err := reference.New(files)
+		So(err, ShouldBeNil)
+		So(store.Get().Taxonomy.Topics[0].FormattedTitle, ShouldEqual, "economy")
+		So(store.Get().HierarchyLevels, ShouldResemble, models.HierarchyLevels{"Countries": 1, "Output Areas": 2})
+
+		Convey("When the taxonomy file is replaced with a valid taxonomy and reloaded", func() {
+			writeFiles(dir, dimensionsJSON, hierarchiesJSON, `{"topics":[{"title":"Business","filterable_title":"business"}]}`)
+
+			docs, err := store.Reload(context.Background())
+
+			Convey("Then the new taxonomy is returned", func() {
+				So(err, ShouldBeNil)
+				So(docs.Taxonomy.Topics[0].FormattedTitle, ShouldEqual, "business")
+				So(store.Get(), ShouldEqual, docs)
+			})
+		})
+
+		Convey("When a file is replaced with invalid content and reloaded", func() {
+			previous := store.Get()
+			writeFiles(dir, dimensionsJSON, `{"items":[{"hierarchy":"Countries"}]}`, taxonomyJSON)
+
+			docs, err := store.Reload(context.Background())
+
+			Convey("Then an error is returned and the previous version is kept", func() {
+				So(err, ShouldEqual, reference.ErrMissingHierarchyName)
+				So(docs, ShouldBeNil)
+				So(store.Get(), ShouldEqual, previous)
+			})
+		})
+
+		Convey("When a file contains invalid json and is reloaded", func() {
+			previous := store.Get()
+			writeFiles(dir, `{"items":`, hierarchiesJSON, taxonomyJSON)
+
+			_, err := store.Reload(context.Background())
+
+			Convey("Then an error is returned and the previous version is kept", func() {
+				So(err, ShouldNotBeNil)
+				So(store.Get(), ShouldEqual, previous)
+			})
+		})
+
+		Convey("When a listener is registered and the files are reloaded", func() {
+			var reloaded []*reference.Docs
+			store.OnReload(func(docs *reference.Docs) {
+				reloaded = append(reloaded, docs)
+			})
+
+			docs, err := store.Reload(context.Background())
+			So(err, ShouldBeNil)
+
+			writeFiles(dir, `{"items":`, hierarchiesJSON, taxonomyJSON)
+			_, err = store.Reload(context.Background())
+			So(err, ShouldNotBeNil)
+
+			Convey("Then the listener is only called with the new version after a successful reload", func() {
+				So(reloaded, ShouldHaveLength, 1)
+				So(reloaded[0], ShouldEqual, docs)
+			})
+		})
+	})
+
+	Convey("Given a store loaded from valid reference files", t, func() {
+		dir, err := ioutil.TempDir("", "reference")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		files := writeFiles(dir, dimensionsJSON, hierarchiesJSON, taxonomyJSON)
+
+		store, err := reference.New(files)
+		So(err, ShouldBeNil)
+		previous := store.Get()
+
+		Convey("When a file is replaced with invalid json while the files are watched", func() {
+			writeFiles(dir, `{"items":`, hierarchiesJSON, taxonomyJSON)
+			So(os.Chtimes(files.Dimensions, time.Now(), time.Now().Add(time.Minute)), ShouldBeNil)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go store.Watch(ctx, 10*time.Millisecond)
+
+			failedAt := waitForFailure(store, true)
+
+			Convey("Then the broken file is only reloaded once until it changes", func() {
+				So(failedAt.IsZero(), ShouldBeFalse)
+				So(store.Get(), ShouldEqual, previous)
+
+				time.Sleep(100 * time.Millisecond)
+				lastFailedAt, err := store.LastFailure()
+				So(err, ShouldNotBeNil)
+				So(lastFailedAt, ShouldEqual, failedAt)
+
+				Convey("And the files are reloaded once the file is fixed", func() {
+					writeFiles(dir, dimensionsJSON, hierarchiesJSON, taxonomyJSON)
+					So(os.Chtimes(files.Dimensions, time.Now(), time.Now().Add(2*time.Minute)), ShouldBeNil)
+
+					waitForFailure(store, false)
+					So(store.Get(), ShouldNotEqual, previous)
+				})
+			})
+		})
+	})
+
+	Convey("Given a hierarchy without a level", t, func() {
+		dir, err := ioutil.TempDir("", "reference")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		files := writeFiles(dir, dimensionsJSON, `{"items":[{"hierarchy":"Countries","filterable_hierarchy":"countries"}],"total_count":1}`, taxonomyJSON)
+
+		Convey("When the store is created then the hierarchies are rejected", func() {
+			store, err := reference.New(files)
+			So(store, ShouldBeNil)
+			So(err, ShouldEqual, reference.ErrMissingHierarchyLevel)
+		})
+	})
+
+	Convey("Given a taxonomy with the same topic at two levels", t, func() {
+		dir, err := ioutil.TempDir("", "reference")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		files := writeFiles(dir, dimensionsJSON, hierarchiesJSON, `{"topics":[{"title":"Economy","filterable_title":"economy","child_topics":[{"title":"Economy","filterable_title":"economy"}]}]}`)
+
+		Convey("When the store is created then the taxonomy is rejected", func() {
+			store, err := reference.New(files)
+			So(store, ShouldBeNil)
+			So(err, ShouldEqual, reference.ErrDuplicateTopicFilter)
+		})
+	})
+}
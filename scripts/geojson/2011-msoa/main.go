@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"flag"
 	"io"
 	"io/ioutil"
 	"math/rand"
@@ -23,16 +24,18 @@ import (
 const (
 	elasticsearchAPIURL = "http://localhost:9200"
 	features            = "features"
-	geoFileIndex        = "area-profiles"
+	geoFileIndex        = "area-profiles-next" // the staging alias created by the refresh script
 	geoJSONPath         = "../geojson/"
 	port                = "10300"
 	documentType        = "area_profile"
+
+	defaultBulkRetries     = 3
+	defaultRejectsFilename = "msoa-rejects.jsonl"
 )
 
 var (
-	countCh             = make(chan int)
-	polygonCountCh      = make(chan int)
-	multiPolygonCountCh = make(chan int)
+	bulkRetries, bulkSize, bulkWorkers int
+	rejectsFilename                    string
 
 	geojsonfiles = []string{
 		"Middle_Layer_Super_Output_Areas__December_2011__Boundaries_EW_BGC.geojson",
@@ -41,11 +44,28 @@ var (
 
 func main() {
 	ctx := context.Background()
+	flag.IntVar(&bulkRetries, "bulk-retries", defaultBulkRetries, "the number of times documents rejected because elasticsearch is overloaded are sent again")
+	flag.IntVar(&bulkSize, "bulk-size", es.DefaultBulkSize, "the most documents sent in each bulk request")
+	flag.IntVar(&bulkWorkers, "bulk-workers", es.DefaultBulkWorkers, "the number of bulk requests sent to elasticsearch at the same time")
+	flag.StringVar(&rejectsFilename, "rejects-filename", defaultRejectsFilename, "the file that documents elasticsearch failed to index are written to")
+	flag.Parse()
 
 	cli := dphttp.NewClient()
 	esAPI := es.NewElasticSearchAPI(cli, elasticsearchAPIURL)
-
-	go trackCounts(ctx)
+	rejects := es.NewRejectFile(rejectsFilename)
+
+	indexer, err := es.NewBulkIndexer(ctx, esAPI, es.BulkIndexerConfig{
+		Index:      geoFileIndex,
+		Workers:    bulkWorkers,
+		Size:       bulkSize,
+		Retries:    bulkRetries,
+		Rejects:    rejects,
+		OnProgress: es.LogProgress(ctx, 5*time.Second),
+	})
+	if err != nil {
+		log.Event(ctx, "failed to create bulk indexer", log.FATAL, log.Error(err))
+		os.Exit(1)
+	}
 
 	log.Event(ctx, "about to read in geojson", log.INFO)
 
@@ -63,38 +83,29 @@ func main() {
 		log.Event(ctx, "about to store docs in elastic search", log.INFO)
 
 		// Iterate items for individual geo boundaries and store documents in elasticsearch
-		if err = storeDocs(ctx, esAPI, geoFileIndex, parser); err != nil {
+		if err = storeDocs(ctx, indexer, parser); err != nil {
 			log.Event(ctx, "failed to store msoa data in elasticsearch", log.FATAL, log.Error(err))
 			os.Exit(1)
 		}
 	}
 
-	log.Event(ctx, "successfully added 2011 msoa data to "+geoFileIndex+" index", log.INFO)
-}
+	// send the documents waiting in the last batch
+	if err = indexer.Close(ctx); err != nil {
+		log.Event(ctx, "failed to store msoa data in elasticsearch", log.FATAL, log.Error(err))
+		os.Exit(1)
+	}
 
-func trackCounts(ctx context.Context) {
-	var (
-		totalCounter        = 0
-		polygonCounter      = 0
-		multiPolygonCounter = 0
-	)
-
-	t := time.NewTicker(5 * time.Second)
-
-	for {
-		select {
-		case n := <-countCh:
-			totalCounter += n
-		case n := <-polygonCountCh:
-			polygonCounter += n
-		case n := <-multiPolygonCountCh:
-			multiPolygonCounter += n
-		case n := <-countCh:
-			totalCounter += n
-		case <-t.C:
-			log.Event(ctx, "Total uploaded: "+strconv.Itoa(totalCounter)+" | Polygons: "+strconv.Itoa(polygonCounter)+" | MultiPolygons: "+strconv.Itoa(multiPolygonCounter), log.INFO)
-		}
+	if err = rejects.Close(); err != nil {
+		log.Event(ctx, "failed to close rejects file", log.ERROR, log.Error(err), log.Data{"filename": rejects.Filename()})
 	}
+
+	if rejects.Rejected() > 0 {
+		log.Event(ctx, "elasticsearch failed to index some documents, see the rejects file", log.WARN, log.Data{"rejected": rejects.Rejected(), "filename": rejects.Filename()})
+	}
+
+	stats := indexer.Stats()
+
+	log.Event(ctx, "successfully added 2011 msoa data to "+geoFileIndex+" index", log.INFO, log.Data{"indexed": stats.Indexed, "failed": stats.Failed})
 }
 
 func createGeoDoc(reader io.Reader) (*models.GeoDocs, error) {
@@ -112,15 +123,14 @@ func createGeoDoc(reader io.Reader) (*models.GeoDocs, error) {
 	return &geoDocs, nil
 }
 
-func storeDocs(ctx context.Context, esAPI *es.API, indexName string, parser *jsparser.JsonParser) error {
-	count := 0
+func storeDocs(ctx context.Context, indexer *es.BulkIndexer, parser *jsparser.JsonParser) error {
+	position := 0
 	polygonCount := 0
 	multiPolygonCount := 0
-	var geoDocs []interface{}
 
 	// Iterate through the records
 	for feature := range parser.Stream() {
-		count++
+		position++
 
 		sA := feature.ObjectVals["properties"].(*jsparser.JSON).ObjectVals["st_areashape"].(string)
 		statedArea, err := strconv.ParseFloat(sA, 64)
@@ -223,46 +233,20 @@ func storeDocs(ctx context.Context, esAPI *es.API, indexName string, parser *jsp
 
 		}
 		if err != nil {
-			log.Event(ctx, "failed to get coordinates", log.ERROR, log.Error(err), log.Data{"count": count})
+			log.Event(ctx, "failed to get coordinates", log.ERROR, log.Error(err), log.Data{"position": position})
 			return err
 		}
 
-		geoDocs = append(geoDocs, newDoc)
-
-		if count == 100 {
-			if _, err := esAPI.BulkRequest(ctx, indexName, geoDocs); err != nil {
-				log.Event(ctx, "failed to upload document to index", log.ERROR, log.Error(err), log.Data{"count": count})
-				return err
-			}
-
-			countCh <- count
-			polygonCountCh <- polygonCount
-			multiPolygonCountCh <- multiPolygonCount
-
-			count = 0
-			polygonCount = 0
-			multiPolygonCount = 0
-			geoDocs = nil
-		}
-	}
+		newDoc.Centroid = models.Centroid(newDoc.Location.Coordinates)
 
-	// Capture last bulk
-	if count != 0 {
-		if _, err := esAPI.BulkRequest(ctx, indexName, geoDocs); err != nil {
-			log.Event(ctx, "failed to upload document to index", log.ERROR, log.Error(err), log.Data{"count": count})
+		if err = indexer.Add(ctx, position, newDoc); err != nil {
+			log.Event(ctx, "failed to upload document to index", log.ERROR, log.Error(err), log.Data{"position": position})
 			return err
 		}
-
-		countCh <- count
-		polygonCountCh <- polygonCount
-		multiPolygonCountCh <- multiPolygonCount
-
-		count = 0
-		polygonCount = 0
-		multiPolygonCount = 0
-		geoDocs = nil
 	}
 
+	log.Event(ctx, "read every feature of the geojson file", log.INFO, log.Data{"features": position, "polygons": polygonCount, "multi_polygons": multiPolygonCount})
+
 	return nil
 }
 
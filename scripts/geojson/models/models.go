@@ -1,31 +1,40 @@
 package models
 
+import "math"
+
 type GeoDocs struct {
 	Items []GeoDoc `json:"features"`
 }
 
 type GeoDoc struct {
-	ID             string         `json:"id"`
-	Name           string         `json:"name"`
-	Code           string         `json:"code"`
-	Datasets       Datasets       `json:"datasets"`
-	DocType        string         `json:"doc_type"`
-	Hierarchy      string         `json:"hierarchy"`
-	LAD11CD        string         `json:"lad11cd,omitempty"`
-	Links          Links          `json:"links"`
-	Location       GeoLocation    `json:"location"`
-	LSOA11NM       string         `json:"lsoa11nm,omitempty"`
-	LSOA11NMW      string         `json:"lsoa11nmw,omitempty"`
-	MSOA11NM       string         `json:"msoa11nm,omitempty"`
-	MSOA11NMW      string         `json:"msoa11nmw,omitempty"`
-	OA11CD         string         `json:"oa11cd,omitempty"`
-	ShapeArea      float64        `json:"shape_area,omitempty"`
-	ShapeLength    float64        `json:"shape_length,omitempty"`
-	StatedArea     float64        `json:"stated_area,omitempty"`
-	StatedLength   float64        `json:"stated_length,omitempty"`
-	Statistics     []Statistic    `json:"statistics"`
-	TCITY15NM      string         `json:"tcity15nm,omitempty"`
-	Visualisations Visualisations `json:"visualisation"`
+	ID             string           `json:"id"`
+	Name           string           `json:"name"`
+	Centroid       *CoordinatePoint `json:"centroid,omitempty"`
+	Code           string           `json:"code"`
+	Datasets       Datasets         `json:"datasets"`
+	DocType        string           `json:"doc_type"`
+	Hierarchy      string           `json:"hierarchy"`
+	LAD11CD        string           `json:"lad11cd,omitempty"`
+	Links          Links            `json:"links"`
+	Location       GeoLocation      `json:"location"`
+	LSOA11NM       string           `json:"lsoa11nm,omitempty"`
+	LSOA11NMW      string           `json:"lsoa11nmw,omitempty"`
+	MSOA11NM       string           `json:"msoa11nm,omitempty"`
+	MSOA11NMW      string           `json:"msoa11nmw,omitempty"`
+	OA11CD         string           `json:"oa11cd,omitempty"`
+	ShapeArea      float64          `json:"shape_area,omitempty"`
+	ShapeLength    float64          `json:"shape_length,omitempty"`
+	StatedArea     float64          `json:"stated_area,omitempty"`
+	StatedLength   float64          `json:"stated_length,omitempty"`
+	Statistics     []Statistic      `json:"statistics"`
+	TCITY15NM      string           `json:"tcity15nm,omitempty"`
+	Visualisations Visualisations   `json:"visualisation"`
+}
+
+// DocumentID returns the id the area profile is stored with, so that it can be updated or
+// deleted by id through the admin api
+func (g GeoDoc) DocumentID() string {
+	return g.ID
 }
 
 type GeoLocation struct {
@@ -77,3 +86,41 @@ type CoordinatePoint struct {
 	Latitude  float64 `json:"lat"`
 	Longitude float64 `json:"lon"`
 }
+
+// Centroid calculates the centre of the bounding box around a polygon or multipolygon, coordinates
+// are expected in geojson order [longitude, latitude]
+func Centroid(coordinates interface{}) *CoordinatePoint {
+	var points [][]float64
+
+	switch c := coordinates.(type) {
+	case [][][]float64:
+		for _, ring := range c {
+			points = append(points, ring...)
+		}
+	case [][][][]float64:
+		for _, polygon := range c {
+			for _, ring := range polygon {
+				points = append(points, ring...)
+			}
+		}
+	}
+
+	if len(points) < 1 {
+		return nil
+	}
+
+	minLon, minLat := points[0][0], points[0][1]
+	maxLon, maxLat := minLon, minLat
+
+	for _, point := range points {
+		minLon = math.Min(minLon, point[0])
+		maxLon = math.Max(maxLon, point[0])
+		minLat = math.Min(minLat, point[1])
+		maxLat = math.Max(maxLat, point[1])
+	}
+
+	return &CoordinatePoint{
+		Latitude:  (minLat + maxLat) / 2,
+		Longitude: (minLon + maxLon) / 2,
+	}
+}
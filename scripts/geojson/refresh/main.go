@@ -2,10 +2,16 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"math"
 	"net/http"
 	"os"
+	"time"
 
 	es "github.com/ONSdigital/dp-census-alpha-search-api/internal/elasticsearch"
+	"github.com/ONSdigital/dp-census-alpha-search-api/internal/searchapi"
+	"github.com/ONSdigital/dp-census-alpha-search-api/models"
 	dphttp "github.com/ONSdigital/dp-net/http"
 	"github.com/ONSdigital/log.go/log"
 )
@@ -14,31 +20,123 @@ const (
 	elasticsearchAPIURL = "http://localhost:9200"
 	geoFileIndex        = "area-profiles"
 	mappingsFile        = "geography-mappings.json"
+	defaultGenerations  = 2
+	defaultMinRatio     = 0.95
+
+	// stagingAlias points at the new generation of the index while the geojson scripts load
+	// documents into it, the scripts write to this alias rather than the live index
+	stagingAlias = geoFileIndex + "-next"
+)
+
+var (
+	expected, generations int
+	minRatio              float64
+	publish               bool
+	searchAPIURL          string
 )
 
 func main() {
 	ctx := context.Background()
+	flag.BoolVar(&publish, "publish", false, "publish the index loaded by the geojson scripts instead of creating a new index")
+	flag.IntVar(&expected, "expected-documents", 0, "the least number of documents the index must contain to be published, overrides -min-ratio")
+	flag.Float64Var(&minRatio, "min-ratio", defaultMinRatio, "the least number of documents the index must contain to be published as a share of the documents in the published index")
+	flag.IntVar(&generations, "generations", defaultGenerations, "the number of previous generations of the index to keep for rollback")
+	flag.StringVar(&searchAPIURL, "search-api-url", searchapi.DefaultURL, "the url of the search api whose cache of search responses is purged once the index is published")
+	flag.Parse()
 
 	cli := dphttp.NewClient()
 	esAPI := es.NewElasticSearchAPI(cli, elasticsearchAPIURL)
 
-	// delete existing elasticsearch index if already exists
-	status, err := esAPI.DeleteSearchIndex(ctx, geoFileIndex)
-	if err != nil {
-		if status != http.StatusNotFound {
-			log.Event(ctx, "failed to delete index", log.ERROR, log.Error(err), log.Data{"status": status})
+	if publish {
+		if err := publishIndex(ctx, esAPI); err != nil {
+			log.Event(ctx, "failed to publish "+geoFileIndex+" index, the previous index is still in use", log.ERROR, log.Error(err))
+			os.Exit(1)
+		}
+
+		if err := searchapi.PurgeAfterPublish(ctx, cli, searchAPIURL, geoFileIndex); err != nil {
 			os.Exit(1)
 		}
 
-		log.Event(ctx, "failed to delete index as index cannot be found, continuing", log.WARN, log.Error(err), log.Data{"status": status})
+		log.Event(ctx, "successfully published "+geoFileIndex+" index", log.INFO)
+		return
+	}
+
+	// remove the generations left behind by runs that failed before publishing
+	if err := esAPI.DeleteUnpublishedGenerations(ctx, geoFileIndex); err != nil {
+		log.Event(ctx, "failed to delete unpublished generations of index", log.ERROR, log.Error(err), log.Data{"alias": geoFileIndex})
+		os.Exit(1)
 	}
 
+	// build a new generation of the index, the alias keeps pointing at the current index
+	// until the index is published
+	indexName := es.VersionedIndex(geoFileIndex, time.Now())
+
 	// create elasticsearch index with settings/mapping
-	status, err = esAPI.CreateSearchIndex(ctx, geoFileIndex, mappingsFile)
+	status, err := esAPI.CreateSearchIndex(ctx, indexName, mappingsFile)
 	if err != nil {
-		log.Event(ctx, "failed to create index", log.ERROR, log.Error(err), log.Data{"status": status})
+		log.Event(ctx, "failed to create index", log.ERROR, log.Error(err), log.Data{"status": status, "index": indexName})
 		os.Exit(1)
 	}
 
-	log.Event(ctx, "successfully refreshed "+geoFileIndex+" index", log.INFO)
+	if _, status, err = esAPI.SwapAlias(ctx, stagingAlias, indexName); err != nil {
+		log.Event(ctx, "failed to point staging alias at index", log.ERROR, log.Error(err), log.Data{"status": status, "index": indexName, "alias": stagingAlias})
+		os.Exit(1)
+	}
+
+	log.Event(ctx, "successfully created "+indexName+" index, run the geojson scripts and then publish the index", log.INFO, log.Data{"alias": stagingAlias})
+}
+
+// publishIndex points the alias at the index loaded through the staging alias and removes the
+// staging alias
+func publishIndex(ctx context.Context, esAPI *es.API) error {
+	indices, status, err := esAPI.GetAliasedIndices(ctx, stagingAlias)
+	if err != nil {
+		log.Event(ctx, "failed to get index to publish", log.ERROR, log.Error(err), log.Data{"status": status, "alias": stagingAlias})
+		return err
+	}
+
+	if len(indices) != 1 {
+		log.Event(ctx, "staging alias should point at a single index", log.ERROR, log.Data{"alias": stagingAlias, "indices": indices})
+		return errors.New("staging alias should point at a single index")
+	}
+
+	indexName := indices[0]
+
+	expectedDocuments, err := expectedDocumentCount(ctx, esAPI)
+	if err != nil {
+		return err
+	}
+
+	if err = esAPI.PublishIndex(ctx, geoFileIndex, indexName, expectedDocuments, generations); err != nil {
+		return err
+	}
+
+	if status, err = esAPI.UpdateAliases(ctx, []models.AliasAction{{Remove: &models.AliasIndex{Index: indexName, Alias: stagingAlias}}}); err != nil {
+		log.Event(ctx, "failed to remove staging alias", log.ERROR, log.Error(err), log.Data{"status": status, "alias": stagingAlias, "index": indexName})
+		return err
+	}
+
+	return nil
+}
+
+// expectedDocumentCount returns the least number of documents the new index must contain, a
+// share of the documents in the published index unless set with a flag, so that an index the
+// geojson scripts only partly loaded is never published
+func expectedDocumentCount(ctx context.Context, esAPI *es.API) (int, error) {
+	if expected > 0 {
+		return expected, nil
+	}
+
+	count, status, err := esAPI.CountDocuments(ctx, geoFileIndex)
+	if err != nil {
+		if status == http.StatusNotFound {
+			log.Event(ctx, "no published index to compare documents against, the index only needs to contain documents", log.WARN, log.Data{"alias": geoFileIndex})
+			return 0, nil
+		}
+
+		log.Event(ctx, "failed to count documents in published index", log.ERROR, log.Error(err), log.Data{"status": status, "alias": geoFileIndex})
+		return 0, err
+	}
+
+	return int(math.Ceil(float64(count) * minRatio)), nil
 }
@@ -11,18 +11,20 @@ import (
 
 const hierarchyFilename = "../data/hierarchy.json"
 
-var geoHierarchyMap = map[string]string{
-	"Lower Layer Super Output Areas":  "lowerlayersuperoutputareas",
-	"Middle Layer Super Output Areas": "middlelayersuperoutputareas",
-	"Output Areas":                    "outputareas",
-	"Major Towns and Cities":          "majortownsandcities",
-	"Countries":                       "countries",
+// geoHierarchies lists the hierarchies in order from the largest areas down to the smallest,
+// which gives the level of each hierarchy used to sort area profiles
+var geoHierarchies = []GeographyObject{
+	{Hierarchy: "Countries", FilterableHierarchy: "countries"},
+	{Hierarchy: "Major Towns and Cities", FilterableHierarchy: "majortownsandcities"},
+	{Hierarchy: "Middle Layer Super Output Areas", FilterableHierarchy: "middlelayersuperoutputareas"},
+	{Hierarchy: "Lower Layer Super Output Areas", FilterableHierarchy: "lowerlayersuperoutputareas"},
+	{Hierarchy: "Output Areas", FilterableHierarchy: "outputareas"},
 }
 
 func main() {
 	ctx := context.Background()
 
-	hierarchyList := createGeoHierarchyList(ctx, geoHierarchyMap)
+	hierarchyList := createGeoHierarchyList(ctx, geoHierarchies)
 	// Store hierarchies to a file
 	file, err := json.MarshalIndent(hierarchyList, "", "  ")
 	if err != nil {
@@ -48,15 +50,14 @@ type GeoHierarchiesDoc struct {
 type GeographyObject struct {
 	Hierarchy           string `json:"hierarchy"`
 	FilterableHierarchy string `json:"filterable_hierarchy"`
+	Level               int    `json:"level"`
 }
 
-func createGeoHierarchyList(ctx context.Context, geoHierarchyMap map[string]string) GeoHierarchiesDoc {
+func createGeoHierarchyList(ctx context.Context, geoHierarchies []GeographyObject) GeoHierarchiesDoc {
 	var hierarchies []GeographyObject
-	for k, v := range geoHierarchyMap {
-		hierarchies = append(hierarchies, GeographyObject{
-			Hierarchy:           k,
-			FilterableHierarchy: v,
-		})
+	for i, hierarchy := range geoHierarchies {
+		hierarchy.Level = i + 1
+		hierarchies = append(hierarchies, hierarchy)
 	}
 
 	return GeoHierarchiesDoc{
@@ -4,14 +4,15 @@ import (
 	"context"
 	"encoding/csv"
 	"errors"
+	"flag"
 	"io"
-	"net/http"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 
 	es "github.com/ONSdigital/dp-census-alpha-search-api/internal/elasticsearch"
+	"github.com/ONSdigital/dp-census-alpha-search-api/internal/searchapi"
 	dphttp "github.com/ONSdigital/dp-net/http"
 
 	"github.com/ONSdigital/dp-census-alpha-search-api/scripts/load-postcodes/models"
@@ -22,47 +23,110 @@ const (
 	elasticsearchAPIURL = "http://localhost:9200"
 	postcodeIndex       = "postcodes"
 	mappingsFile        = "postcode-mappings.json"
+	defaultGenerations  = 2
+
+	defaultBulkRetries     = 3
+	defaultMaxRejects      = 0
+	defaultRejectsFilename = "postcodes-rejects.jsonl"
 )
 
 var (
 	root = "../NSPL_FEB_2020_UK/Data/NSPL_FEB_2020_UK.csv"
 
-	countCh = make(chan int)
+	bulkRetries, bulkSize, bulkWorkers int
+	generations, maxRejects            int
+	rejectsFilename                    string
+	searchAPIURL                       string
 )
 
 func main() {
 	ctx := context.Background()
+	flag.IntVar(&bulkRetries, "bulk-retries", defaultBulkRetries, "the number of times documents rejected because elasticsearch is overloaded are sent again")
+	flag.IntVar(&bulkSize, "bulk-size", es.DefaultBulkSize, "the most documents sent in each bulk request")
+	flag.IntVar(&bulkWorkers, "bulk-workers", es.DefaultBulkWorkers, "the number of bulk requests sent to elasticsearch at the same time")
+	flag.IntVar(&generations, "generations", defaultGenerations, "the number of previous generations of the index to keep for rollback")
+	flag.IntVar(&maxRejects, "max-rejects", defaultMaxRejects, "the most postcodes elasticsearch can fail to index before the index is not published")
+	flag.StringVar(&rejectsFilename, "rejects-filename", defaultRejectsFilename, "the file that documents elasticsearch failed to index are written to")
+	flag.StringVar(&searchAPIURL, "search-api-url", searchapi.DefaultURL, "the url of the search api whose cache of search responses is purged once the index is published")
+	flag.Parse()
 
 	cli := dphttp.NewClient()
 	esAPI := es.NewElasticSearchAPI(cli, elasticsearchAPIURL)
 
-	// delete existing elasticsearch index if already exists
-	status, err := esAPI.DeleteSearchIndex(ctx, postcodeIndex)
-	if err != nil {
-		if status != http.StatusNotFound {
-			log.Event(ctx, "failed to delete index", log.ERROR, log.Error(err), log.Data{"status": status})
-			os.Exit(1)
-		}
-
-		log.Event(ctx, "failed to delete index as index cannot be found, continuing", log.WARN, log.Error(err), log.Data{"status": status})
+	// remove the generations left behind by runs that failed before publishing
+	if err := esAPI.DeleteUnpublishedGenerations(ctx, postcodeIndex); err != nil {
+		log.Event(ctx, "failed to delete unpublished generations of index", log.ERROR, log.Error(err), log.Data{"alias": postcodeIndex})
+		os.Exit(1)
 	}
 
+	// build a new generation of the index, the alias keeps pointing at the current index
+	// until the new index has been verified
+	indexName := es.VersionedIndex(postcodeIndex, time.Now())
+
 	// create elasticsearch index with settings/mapping
-	status, err = esAPI.CreateSearchIndex(ctx, postcodeIndex, mappingsFile)
+	status, err := esAPI.CreateSearchIndex(ctx, indexName, mappingsFile)
 	if err != nil {
-		log.Event(ctx, "failed to create index", log.ERROR, log.Error(err), log.Data{"status": status})
+		log.Event(ctx, "failed to create index", log.ERROR, log.Error(err), log.Data{"status": status, "index": indexName})
+		os.Exit(1)
+	}
+
+	rejects := es.NewRejectFile(rejectsFilename)
+
+	indexer, err := es.NewBulkIndexer(ctx, esAPI, es.BulkIndexerConfig{
+		Index:      indexName,
+		Workers:    bulkWorkers,
+		Size:       bulkSize,
+		Retries:    bulkRetries,
+		Rejects:    rejects,
+		OnProgress: es.LogProgress(ctx, 5*time.Second),
+	})
+	if err != nil {
+		log.Event(ctx, "failed to create bulk indexer", log.ERROR, log.Error(err), log.Data{"index": indexName})
+		os.Exit(1)
+	}
+
+	if err = getPostcodeData(ctx, indexer, root); err != nil {
+		log.Event(ctx, "failed to get all postcode data into index", log.ERROR, log.Error(err), log.Data{"index": indexName})
+		os.Exit(1)
+	}
+
+	// send the postcodes waiting in the last batch
+	if err = indexer.Close(ctx); err != nil {
+		log.Event(ctx, "failed to get all postcode data into index", log.ERROR, log.Error(err), log.Data{"index": indexName})
+		os.Exit(1)
+	}
+
+	stats := indexer.Stats()
+
+	if err = rejects.Close(); err != nil {
+		log.Event(ctx, "failed to close rejects file", log.ERROR, log.Error(err), log.Data{"filename": rejects.Filename()})
+	}
+
+	if rejects.Rejected() > 0 {
+		log.Event(ctx, "elasticsearch failed to index some postcodes, see the rejects file", log.WARN, log.Data{"rejected": rejects.Rejected(), "filename": rejects.Filename()})
+	}
+
+	if stats.Failed > maxRejects {
+		log.Event(ctx, "not publishing postcode index as too many postcodes were rejected, the previous index is still in use", log.ERROR, log.Data{"rejected": stats.Failed, "max_rejects": maxRejects, "filename": rejects.Filename(), "index": indexName})
 		os.Exit(1)
 	}
 
-	go trackCounts(ctx)
+	// every postcode read from the file must be in the index apart from the rejected postcodes
+	if err = esAPI.PublishIndex(ctx, postcodeIndex, indexName, stats.Added-stats.Failed, generations); err != nil {
+		log.Event(ctx, "failed to publish postcode index, the previous index is still in use", log.ERROR, log.Error(err), log.Data{"index": indexName})
+		os.Exit(1)
+	}
 
-	if err = getPostcodeData(ctx, esAPI, postcodeIndex, root); err != nil {
-		log.Event(ctx, "failed to get all postcode data into index", log.ERROR, log.Error(err))
+	if err = searchapi.PurgeAfterPublish(ctx, cli, searchAPIURL, postcodeIndex); err != nil {
 		os.Exit(1)
 	}
+
+	log.Event(ctx, "successfully loaded in postcode docs", log.INFO, log.Data{"count": stats.Indexed, "index": indexName})
 }
 
-func getPostcodeData(ctx context.Context, esAPI *es.API, indexName, filename string) error {
+// getPostcodeData reads every postcode in the csv file and adds it to the indexer along with
+// its row of the file
+func getPostcodeData(ctx context.Context, indexer *es.BulkIndexer, filename string) error {
 	csvfile, err := os.Open(filename)
 	if err != nil {
 		log.Event(ctx, "failed to open the csv file", log.ERROR, log.Error(err))
@@ -96,21 +160,20 @@ func getPostcodeData(ctx context.Context, esAPI *es.API, indexName, filename str
 		return errors.New("missing latitude or longitude header")
 	}
 
-	count := 0
-
-	var postcodeDocs []interface{}
+	// the header is row 1
+	line := 1
 
 	// Iterate through the records
 	for {
-		count++
+		line++
 		// Read each record from csv
 		row, err := r.Read()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			log.Event(ctx, "failed to read row", log.ERROR, log.Error(err))
-			break
+			log.Event(ctx, "failed to read row", log.ERROR, log.Error(err), log.Data{"row": line})
+			return err
 		}
 
 		lat, err := convertCoordinate(row[latcol])
@@ -141,32 +204,10 @@ func getPostcodeData(ctx context.Context, esAPI *es.API, indexName, filename str
 			},
 		}
 
-		postcodeDocs = append(postcodeDocs, postcodeDoc)
-
-		if count == 500 {
-			if _, err = esAPI.BulkRequest(ctx, indexName, postcodeDocs); err != nil {
-				log.Event(ctx, "failed to upload document to index", log.ERROR, log.Error(err), log.Data{"count": count})
-				return err
-			}
-
-			countCh <- count
-
-			count = 0
-			postcodeDocs = nil
-		}
-	}
-
-	// Capture last bulk
-	if count != 0 {
-		if _, err = esAPI.BulkRequest(ctx, indexName, postcodeDocs); err != nil {
-			log.Event(ctx, "failed to upload document to index", log.ERROR, log.Error(err), log.Data{"count": count})
+		if err = indexer.Add(ctx, line, postcodeDoc); err != nil {
+			log.Event(ctx, "failed to upload document to index", log.ERROR, log.Error(err), log.Data{"row": line})
 			return err
 		}
-
-		countCh <- count
-
-		count = 0
-		postcodeDocs = nil
 	}
 
 	return nil
@@ -177,20 +218,3 @@ func convertCoordinate(coordinate string) (convertedLatLong float64, err error)
 
 	return
 }
-
-func trackCounts(ctx context.Context) {
-	var (
-		totalCounter = 0
-	)
-
-	t := time.NewTicker(5 * time.Second)
-
-	for {
-		select {
-		case n := <-countCh:
-			totalCounter += n
-		case <-t.C:
-			log.Event(ctx, "Total uploaded: "+strconv.Itoa(totalCounter), log.INFO)
-		}
-	}
-}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	es "github.com/ONSdigital/dp-census-alpha-search-api/internal/elasticsearch"
+	"github.com/ONSdigital/dp-census-alpha-search-api/internal/searchapi"
+	dphttp "github.com/ONSdigital/dp-net/http"
+	"github.com/ONSdigital/log.go/log"
+)
+
+const (
+	defaultElasticsearchAPIURL = "http://localhost:9200"
+	defaultGenerations         = 2
+	taskPollInterval           = 5 * time.Second
+)
+
+var (
+	elasticsearchAPIURL, index, mappingsFile string
+	generations                              int
+
+	// mappings files for each of the default indexes
+	defaultMappingsFiles = map[string]string{
+		"area-profiles": "geography-mappings.json",
+		"datasets":      "dataset-mappings.json",
+		"postcodes":     "postcode-mappings.json",
+		"publications":  "publication-mappings.json",
+	}
+	searchAPIURL string
+)
+
+func main() {
+	ctx := context.Background()
+	flag.StringVar(&elasticsearchAPIURL, "elasticsearch-url", defaultElasticsearchAPIURL, "the elasticsearch url")
+	flag.StringVar(&index, "index", "", "the elasticsearch alias of the index to rebuild with the latest mappings")
+	flag.StringVar(&mappingsFile, "mappings-file", "", "the mappings file to rebuild the index with, defaults to the mappings file used to create the index")
+	flag.IntVar(&generations, "generations", defaultGenerations, "the number of previous generations of the index to keep for rollback")
+	flag.StringVar(&searchAPIURL, "search-api-url", searchapi.DefaultURL, "the url of the search api whose cache of search responses is purged once the index is published")
+	flag.Parse()
+
+	if elasticsearchAPIURL == "" {
+		elasticsearchAPIURL = defaultElasticsearchAPIURL
+	}
+
+	if index == "" {
+		log.Event(ctx, "missing index flag, e.g. -index=datasets", log.ERROR)
+		os.Exit(1)
+	}
+
+	if mappingsFile == "" {
+		mappingsFile = defaultMappingsFiles[index]
+	}
+
+	if mappingsFile == "" {
+		log.Event(ctx, "missing mappings-file flag, no default mappings file for index", log.ERROR, log.Data{"index": index})
+		os.Exit(1)
+	}
+
+	log.Event(ctx, "script variables", log.INFO, log.Data{"elasticsearch_api_url": elasticsearchAPIURL, "index": index, "mappings_file": mappingsFile, "generations": generations})
+
+	cli := dphttp.NewClient()
+	esAPI := es.NewElasticSearchAPI(cli, elasticsearchAPIURL)
+
+	// count the documents to copy before copying them, so that the new index can be verified
+	if status, err := esAPI.RefreshIndex(ctx, index); err != nil {
+		log.Event(ctx, "failed to refresh index", log.ERROR, log.Error(err), log.Data{"status": status})
+		os.Exit(1)
+	}
+
+	count, status, err := esAPI.CountDocuments(ctx, index)
+	if err != nil {
+		log.Event(ctx, "failed to count documents in index", log.ERROR, log.Error(err), log.Data{"status": status})
+		os.Exit(1)
+	}
+
+	// remove the generations left behind by runs that failed before publishing
+	if err := esAPI.DeleteUnpublishedGenerations(ctx, index); err != nil {
+		log.Event(ctx, "failed to delete unpublished generations of index", log.ERROR, log.Error(err), log.Data{"alias": index})
+		os.Exit(1)
+	}
+
+	// copy documents into a new generation of the index with the latest mappings, searches
+	// use the current index until the alias is swapped
+	versionedIndex := es.VersionedIndex(index, time.Now())
+
+	if err := copyIndex(ctx, esAPI, index, versionedIndex, mappingsFile); err != nil {
+		log.Event(ctx, "failed to copy documents into new index", log.ERROR, log.Error(err), log.Data{"index": index, "new_index": versionedIndex})
+		os.Exit(1)
+	}
+
+	if err := esAPI.PublishIndex(ctx, index, versionedIndex, count, generations); err != nil {
+		log.Event(ctx, "failed to publish new index, the previous index is still in use", log.ERROR, log.Error(err), log.Data{"index": index, "new_index": versionedIndex})
+		os.Exit(1)
+	}
+
+	if err = searchapi.PurgeAfterPublish(ctx, cli, searchAPIURL, index); err != nil {
+		os.Exit(1)
+	}
+
+	log.Event(ctx, "successfully reindexed "+index+" index", log.INFO, log.Data{"count": count, "new_index": versionedIndex})
+}
+
+// copyIndex creates the destination index with mappings and copies all documents from the source index into it
+func copyIndex(ctx context.Context, esAPI *es.API, sourceIndex, destinationIndex, mappingsFile string) error {
+	logData := log.Data{"source_index": sourceIndex, "destination_index": destinationIndex}
+
+	// delete destination index if left over from a previous failed run
+	status, err := esAPI.DeleteSearchIndex(ctx, destinationIndex)
+	if err != nil {
+		if status != http.StatusNotFound {
+			log.Event(ctx, "failed to delete index", log.ERROR, log.Error(err), log.Data{"status": status})
+			return err
+		}
+
+		log.Event(ctx, "failed to delete index as index cannot be found, continuing", log.WARN, log.Error(err), log.Data{"status": status})
+	}
+
+	status, err = esAPI.CreateSearchIndex(ctx, destinationIndex, mappingsFile)
+	if err != nil {
+		log.Event(ctx, "failed to create index", log.ERROR, log.Error(err), log.Data{"status": status})
+		return err
+	}
+
+	taskID, status, err := esAPI.Reindex(ctx, sourceIndex, destinationIndex)
+	if err != nil {
+		log.Event(ctx, "failed to start reindex", log.ERROR, log.Error(err), log.Data{"status": status})
+		return err
+	}
+
+	logData["task_id"] = taskID
+
+	for {
+		time.Sleep(taskPollInterval)
+
+		task, status, err := esAPI.GetTask(ctx, taskID)
+		if err != nil {
+			log.Event(ctx, "failed to get reindex task", log.ERROR, log.Error(err), log.Data{"status": status})
+			return err
+		}
+
+		if !task.Completed {
+			log.Event(ctx, "Total reindexed: "+strconv.Itoa(task.Task.Status.Created+task.Task.Status.Updated)+" of "+strconv.Itoa(task.Task.Status.Total), log.INFO, logData)
+			continue
+		}
+
+		if task.Error != nil || len(task.Response.Failures) > 0 {
+			logData["task_error"] = task.Error
+			logData["failures"] = task.Response.Failures
+			log.Event(ctx, "reindex task completed with failures", log.ERROR, logData)
+			return errors.New("reindex task completed with failures")
+		}
+
+		logData["total"] = task.Response.Total
+		log.Event(ctx, "reindex task completed", log.INFO, logData)
+
+		return nil
+	}
+}
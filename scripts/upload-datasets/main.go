@@ -8,11 +8,12 @@ import (
 	"flag"
 	"io"
 	"io/ioutil"
-	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	es "github.com/ONSdigital/dp-census-alpha-search-api/internal/elasticsearch"
+	"github.com/ONSdigital/dp-census-alpha-search-api/internal/searchapi"
 	"github.com/ONSdigital/dp-census-alpha-search-api/models"
 	dphttp "github.com/ONSdigital/dp-net/http"
 	"github.com/ONSdigital/log.go/log"
@@ -23,15 +24,22 @@ const (
 	defaultElasticsearchAPIURL = "http://localhost:9200"
 	defaultFilename            = "cmd-datasets.csv"
 	defaultDimensionFile       = "../data/dimensions.json"
+	defaultGenerations         = 2
 	defaultTaxonomyFile        = "../data/taxonomy.json"
 	mappingsFile               = "dataset-mappings.json"
 	documentType               = "dataset"
+	defaultBulkRetries         = 3
+	defaultMaxRejects          = 0
+	defaultRejectsFilename     = "datasets-rejects.jsonl"
 )
 
 var (
 	datasetIndex, elasticsearchAPIURL, filename, dimensionsFilename, taxonomyFilename string
+	rejectsFilename                                                                   string
+	bulkRetries, bulkSize, bulkWorkers, generations, maxRejects                       int
 	taxonomy                                                                          models.Taxonomy
 	topicLevels                                                                       = make(map[string]TopicLevels)
+	searchAPIURL                                                                      string
 )
 
 // Dataset represents the data stored against a resource in elasticsearch index
@@ -48,6 +56,12 @@ type Dataset struct {
 	Topic3      string      `json:"topic3,omitempty"`
 }
 
+// DocumentID returns the alias, datasets are stored under their alias so that the dataset can
+// be updated or deleted through the admin api
+func (d Dataset) DocumentID() string {
+	return d.Alias
+}
+
 // Dimension is an object representing a single dimension
 type Dimension struct {
 	Label string `json:"label"`
@@ -80,11 +94,18 @@ type TopicLevels struct {
 
 func main() {
 	ctx := context.Background()
-	flag.StringVar(&datasetIndex, "dataset-index", defaultDatasetIndex, "the elasticsearch index that datasets will be uploaded to")
+	flag.StringVar(&datasetIndex, "dataset-index", defaultDatasetIndex, "the elasticsearch alias of the index that datasets will be uploaded to")
 	flag.StringVar(&elasticsearchAPIURL, "elasticsearch-url", defaultElasticsearchAPIURL, "the elasticsearch url")
 	flag.StringVar(&filename, "filename", defaultFilename, "the csv filename that contains data to upload to elasticsearch")
 	flag.StringVar(&dimensionsFilename, "dimensions-filename", defaultDimensionFile, "the file locataion and name that contains a list of dataset dimensions")
 	flag.StringVar(&taxonomyFilename, "taxonomy-filename", defaultTaxonomyFile, "the file locataion and name that contains the taxonomy hierarchy")
+	flag.IntVar(&generations, "generations", defaultGenerations, "the number of previous generations of the index to keep for rollback")
+	flag.IntVar(&bulkRetries, "bulk-retries", defaultBulkRetries, "the number of times documents rejected because elasticsearch is overloaded are sent again")
+	flag.IntVar(&bulkSize, "bulk-size", es.DefaultBulkSize, "the most documents sent in each bulk request")
+	flag.IntVar(&bulkWorkers, "bulk-workers", es.DefaultBulkWorkers, "the number of bulk requests sent to elasticsearch at the same time")
+	flag.IntVar(&maxRejects, "max-rejects", defaultMaxRejects, "the most datasets elasticsearch can fail to index before the index is not published")
+	flag.StringVar(&rejectsFilename, "rejects-filename", defaultRejectsFilename, "the file that documents elasticsearch failed to index are written to")
+	flag.StringVar(&searchAPIURL, "search-api-url", searchapi.DefaultURL, "the url of the search api whose cache of search responses is purged once the index is published")
 	flag.Parse()
 
 	if datasetIndex == "" {
@@ -107,7 +128,7 @@ func main() {
 		taxonomyFilename = defaultTaxonomyFile
 	}
 
-	log.Event(ctx, "script variables", log.INFO, log.Data{"dataset_index": datasetIndex, "elasticsearch_api_url": elasticsearchAPIURL, "filename": filename, "dimensions-file": dimensionsFilename, "taxonomy-file": taxonomyFilename})
+	log.Event(ctx, "script variables", log.INFO, log.Data{"dataset_index": datasetIndex, "elasticsearch_api_url": elasticsearchAPIURL, "filename": filename, "dimensions-file": dimensionsFilename, "taxonomy-file": taxonomyFilename, "generations": generations})
 
 	cli := dphttp.NewClient()
 	esAPI := es.NewElasticSearchAPI(cli, elasticsearchAPIURL)
@@ -146,34 +167,83 @@ func main() {
 		}
 	}
 
-	// delete existing elasticsearch index if already exists
-	status, err := esAPI.DeleteSearchIndex(ctx, datasetIndex)
-	if err != nil {
-		if status != http.StatusNotFound {
-			log.Event(ctx, "failed to delete index", log.ERROR, log.Error(err), log.Data{"status": status})
-			os.Exit(1)
-		}
-
-		log.Event(ctx, "failed to delete index as index cannot be found, continuing", log.WARN, log.Error(err), log.Data{"status": status})
+	// remove the generations left behind by runs that failed before publishing
+	if err := esAPI.DeleteUnpublishedGenerations(ctx, datasetIndex); err != nil {
+		log.Event(ctx, "failed to delete unpublished generations of index", log.ERROR, log.Error(err), log.Data{"alias": datasetIndex})
+		os.Exit(1)
 	}
 
+	// build a new generation of the index, the alias keeps pointing at the current index
+	// until the new index has been verified
+	indexName := es.VersionedIndex(datasetIndex, time.Now())
+
 	// create elasticsearch index with settings/mapping
-	status, err = esAPI.CreateSearchIndex(ctx, datasetIndex, mappingsFile)
+	status, err := esAPI.CreateSearchIndex(ctx, indexName, mappingsFile)
+	if err != nil {
+		log.Event(ctx, "failed to create index", log.ERROR, log.Error(err), log.Data{"status": status, "index": indexName})
+		os.Exit(1)
+	}
+
+	rejects := es.NewRejectFile(rejectsFilename)
+
+	indexer, err := es.NewBulkIndexer(ctx, esAPI, es.BulkIndexerConfig{
+		Index:      indexName,
+		Workers:    bulkWorkers,
+		Size:       bulkSize,
+		Retries:    bulkRetries,
+		Rejects:    rejects,
+		OnProgress: es.LogProgress(ctx, 5*time.Second),
+	})
 	if err != nil {
-		log.Event(ctx, "failed to create index", log.ERROR, log.Error(err), log.Data{"status": status})
+		log.Event(ctx, "failed to create bulk indexer", log.ERROR, log.Error(err), log.Data{"index": indexName})
 		os.Exit(1)
 	}
 
 	// upload geo locations from data/datasets-test.csv and manipulate data into models.GeoDoc
-	if err = uploadDocs(ctx, esAPI, datasetIndex, filename); err != nil {
-		log.Event(ctx, "failed to retrieve dataset docs", log.ERROR, log.Error(err))
+	if err = uploadDocs(ctx, indexer, filename); err != nil {
+		log.Event(ctx, "failed to retrieve dataset docs", log.ERROR, log.Error(err), log.Data{"index": indexName})
+		os.Exit(1)
+	}
+
+	// send the datasets waiting in the last batch
+	if err = indexer.Close(ctx); err != nil {
+		log.Event(ctx, "failed to upload dataset docs", log.ERROR, log.Error(err), log.Data{"index": indexName})
+		os.Exit(1)
+	}
+
+	stats := indexer.Stats()
+
+	if err = rejects.Close(); err != nil {
+		log.Event(ctx, "failed to close rejects file", log.ERROR, log.Error(err), log.Data{"filename": rejects.Filename()})
+	}
+
+	if rejects.Rejected() > 0 {
+		log.Event(ctx, "elasticsearch failed to index some datasets, see the rejects file", log.WARN, log.Data{"rejected": rejects.Rejected(), "filename": rejects.Filename()})
+	}
+
+	if stats.Failed > maxRejects {
+		log.Event(ctx, "not publishing dataset index as too many datasets were rejected, the previous index is still in use", log.ERROR, log.Data{"rejected": stats.Failed, "max_rejects": maxRejects, "filename": rejects.Filename(), "index": indexName})
+		os.Exit(1)
+	}
+
+	// every dataset read from the file must be in the index apart from the rejected datasets
+	if err = esAPI.PublishIndex(ctx, datasetIndex, indexName, stats.Added-stats.Failed, generations); err != nil {
+		log.Event(ctx, "failed to publish dataset index, the previous index is still in use", log.ERROR, log.Error(err), log.Data{"index": indexName})
+		os.Exit(1)
+	}
+
+	if err = searchapi.PurgeAfterPublish(ctx, cli, searchAPIURL, datasetIndex); err != nil {
 		os.Exit(1)
 	}
 
-	log.Event(ctx, "successfully loaded in dataset docs", log.INFO)
+	log.Event(ctx, "successfully loaded in dataset docs", log.INFO, log.Data{"count": stats.Indexed, "index": indexName})
 }
 
-func uploadDocs(ctx context.Context, esAPI *es.API, indexName, filename string) error {
+// uploadDocs reads every dataset in the csv file and adds it to the indexer along with its row
+// of the file, a dataset with the same alias as an earlier dataset replaces it. Datasets are
+// only added once the whole file has been read, as the bulk requests holding an alias twice
+// could be indexed in either order.
+func uploadDocs(ctx context.Context, indexer *es.BulkIndexer, filename string) error {
 	csvfile, err := os.Open(filename)
 	if err != nil {
 		log.Event(ctx, "failed to open the csv file", log.ERROR, log.Error(err))
@@ -197,6 +267,13 @@ func uploadDocs(ctx context.Context, esAPI *es.API, indexName, filename string)
 
 	count := 0
 
+	// datasets are kept in the order their alias was first read, with the row of the file
+	var (
+		datasets []*Dataset
+		rows     []int
+	)
+	aliases := make(map[string]int)
+
 	dimensionMap := make(map[string]string)
 	// Iterate through the records
 	for {
@@ -207,7 +284,8 @@ func uploadDocs(ctx context.Context, esAPI *es.API, indexName, filename string)
 			break
 		}
 		if err != nil {
-			log.Event(ctx, "failed to read row", log.ERROR, log.Error(err))
+			log.Event(ctx, "failed to read row", log.ERROR, log.Error(err), log.Data{"row": count + 1})
+			return err
 		}
 
 		datasetDoc := &Dataset{
@@ -257,15 +335,22 @@ func uploadDocs(ctx context.Context, esAPI *es.API, indexName, filename string)
 			log.Event(ctx, "dataset?", log.Data{"datasets": datasetDoc})
 		}
 
-		bytes, err := json.Marshal(datasetDoc)
-		if err != nil {
-			log.Event(ctx, "failed to marshal dataset document to bytes", log.ERROR, log.Error(err), log.Data{"count": count})
-			return err
+		// a repeated alias replaces the earlier dataset rather than adding a document
+		if i, ok := aliases[datasetDoc.Alias]; ok {
+			log.Event(ctx, "dataset replaced an earlier dataset with the same alias", log.WARN, log.Data{"row": count + 1, "alias": datasetDoc.Alias})
+			datasets[i] = datasetDoc
+			rows[i] = count + 1
+			continue
 		}
 
-		// Add document to elasticsearch index
-		if _, err = esAPI.AddDocument(ctx, indexName, bytes); err != nil {
-			log.Event(ctx, "failed to upload dataset document to index", log.ERROR, log.Error(err), log.Data{"count": count})
+		aliases[datasetDoc.Alias] = len(datasets)
+		datasets = append(datasets, datasetDoc)
+		rows = append(rows, count+1)
+	}
+
+	for i, datasetDoc := range datasets {
+		if err = indexer.Add(ctx, rows[i], datasetDoc); err != nil {
+			log.Event(ctx, "failed to upload dataset document to index", log.ERROR, log.Error(err), log.Data{"row": rows[i]})
 			return err
 		}
 	}
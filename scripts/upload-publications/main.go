@@ -0,0 +1,496 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	es "github.com/ONSdigital/dp-census-alpha-search-api/internal/elasticsearch"
+	"github.com/ONSdigital/dp-census-alpha-search-api/internal/searchapi"
+	"github.com/ONSdigital/dp-census-alpha-search-api/models"
+	dphttp "github.com/ONSdigital/dp-net/http"
+	"github.com/ONSdigital/log.go/log"
+	uuid "github.com/satori/go.uuid"
+	"golang.org/x/net/html"
+)
+
+const (
+	defaultPublicationIndex    = "publications"
+	defaultElasticsearchAPIURL = "http://localhost:9200"
+	defaultDirectory           = "../publications/"
+	defaultGenerations         = 2
+	defaultTaxonomyFile        = "../data/taxonomy.json"
+	mappingsFile               = "publication-mappings.json"
+	documentType               = "publication"
+	onsWebsite                 = "https://www.ons.gov.uk"
+	defaultBulkRetries         = 3
+	defaultMaxRejects          = 0
+	defaultRejectsFilename     = "publications-rejects.jsonl"
+)
+
+var (
+	publicationIndex, elasticsearchAPIURL, directory, taxonomyFilename string
+	rejectsFilename                                                    string
+	bulkRetries, bulkSize, bulkWorkers, generations, maxRejects        int
+	taxonomy                                                           models.Taxonomy
+	topicLevels                                                        = make(map[string]TopicLevels)
+
+	// layouts of release dates found in publication metadata
+	dateLayouts = []string{time.RFC3339, "2006-01-02", "02/01/2006", "2 January 2006"}
+
+	errMissingTitle = errors.New("publication is missing a title")
+	searchAPIURL    string
+)
+
+// Publication represents the data stored against a resource in elasticsearch index
+type Publication struct {
+	DocType         string   `json:"doc_type"`
+	ID              string   `json:"id"`
+	Keywords        []string `json:"keywords,omitempty"`
+	Links           Links    `json:"links"`
+	PublicationType string   `json:"publication_type,omitempty"`
+	ReleaseDate     string   `json:"release_date,omitempty"`
+	Summary         string   `json:"summary,omitempty"`
+	Title           string   `json:"title"`
+	Topic1          string   `json:"topic1,omitempty"`
+	Topic2          string   `json:"topic2,omitempty"`
+	Topic3          string   `json:"topic3,omitempty"`
+}
+
+// Metadata represents the bulletin or article metadata read from a json or html file
+type Metadata struct {
+	ID              string   `json:"id"`
+	Keywords        []string `json:"keywords"`
+	PublicationType string   `json:"publication_type"`
+	ReleaseDate     string   `json:"release_date"`
+	Summary         string   `json:"summary"`
+	Title           string   `json:"title"`
+	Topic           string   `json:"topic"`
+	URI             string   `json:"uri"`
+}
+
+// Links represents a set of links related to the publication
+type Links struct {
+	Self Self `json:"self"`
+}
+
+// Self represents a link to a unique publication resource
+type Self struct {
+	HRef string `json:"href"`
+	ID   string `json:"id"`
+}
+
+// TopicLevels represent the levels within the topic hierarchy (aka taxonomy)
+type TopicLevels struct {
+	TopicLevel1 string
+	TopicLevel2 string
+	TopicLevel3 string
+}
+
+func main() {
+	ctx := context.Background()
+	flag.StringVar(&publicationIndex, "publication-index", defaultPublicationIndex, "the elasticsearch alias of the index that publications will be uploaded to")
+	flag.StringVar(&elasticsearchAPIURL, "elasticsearch-url", defaultElasticsearchAPIURL, "the elasticsearch url")
+	flag.StringVar(&directory, "directory", defaultDirectory, "the directory containing json and/or html files of publications to upload to elasticsearch")
+	flag.StringVar(&taxonomyFilename, "taxonomy-filename", defaultTaxonomyFile, "the file locataion and name that contains the taxonomy hierarchy")
+	flag.IntVar(&generations, "generations", defaultGenerations, "the number of previous generations of the index to keep for rollback")
+	flag.IntVar(&bulkRetries, "bulk-retries", defaultBulkRetries, "the number of times documents rejected because elasticsearch is overloaded are sent again")
+	flag.IntVar(&bulkSize, "bulk-size", es.DefaultBulkSize, "the most documents sent in each bulk request")
+	flag.IntVar(&bulkWorkers, "bulk-workers", es.DefaultBulkWorkers, "the number of bulk requests sent to elasticsearch at the same time")
+	flag.IntVar(&maxRejects, "max-rejects", defaultMaxRejects, "the most publications elasticsearch can fail to index before the index is not published")
+	flag.StringVar(&rejectsFilename, "rejects-filename", defaultRejectsFilename, "the file that documents elasticsearch failed to index are written to")
+	flag.StringVar(&searchAPIURL, "search-api-url", searchapi.DefaultURL, "the url of the search api whose cache of search responses is purged once the index is published")
+	flag.Parse()
+
+	if publicationIndex == "" {
+		publicationIndex = defaultPublicationIndex
+	}
+
+	if elasticsearchAPIURL == "" {
+		elasticsearchAPIURL = defaultElasticsearchAPIURL
+	}
+
+	if directory == "" {
+		directory = defaultDirectory
+	}
+
+	if taxonomyFilename == "" {
+		taxonomyFilename = defaultTaxonomyFile
+	}
+
+	log.Event(ctx, "script variables", log.INFO, log.Data{"publication_index": publicationIndex, "elasticsearch_api_url": elasticsearchAPIURL, "directory": directory, "taxonomy-file": taxonomyFilename, "generations": generations})
+
+	cli := dphttp.NewClient()
+	esAPI := es.NewElasticSearchAPI(cli, elasticsearchAPIURL)
+
+	// Read in Taxonomy into memory
+	taxonomyFile, err := ioutil.ReadFile(taxonomyFilename)
+	if err != nil {
+		log.Event(ctx, "failed to read taxonomy file", log.ERROR, log.Error(err), log.Data{"taxonomy_filename": taxonomyFilename})
+		os.Exit(1)
+	}
+
+	if err = json.Unmarshal([]byte(taxonomyFile), &taxonomy); err != nil {
+		log.Event(ctx, "unable to unmarshal taxonomy into struct", log.ERROR, log.Error(err), log.Data{"taxonomy_filename": taxonomyFilename})
+		os.Exit(1)
+	}
+
+	// Invert taxonomy so each topic has a list of parent topics and store in map
+	for _, topic := range taxonomy.Topics {
+		topicLevels[topic.FormattedTitle] = TopicLevels{
+			TopicLevel1: topic.FormattedTitle,
+		}
+
+		for _, topic2 := range topic.ChildTopics {
+			topicLevels[topic2.FormattedTitle] = TopicLevels{
+				TopicLevel1: topic.FormattedTitle,
+				TopicLevel2: topic2.FormattedTitle,
+			}
+
+			for _, topic3 := range topic2.ChildTopics {
+				topicLevels[topic3.FormattedTitle] = TopicLevels{
+					TopicLevel1: topic.FormattedTitle,
+					TopicLevel2: topic2.FormattedTitle,
+					TopicLevel3: topic3.FormattedTitle,
+				}
+			}
+		}
+	}
+
+	// remove the generations left behind by runs that failed before publishing
+	if err := esAPI.DeleteUnpublishedGenerations(ctx, publicationIndex); err != nil {
+		log.Event(ctx, "failed to delete unpublished generations of index", log.ERROR, log.Error(err), log.Data{"alias": publicationIndex})
+		os.Exit(1)
+	}
+
+	// build a new generation of the index, the alias keeps pointing at the current index
+	// until the new index has been verified
+	indexName := es.VersionedIndex(publicationIndex, time.Now())
+
+	// create elasticsearch index with settings/mapping
+	status, err := esAPI.CreateSearchIndex(ctx, indexName, mappingsFile)
+	if err != nil {
+		log.Event(ctx, "failed to create index", log.ERROR, log.Error(err), log.Data{"status": status, "index": indexName})
+		os.Exit(1)
+	}
+
+	rejects := es.NewRejectFile(rejectsFilename)
+
+	indexer, err := es.NewBulkIndexer(ctx, esAPI, es.BulkIndexerConfig{
+		Index:      indexName,
+		Workers:    bulkWorkers,
+		Size:       bulkSize,
+		Retries:    bulkRetries,
+		Rejects:    rejects,
+		OnProgress: es.LogProgress(ctx, 5*time.Second),
+	})
+	if err != nil {
+		log.Event(ctx, "failed to create bulk indexer", log.ERROR, log.Error(err), log.Data{"index": indexName})
+		os.Exit(1)
+	}
+
+	if err = uploadDocs(ctx, indexer, directory); err != nil {
+		log.Event(ctx, "failed to upload publication docs", log.ERROR, log.Error(err), log.Data{"index": indexName})
+		os.Exit(1)
+	}
+
+	// send the publications waiting in the last batch
+	if err = indexer.Close(ctx); err != nil {
+		log.Event(ctx, "failed to upload publication docs", log.ERROR, log.Error(err), log.Data{"index": indexName})
+		os.Exit(1)
+	}
+
+	stats := indexer.Stats()
+
+	if err = rejects.Close(); err != nil {
+		log.Event(ctx, "failed to close rejects file", log.ERROR, log.Error(err), log.Data{"filename": rejects.Filename()})
+	}
+
+	if rejects.Rejected() > 0 {
+		log.Event(ctx, "elasticsearch failed to index some publications, see the rejects file", log.WARN, log.Data{"rejected": rejects.Rejected(), "filename": rejects.Filename()})
+	}
+
+	if stats.Failed > maxRejects {
+		log.Event(ctx, "not publishing publication index as too many publications were rejected, the previous index is still in use", log.ERROR, log.Data{"rejected": stats.Failed, "max_rejects": maxRejects, "filename": rejects.Filename(), "index": indexName})
+		os.Exit(1)
+	}
+
+	// every publication read must be in the index apart from the rejected publications
+	if err = esAPI.PublishIndex(ctx, publicationIndex, indexName, stats.Added-stats.Failed, generations); err != nil {
+		log.Event(ctx, "failed to publish publication index, the previous index is still in use", log.ERROR, log.Error(err), log.Data{"index": indexName})
+		os.Exit(1)
+	}
+
+	if err = searchapi.PurgeAfterPublish(ctx, cli, searchAPIURL, publicationIndex); err != nil {
+		os.Exit(1)
+	}
+
+	log.Event(ctx, "successfully loaded in publication docs", log.INFO, log.Data{"count": stats.Indexed, "index": indexName})
+}
+
+// uploadDocs reads every json and html file in the directory and adds the publications to the
+// indexer along with the order they were read in
+func uploadDocs(ctx context.Context, indexer *es.BulkIndexer, directory string) error {
+	count := 0
+
+	return filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		logData := log.Data{"file": path}
+
+		var metadata []Metadata
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".json":
+			metadata, err = readJSON(path)
+		case ".html", ".htm":
+			var m *Metadata
+			m, err = readHTML(path)
+			if m != nil {
+				metadata = []Metadata{*m}
+			}
+		default:
+			log.Event(ctx, "skipping file as it is not json or html", log.WARN, logData)
+			return nil
+		}
+
+		if err != nil {
+			log.Event(ctx, "failed to read publication metadata from file", log.ERROR, log.Error(err), logData)
+			return err
+		}
+
+		for _, m := range metadata {
+			doc, err := createDoc(ctx, m)
+			if err != nil {
+				log.Event(ctx, "skipping publication with invalid metadata", log.WARN, log.Error(err), logData)
+				continue
+			}
+
+			count++
+
+			if err = indexer.Add(ctx, count, doc); err != nil {
+				log.Event(ctx, "failed to upload document to index", log.ERROR, log.Error(err), log.Data{"count": count})
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// readJSON reads a single publication or a list of publications from a json file
+func readJSON(path string) ([]Metadata, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var metadata []Metadata
+	if err = json.Unmarshal(b, &metadata); err == nil {
+		return metadata, nil
+	}
+
+	var m Metadata
+	if err = json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+
+	return []Metadata{m}, nil
+}
+
+// readHTML reads the publication metadata from the title, meta and canonical link tags of a html page
+func readHTML(path string) (*Metadata, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	doc, err := html.Parse(f)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Metadata{}
+	meta := make(map[string]string)
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "title":
+				if n.FirstChild != nil && m.Title == "" {
+					m.Title = strings.TrimSpace(n.FirstChild.Data)
+				}
+			case "meta":
+				name := strings.ToLower(attribute(n, "name"))
+				if name == "" {
+					name = strings.ToLower(attribute(n, "property"))
+				}
+
+				if name != "" {
+					meta[name] = strings.TrimSpace(attribute(n, "content"))
+				}
+			case "link":
+				if strings.ToLower(attribute(n, "rel")) == "canonical" {
+					m.URI = attribute(n, "href")
+				}
+			}
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	if title := firstValue(meta, "og:title", "citation_title"); title != "" {
+		m.Title = title
+	}
+
+	m.Summary = firstValue(meta, "description", "og:description")
+	m.ReleaseDate = firstValue(meta, "release_date", "citation_publication_date", "dcterms.date")
+	m.PublicationType = firstValue(meta, "publication_type")
+	m.Topic = firstValue(meta, "topic")
+
+	if m.URI == "" {
+		m.URI = firstValue(meta, "og:url")
+	}
+
+	for _, keyword := range strings.Split(meta["keywords"], ",") {
+		if keyword = strings.TrimSpace(keyword); keyword != "" {
+			m.Keywords = append(m.Keywords, keyword)
+		}
+	}
+
+	return m, nil
+}
+
+func attribute(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if strings.ToLower(attr.Key) == key {
+			return attr.Val
+		}
+	}
+
+	return ""
+}
+
+func firstValue(meta map[string]string, names ...string) string {
+	for _, name := range names {
+		if value := meta[name]; value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
+
+// createDoc converts publication metadata into an elasticsearch document, the topic and publication
+// type are taken from the uri of the publication if they are missing from the metadata
+func createDoc(ctx context.Context, m Metadata) (*Publication, error) {
+	if m.Title == "" {
+		return nil, errMissingTitle
+	}
+
+	id := m.ID
+	if id == "" {
+		id = uuid.NewV4().String()
+	}
+
+	doc := &Publication{
+		DocType:         documentType,
+		ID:              id,
+		Keywords:        m.Keywords,
+		PublicationType: strings.ToLower(m.PublicationType),
+		Summary:         m.Summary,
+		Title:           m.Title,
+		Links: Links{
+			Self: Self{
+				HRef: m.URI,
+				ID:   id,
+			},
+		},
+	}
+
+	var segments []string
+	if m.URI != "" {
+		uri, err := url.Parse(m.URI)
+		if err != nil {
+			return nil, err
+		}
+
+		if uri.Host == "" {
+			doc.Links.Self.HRef = onsWebsite + "/" + strings.TrimPrefix(uri.Path, "/")
+		}
+
+		segments = strings.Split(strings.Trim(uri.Path, "/"), "/")
+	}
+
+	if doc.PublicationType == "" {
+		for _, segment := range segments {
+			switch segment {
+			case "bulletins":
+				doc.PublicationType = "bulletin"
+			case "articles":
+				doc.PublicationType = "article"
+			}
+		}
+	}
+
+	// find topic hierarchy - using taxonomy map, the deepest topic in the uri is used if no topic is given
+	topic := m.Topic
+	if topic == "" {
+		for _, segment := range segments {
+			if _, ok := topicLevels[segment]; ok {
+				topic = segment
+			}
+		}
+	}
+
+	if topic != "" {
+		levels, ok := topicLevels[topic]
+		if !ok {
+			log.Event(ctx, "topic not found in taxonomy", log.WARN, log.Data{"topic": topic, "title": m.Title})
+		}
+
+		doc.Topic1 = levels.TopicLevel1
+		doc.Topic2 = levels.TopicLevel2
+		doc.Topic3 = levels.TopicLevel3
+	}
+
+	if m.ReleaseDate != "" {
+		releaseDate, err := parseDate(m.ReleaseDate)
+		if err != nil {
+			log.Event(ctx, "unable to parse release date, continuing without it", log.WARN, log.Error(err), log.Data{"release_date": m.ReleaseDate, "title": m.Title})
+		} else {
+			doc.ReleaseDate = releaseDate.Format("2006-01-02")
+		}
+	}
+
+	return doc, nil
+}
+
+func parseDate(value string) (time.Time, error) {
+	var err error
+	for _, layout := range dateLayouts {
+		var t time.Time
+		if t, err = time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, err
+}